@@ -2,14 +2,23 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type EnvConfig struct {
-	APP *app
-	DB  *db
-	JWT *jwt
+	APP      *app
+	DB       *db
+	JWT      *jwt
+	Account  *account
+	Worker   *worker
+	MFA      *mfa
+	Auth     *auth
+	Password *password
+	Mail     *mail
 }
 
 type db struct {
@@ -21,14 +30,86 @@ type db struct {
 	SSL  string
 }
 
+// app configures the HTTP server. TrustedProxies are the IPs or CIDRs of
+// reverse proxies whose X-Forwarded-For header gives the client IP; with
+// none, the client IP is the address of the connection.
 type app struct {
-	Version string
-	Port    string
+	Version        string
+	Port           string
+	TrustedProxies []string
 }
 
+// jwt configures token signing. Keys are PEM files in KeysDir, named
+// <kid>.pem; new keys are generated with Algorithm (EdDSA or RS256) every
+// KeyRotation.
 type jwt struct {
-	SecretKey  string
-	RefreshKey string
+	KeysDir     string
+	Algorithm   string
+	Issuer      string
+	Audience    string
+	KeyRotation time.Duration
+}
+
+type account struct {
+	HoldExpiry time.Duration
+}
+
+// mfa configures two-factor authentication. Transfers above
+// StepUpThreshold, in units of the account currency, need a fresh one-time
+// code; an empty threshold turns the check off.
+type mfa struct {
+	Issuer          string
+	ChallengeExpiry time.Duration
+	StepUpThreshold string
+}
+
+// auth configures how long the single-use tokens sent by email are valid
+// and how failed logins are throttled: after LoginMaxFailures failures for
+// one email address, or LoginIPMaxFailures from one IP, within LoginWindow,
+// further logins are refused for LoginLockout. ClientTokenExpiry is how
+// long the access tokens of OAuth2 clients are valid.
+type auth struct {
+	PasswordResetExpiry time.Duration
+	EmailVerifyExpiry   time.Duration
+	LoginMaxFailures    int
+	LoginIPMaxFailures  int
+	LoginWindow         time.Duration
+	LoginLockout        time.Duration
+	ClientTokenExpiry   time.Duration
+}
+
+// password configures the password policy. Passwords need MinLength
+// characters from at least MinClasses of lowercase, uppercase, digits and
+// symbols, and must not be listed in CommonListFile, one per line; an
+// empty path turns that check off. The last HistorySize passwords of a
+// user cannot be chosen again. New hashes use BcryptCost, and older hashes
+// are upgraded at the next login.
+type password struct {
+	MinLength      int
+	MinClasses     int
+	CommonListFile string
+	HistorySize    int
+	BcryptCost     int
+}
+
+// mail configures outgoing email. Driver is smtp, file or memory; links in
+// emails start with LinkBaseURL.
+type mail struct {
+	Driver      string
+	From        string
+	Host        string
+	Port        string
+	User        string
+	Pass        string
+	Dir         string
+	LinkBaseURL string
+}
+
+type worker struct {
+	StandingOrderInterval time.Duration
+	HoldExpiryInterval    time.Duration
+	TokenCleanupInterval  time.Duration
+	KeyRotationInterval   time.Duration
 }
 
 func LoadEnvConfig(configFile string) (*EnvConfig, error) {
@@ -38,8 +119,9 @@ func LoadEnvConfig(configFile string) (*EnvConfig, error) {
 
 	env := &EnvConfig{
 		APP: &app{
-			Version: getEnvString("APP_VERSION", "v1"),
-			Port:    getEnvString("APP_PORT", ":8080"),
+			Version:        getEnvString("APP_VERSION", "v1"),
+			Port:           getEnvString("APP_PORT", ":8080"),
+			TrustedProxies: getEnvList("APP_TRUSTED_PROXIES"),
 		},
 		DB: &db{
 			User: getEnvString("POSTGRES_USER", "postgres"),
@@ -50,8 +132,51 @@ func LoadEnvConfig(configFile string) (*EnvConfig, error) {
 			SSL:  getEnvString("POSTGRES_SSL", "disable"),
 		},
 		JWT: &jwt{
-			SecretKey:  getEnvString("JWT_SECRET_KEY", "my-secret-123"),
-			RefreshKey: getEnvString("JWT_REFRESH_KEY", "my-refresh-123"),
+			KeysDir:     getEnvString("JWT_KEYS_DIR", "keys/jwt"),
+			Algorithm:   getEnvString("JWT_ALGORITHM", "EdDSA"),
+			Issuer:      getEnvString("JWT_ISSUER", "simple-bank"),
+			Audience:    getEnvString("JWT_AUDIENCE", "simple-bank"),
+			KeyRotation: getEnvDuration("JWT_KEY_ROTATION", time.Hour*24*30),
+		},
+		Account: &account{
+			HoldExpiry: getEnvDuration("ACCOUNT_HOLD_EXPIRY", time.Hour*24*7),
+		},
+		Worker: &worker{
+			StandingOrderInterval: getEnvDuration("WORKER_STANDING_ORDER_INTERVAL", time.Minute),
+			HoldExpiryInterval:    getEnvDuration("WORKER_HOLD_EXPIRY_INTERVAL", time.Minute),
+			TokenCleanupInterval:  getEnvDuration("WORKER_TOKEN_CLEANUP_INTERVAL", time.Hour),
+			KeyRotationInterval:   getEnvDuration("WORKER_KEY_ROTATION_INTERVAL", time.Hour),
+		},
+		MFA: &mfa{
+			Issuer:          getEnvString("MFA_ISSUER", "Simple Bank"),
+			ChallengeExpiry: getEnvDuration("MFA_CHALLENGE_EXPIRY", time.Minute*5),
+			StepUpThreshold: getEnvString("MFA_STEP_UP_THRESHOLD", "50000"),
+		},
+		Auth: &auth{
+			PasswordResetExpiry: getEnvDuration("AUTH_PASSWORD_RESET_EXPIRY", time.Minute*30),
+			EmailVerifyExpiry:   getEnvDuration("AUTH_EMAIL_VERIFY_EXPIRY", time.Hour*48),
+			LoginMaxFailures:    getEnvInt("AUTH_LOGIN_MAX_FAILURES", 5),
+			LoginIPMaxFailures:  getEnvInt("AUTH_LOGIN_IP_MAX_FAILURES", 20),
+			LoginWindow:         getEnvDuration("AUTH_LOGIN_WINDOW", time.Minute*15),
+			LoginLockout:        getEnvDuration("AUTH_LOGIN_LOCKOUT", time.Minute*15),
+			ClientTokenExpiry:   getEnvDuration("AUTH_CLIENT_TOKEN_EXPIRY", time.Minute*15),
+		},
+		Password: &password{
+			MinLength:      getEnvInt("PASSWORD_MIN_LENGTH", 12),
+			MinClasses:     getEnvInt("PASSWORD_MIN_CLASSES", 3),
+			CommonListFile: getEnvString("PASSWORD_COMMON_LIST_FILE", "config/common-passwords.txt"),
+			HistorySize:    getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+			BcryptCost:     getEnvInt("PASSWORD_BCRYPT_COST", 12),
+		},
+		Mail: &mail{
+			Driver:      getEnvString("MAIL_DRIVER", "file"),
+			From:        getEnvString("MAIL_FROM", "Simple Bank <no-reply@simple-bank.local>"),
+			Host:        getEnvString("SMTP_HOST", "localhost"),
+			Port:        getEnvString("SMTP_PORT", "587"),
+			User:        getEnvString("SMTP_USER", ""),
+			Pass:        getEnvString("SMTP_PASS", ""),
+			Dir:         getEnvString("MAIL_DIR", "tmp/mail"),
+			LinkBaseURL: getEnvString("MAIL_LINK_BASE_URL", "http://localhost:8080"),
 		},
 	}
 
@@ -65,3 +190,40 @@ func getEnvString(key, fallback string) string {
 	}
 	return val
 }
+
+// getEnvList splits a comma separated value, dropping empty items.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+func getEnvInt(key string, fallback int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS ledger_entries;
+
+DELETE FROM accounts WHERE system_code IS NOT NULL;
+
+ALTER TABLE accounts DROP CONSTRAINT accounts_owner_check;
+
+ALTER TABLE accounts DROP COLUMN system_code;
+
+ALTER TABLE accounts ALTER COLUMN user_id SET NOT NULL;
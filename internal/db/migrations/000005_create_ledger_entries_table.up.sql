@@ -0,0 +1,42 @@
+ALTER TABLE accounts ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE accounts ADD COLUMN system_code VARCHAR(30) UNIQUE;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_owner_check
+    CHECK (user_id IS NOT NULL OR system_code IS NOT NULL);
+
+INSERT INTO accounts (name, balance, currency, status, system_code)
+VALUES ('Cash Vault', 0, 'THB', 'APPROVED', 'CASH_VAULT');
+
+CREATE TABLE ledger_entries (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id),
+    account_id INT NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+
+CREATE INDEX idx_ledger_entries_account_id ON ledger_entries (account_id, created_at);
+
+-- Backfill entries for transactions recorded before the ledger existed.
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, t.to_account, t.amount, t.created_at
+FROM transactions t WHERE t.type IN ('DEPOSIT', 'TRANSFER')
+UNION ALL
+SELECT t.id, t.from_account, -t.amount, t.created_at
+FROM transactions t WHERE t.type IN ('WITHDRAW', 'TRANSFER')
+UNION ALL
+SELECT t.id, v.id, -t.amount, t.created_at
+FROM transactions t, accounts v
+WHERE t.type = 'DEPOSIT' AND v.system_code = 'CASH_VAULT'
+UNION ALL
+SELECT t.id, v.id, t.amount, t.created_at
+FROM transactions t, accounts v
+WHERE t.type = 'WITHDRAW' AND v.system_code = 'CASH_VAULT';
+
+UPDATE accounts SET balance = (
+    SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e WHERE e.account_id = accounts.id
+)
+WHERE system_code = 'CASH_VAULT';
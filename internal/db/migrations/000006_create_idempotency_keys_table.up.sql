@@ -0,0 +1,12 @@
+CREATE TABLE idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(255) NOT NULL,
+    user_id INT NOT NULL DEFAULT 0,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, key)
+);
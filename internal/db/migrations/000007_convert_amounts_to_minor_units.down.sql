@@ -0,0 +1,17 @@
+UPDATE ledger_entries SET amount = amount / currency_scale(currency);
+
+ALTER TABLE ledger_entries DROP COLUMN currency;
+
+UPDATE transactions SET amount = amount / currency_scale(currency);
+
+ALTER TABLE transactions DROP COLUMN currency;
+
+UPDATE accounts SET balance = balance / currency_scale(currency);
+
+ALTER TABLE accounts ALTER COLUMN balance DROP NOT NULL;
+
+ALTER TABLE accounts ALTER COLUMN balance TYPE INT;
+
+ALTER TABLE accounts ALTER COLUMN currency DROP NOT NULL;
+
+DROP FUNCTION currency_scale(VARCHAR);
@@ -0,0 +1,39 @@
+-- Amounts are stored as BIGINT minor units (satang, cents, ...) of the
+-- account currency. Existing values were whole currency units.
+CREATE FUNCTION currency_scale(code VARCHAR) RETURNS BIGINT AS $$
+    SELECT CASE code
+        WHEN 'JPY' THEN 1
+        WHEN 'KWD' THEN 1000
+        ELSE 100
+    END;
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE accounts ALTER COLUMN balance TYPE BIGINT;
+
+UPDATE accounts SET currency = 'THB' WHERE currency IS NULL;
+
+ALTER TABLE accounts ALTER COLUMN currency SET NOT NULL;
+
+UPDATE accounts SET balance = balance * currency_scale(currency);
+
+ALTER TABLE accounts ALTER COLUMN balance SET NOT NULL;
+
+ALTER TABLE transactions ADD COLUMN currency VARCHAR(10);
+
+UPDATE transactions t SET currency = a.currency
+FROM accounts a WHERE a.id = COALESCE(t.from_account, t.to_account);
+
+UPDATE transactions SET currency = 'THB' WHERE currency IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN currency SET NOT NULL;
+
+UPDATE transactions SET amount = amount * currency_scale(currency);
+
+ALTER TABLE ledger_entries ADD COLUMN currency VARCHAR(10);
+
+UPDATE ledger_entries e SET currency = a.currency
+FROM accounts a WHERE a.id = e.account_id;
+
+ALTER TABLE ledger_entries ALTER COLUMN currency SET NOT NULL;
+
+UPDATE ledger_entries SET amount = amount * currency_scale(currency);
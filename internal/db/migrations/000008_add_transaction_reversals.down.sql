@@ -0,0 +1,23 @@
+DELETE FROM ledger_entries WHERE transaction_id IN (
+    SELECT id FROM transactions WHERE type = 'REVERSAL'
+);
+
+DELETE FROM transactions WHERE type = 'REVERSAL';
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_reversed_amount_check;
+
+ALTER TABLE transactions DROP COLUMN reason;
+
+ALTER TABLE transactions DROP COLUMN created_by;
+
+ALTER TABLE transactions DROP COLUMN reversed_amount;
+
+ALTER TABLE transactions DROP COLUMN reversal_of;
+
+ALTER TYPE transaction_type RENAME TO transaction_type_old;
+
+CREATE TYPE transaction_type AS ENUM ('DEPOSIT', 'TRANSFER', 'WITHDRAW');
+
+ALTER TABLE transactions ALTER COLUMN type TYPE transaction_type USING type::TEXT::transaction_type;
+
+DROP TYPE transaction_type_old;
@@ -0,0 +1,14 @@
+ALTER TYPE transaction_type ADD VALUE 'REVERSAL';
+
+ALTER TABLE transactions ADD COLUMN reversal_of INT REFERENCES transactions(id);
+
+ALTER TABLE transactions ADD COLUMN reversed_amount BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE transactions ADD COLUMN created_by INT REFERENCES users(id);
+
+ALTER TABLE transactions ADD COLUMN reason TEXT;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_reversed_amount_check
+    CHECK (reversed_amount >= 0 AND reversed_amount <= amount);
+
+CREATE INDEX idx_transactions_reversal_of ON transactions (reversal_of);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transactions_to_account;
+
+DROP INDEX IF EXISTS idx_transactions_from_account;
+
+DROP INDEX IF EXISTS idx_transactions_created_at_id;
+
+ALTER TABLE transactions ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,9 @@
+UPDATE transactions SET created_at = NOW() WHERE created_at IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX idx_transactions_created_at_id ON transactions (created_at DESC, id DESC);
+
+CREATE INDEX idx_transactions_from_account ON transactions (from_account);
+
+CREATE INDEX idx_transactions_to_account ON transactions (to_account);
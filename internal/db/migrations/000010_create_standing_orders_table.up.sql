@@ -0,0 +1,40 @@
+CREATE TYPE standing_order_frequency AS ENUM ('ONCE', 'DAILY', 'WEEKLY', 'MONTHLY');
+
+CREATE TYPE standing_order_status AS ENUM ('ACTIVE', 'PAUSED', 'CANCELLED', 'COMPLETED');
+
+CREATE TYPE standing_order_execution_status AS ENUM ('SUCCEEDED', 'FAILED');
+
+CREATE TABLE standing_orders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    from_account INT NOT NULL REFERENCES accounts(id),
+    to_account INT NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency VARCHAR(10) NOT NULL,
+    frequency standing_order_frequency NOT NULL,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ,
+    max_runs INT CHECK (max_runs > 0),
+    executed_runs INT NOT NULL DEFAULT 0,
+    failure_count INT NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMPTZ,
+    status standing_order_status NOT NULL DEFAULT 'ACTIVE',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_standing_orders_due ON standing_orders (next_run_at) WHERE status = 'ACTIVE';
+
+CREATE INDEX idx_standing_orders_user_id ON standing_orders (user_id);
+
+CREATE TABLE standing_order_executions (
+    id SERIAL PRIMARY KEY,
+    standing_order_id INT NOT NULL REFERENCES standing_orders(id),
+    transaction_id INT REFERENCES transactions(id),
+    status standing_order_execution_status NOT NULL,
+    error TEXT,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    executed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_standing_order_executions_order_id ON standing_order_executions (standing_order_id, executed_at);
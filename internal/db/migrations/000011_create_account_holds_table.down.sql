@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS account_holds;
+
+DROP TYPE IF EXISTS hold_status;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS held_balance;
@@ -0,0 +1,23 @@
+-- Holds reserve money on an account without moving it. held_balance is the
+-- sum of the ACTIVE holds; the available balance is balance - held_balance.
+ALTER TABLE accounts ADD COLUMN held_balance BIGINT NOT NULL DEFAULT 0 CHECK (held_balance >= 0);
+
+CREATE TYPE hold_status AS ENUM ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED');
+
+CREATE TABLE account_holds (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(10) NOT NULL,
+    reference VARCHAR(255),
+    status hold_status NOT NULL DEFAULT 'ACTIVE',
+    transaction_id INT REFERENCES transactions(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_account_holds_account_id ON account_holds (account_id);
+
+CREATE INDEX idx_account_holds_expires_at ON account_holds (expires_at) WHERE status = 'ACTIVE';
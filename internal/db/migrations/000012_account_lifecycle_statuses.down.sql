@@ -0,0 +1,19 @@
+CREATE TYPE account_status_v1 AS ENUM ('PENDING', 'APPROVED', 'REJECTED');
+
+ALTER TABLE accounts ALTER COLUMN status DROP NOT NULL;
+
+ALTER TABLE accounts ALTER COLUMN status DROP DEFAULT;
+
+ALTER TABLE accounts ALTER COLUMN status TYPE account_status_v1 USING (
+    CASE status::TEXT
+        WHEN 'PENDING' THEN 'PENDING'
+        WHEN 'CLOSED' THEN 'REJECTED'
+        ELSE 'APPROVED'
+    END
+)::account_status_v1;
+
+DROP TYPE account_status;
+
+ALTER TYPE account_status_v1 RENAME TO account_status;
+
+ALTER TABLE accounts ALTER COLUMN status SET DEFAULT 'PENDING';
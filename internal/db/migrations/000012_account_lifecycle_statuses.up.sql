@@ -0,0 +1,21 @@
+-- Account lifecycle: PENDING -> ACTIVE <-> FROZEN / DORMANT -> CLOSED.
+-- APPROVED accounts become ACTIVE and REJECTED applications become CLOSED.
+CREATE TYPE account_status_v2 AS ENUM ('PENDING', 'ACTIVE', 'FROZEN', 'DORMANT', 'CLOSED');
+
+ALTER TABLE accounts ALTER COLUMN status DROP DEFAULT;
+
+ALTER TABLE accounts ALTER COLUMN status TYPE account_status_v2 USING (
+    CASE status::TEXT
+        WHEN 'APPROVED' THEN 'ACTIVE'
+        WHEN 'REJECTED' THEN 'CLOSED'
+        ELSE COALESCE(status::TEXT, 'PENDING')
+    END
+)::account_status_v2;
+
+DROP TYPE account_status;
+
+ALTER TYPE account_status_v2 RENAME TO account_status;
+
+ALTER TABLE accounts ALTER COLUMN status SET DEFAULT 'PENDING';
+
+ALTER TABLE accounts ALTER COLUMN status SET NOT NULL;
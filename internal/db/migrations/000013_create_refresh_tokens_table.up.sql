@@ -0,0 +1,16 @@
+-- One row per issued refresh token. A token is used once: refreshing sets
+-- used_at and issues a new token in the same family. Presenting a used
+-- token again revokes the whole family.
+CREATE TABLE refresh_tokens (
+    id VARCHAR(64) PRIMARY KEY,
+    family_id VARCHAR(64) NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
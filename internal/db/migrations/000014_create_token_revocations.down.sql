@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+
+DROP TABLE IF EXISTS revoked_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_valid_after;
@@ -0,0 +1,16 @@
+-- Access tokens issued before tokens_valid_after are rejected; set by
+-- "log out everywhere" and anything else that must end all sessions.
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMPTZ;
+
+-- Access tokens revoked one by one on logout. Rows are only needed until
+-- the token would have expired anyway.
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TABLE IF EXISTS permissions;
+
+CREATE TYPE users_role AS ENUM ('USER', 'STAFF', 'ADMIN');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+
+-- Custom roles have no enum value and fall back to USER.
+ALTER TABLE users ALTER COLUMN role TYPE users_role USING (
+    CASE WHEN role IN ('USER', 'STAFF', 'ADMIN') THEN role ELSE 'USER' END
+)::users_role;
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,55 @@
+-- Roles become rows so admins can edit them. users.role keeps the role
+-- name and references roles instead of the users_role enum.
+CREATE TABLE roles (
+    name VARCHAR(32) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('USER', 'Bank customer'),
+    ('STAFF', 'Bank employee'),
+    ('ADMIN', 'Administrator');
+
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(32) USING role::TEXT;
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'USER';
+
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+
+DROP TYPE users_role;
+
+-- Permissions are defined by the application; this table lists the codes
+-- a role may be granted.
+CREATE TABLE permissions (
+    code VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+INSERT INTO permissions (code, description) VALUES
+    ('accounts:approve', 'Change the status of any account'),
+    ('holds:manage', 'Place, view, capture and release holds'),
+    ('transactions:read_all', 'View the transactions of any user'),
+    ('transactions:reverse', 'Reverse transactions'),
+    ('ledger:verify', 'Verify the ledger'),
+    ('users:read_pii', 'View the personal data of any user'),
+    ('users:manage', 'Create and delete users'),
+    ('roles:manage', 'Edit roles and assign them to users'),
+    ('system:metrics', 'View internal metrics');
+
+CREATE TABLE role_permissions (
+    role VARCHAR(32) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('STAFF', 'accounts:approve'),
+    ('STAFF', 'holds:manage'),
+    ('STAFF', 'transactions:read_all'),
+    ('STAFF', 'transactions:reverse'),
+    ('STAFF', 'ledger:verify');
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'ADMIN', code FROM permissions;
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE code = 'accounts:read_all';
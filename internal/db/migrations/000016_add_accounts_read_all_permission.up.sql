@@ -0,0 +1,7 @@
+-- Reading accounts of other users; staff get read-only access to them.
+INSERT INTO permissions (code, description) VALUES
+    ('accounts:read_all', 'View any account and its statements');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('STAFF', 'accounts:read_all'),
+    ('ADMIN', 'accounts:read_all');
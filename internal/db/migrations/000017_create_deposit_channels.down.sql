@@ -0,0 +1,11 @@
+DELETE FROM permissions WHERE code IN ('transactions:deposit', 'channels:manage');
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS reference;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS channel_id;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS channel;
+
+DROP TABLE IF EXISTS deposit_channels;
+
+DROP TYPE IF EXISTS deposit_channel_type;
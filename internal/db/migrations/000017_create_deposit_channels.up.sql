@@ -0,0 +1,35 @@
+-- Machine-to-machine deposit channels. API_KEY channels authenticate with
+-- a key of which only the SHA-256 hash is stored; HMAC channels sign each
+-- request with a shared secret, which has to be kept to verify signatures.
+CREATE TYPE deposit_channel_type AS ENUM ('API_KEY', 'HMAC');
+
+CREATE TABLE deposit_channels (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    type deposit_channel_type NOT NULL,
+    key_hash CHAR(64) UNIQUE,
+    secret TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    CONSTRAINT deposit_channels_credentials_check CHECK (
+        (type = 'API_KEY' AND key_hash IS NOT NULL AND secret IS NULL) OR
+        (type = 'HMAC' AND secret IS NOT NULL AND key_hash IS NULL)
+    )
+);
+
+-- Where a deposit came from: TELLER (created_by is the teller), API_KEY or
+-- HMAC (channel_id is the channel). Older transactions have no channel.
+ALTER TABLE transactions ADD COLUMN channel VARCHAR(16);
+
+ALTER TABLE transactions ADD COLUMN channel_id INT REFERENCES deposit_channels(id);
+
+ALTER TABLE transactions ADD COLUMN reference VARCHAR(255);
+
+INSERT INTO permissions (code, description) VALUES
+    ('transactions:deposit', 'Deposit cash at a teller'),
+    ('channels:manage', 'Create and revoke deposit channels');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('STAFF', 'transactions:deposit'),
+    ('ADMIN', 'transactions:deposit'),
+    ('ADMIN', 'channels:manage');
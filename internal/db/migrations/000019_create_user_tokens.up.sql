@@ -0,0 +1,19 @@
+-- Users registered before email verification existed count as verified.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = created_at;
+
+-- Single-use tokens sent by email, e.g. to reset a password. Only the
+-- SHA-256 hash of a token is stored.
+CREATE TABLE user_tokens (
+    id CHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_tokens_user_id_purpose ON user_tokens (user_id, purpose);
+
+CREATE INDEX idx_user_tokens_expires_at ON user_tokens (expires_at);
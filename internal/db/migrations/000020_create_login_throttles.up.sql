@@ -0,0 +1,13 @@
+-- Failed login attempts per email address, per client IP and per user for
+-- one-time codes. Email addresses are tracked as typed, so unknown ones are
+-- throttled exactly like real ones and reveal nothing.
+CREATE TABLE login_throttles (
+    scope VARCHAR(8) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE INDEX idx_login_throttles_last_failed_at ON login_throttles (last_failed_at);
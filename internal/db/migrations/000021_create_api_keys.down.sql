@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE code = 'clients:manage';
+
+DROP TABLE IF EXISTS oauth_clients;
+
+DROP TABLE IF EXISTS api_keys;
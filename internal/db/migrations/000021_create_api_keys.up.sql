@@ -0,0 +1,37 @@
+-- Personal API keys. Only the SHA-256 hash of a key is stored; prefix is
+-- the start of the key, shown so its owner can tell keys apart. A key acts
+-- as its user, limited to its scopes.
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
+
+-- OAuth2 clients of the client-credentials grant, e.g. partner systems.
+-- Like an API key, a client acts as the user it belongs to, limited to its
+-- scopes. Only the SHA-256 hash of the secret is stored.
+CREATE TABLE oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    secret_hash CHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+INSERT INTO permissions (code, description) VALUES
+    ('clients:manage', 'Create and revoke OAuth2 clients and API keys of other users');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('ADMIN', 'clients:manage');
@@ -0,0 +1,10 @@
+-- password_history keeps earlier password hashes of a user, so recent
+-- passwords cannot be chosen again. The current hash stays in users.
+CREATE TABLE password_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history (user_id, created_at DESC);
@@ -0,0 +1,11 @@
+-- Signatures of HMAC deposit requests seen within the clock skew window. A
+-- signed request is accepted once; rows are removed after expires_at, when
+-- the timestamp of the request is too old to be accepted anyway.
+CREATE TABLE channel_nonces (
+    channel_id INT NOT NULL REFERENCES deposit_channels(id) ON DELETE CASCADE,
+    signature CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (channel_id, signature)
+);
+
+CREATE INDEX idx_channel_nonces_expires_at ON channel_nonces (expires_at);
@@ -0,0 +1 @@
+ALTER TABLE login_throttles DROP COLUMN IF EXISTS retry_at;
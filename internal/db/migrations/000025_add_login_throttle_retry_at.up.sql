@@ -0,0 +1,3 @@
+-- Attempts are counted before the password or code is checked; retry_at is
+-- the earliest the next attempt of the subject is allowed.
+ALTER TABLE login_throttles ADD COLUMN retry_at TIMESTAMPTZ;
@@ -0,0 +1,16 @@
+UPDATE ledger_entries e SET account_id = thb.id
+FROM accounts thb, accounts v
+WHERE thb.system_code = 'CASH_VAULT' AND thb.currency = 'THB'
+    AND v.system_code = 'CASH_VAULT' AND v.currency <> 'THB'
+    AND e.account_id = v.id;
+
+DELETE FROM accounts WHERE system_code = 'CASH_VAULT' AND currency <> 'THB';
+
+UPDATE accounts SET balance = (
+    SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e WHERE e.account_id = accounts.id
+)
+WHERE system_code = 'CASH_VAULT';
+
+ALTER TABLE accounts DROP CONSTRAINT accounts_system_code_currency_key;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_system_code_key UNIQUE (system_code);
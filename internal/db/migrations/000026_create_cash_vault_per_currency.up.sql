@@ -0,0 +1,22 @@
+-- System accounts hold one currency each, so there is a cash vault for
+-- every supported currency instead of one THB vault for all of them.
+ALTER TABLE accounts DROP CONSTRAINT accounts_system_code_key;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_system_code_currency_key UNIQUE (system_code, currency);
+
+INSERT INTO accounts (name, balance, currency, status, system_code)
+SELECT 'Cash Vault ' || c, 0, c, 'ACTIVE', 'CASH_VAULT'
+FROM unnest(ARRAY['USD', 'EUR', 'GBP', 'SGD', 'JPY', 'KWD']) AS c
+ON CONFLICT DO NOTHING;
+
+-- Move entries in other currencies that were posted to the THB vault.
+UPDATE ledger_entries e SET account_id = v.id
+FROM accounts thb, accounts v
+WHERE thb.system_code = 'CASH_VAULT' AND thb.currency = 'THB'
+    AND e.account_id = thb.id AND e.currency <> 'THB'
+    AND v.system_code = 'CASH_VAULT' AND v.currency = e.currency;
+
+UPDATE accounts SET balance = (
+    SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e WHERE e.account_id = accounts.id
+)
+WHERE system_code = 'CASH_VAULT';
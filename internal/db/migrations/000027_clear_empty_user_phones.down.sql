@@ -0,0 +1 @@
+-- The cleared phone numbers were empty, there is nothing to restore.
@@ -0,0 +1,2 @@
+-- An empty phone number means no phone number.
+UPDATE users SET phone = NULL WHERE phone = '';
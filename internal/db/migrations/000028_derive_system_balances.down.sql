@@ -0,0 +1,4 @@
+UPDATE accounts SET balance = (
+    SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e WHERE e.account_id = accounts.id
+)
+WHERE system_code IS NOT NULL;
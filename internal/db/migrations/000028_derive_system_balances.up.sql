@@ -0,0 +1,3 @@
+-- Postings no longer write the balance of system accounts; it is read as
+-- the sum of their ledger entries, so the stored column is unused.
+UPDATE accounts SET balance = 0 WHERE system_code IS NOT NULL;
@@ -3,11 +3,38 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"expvar"
 	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// maxTxAttempts bounds how often a transaction runs when Postgres keeps
+	// aborting it with a serialization failure or deadlock.
+	maxTxAttempts = 8
+	baseTxBackoff = time.Millisecond * 10
+	maxTxBackoff  = time.Millisecond * 300
+)
+
+// Postgres SQLSTATEs that mean "run the transaction again".
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
 )
 
+// Serializable is the option set for transactions that move money.
+var Serializable = &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+// txMetrics is published at /debug/vars as "db_tx".
+var txMetrics = expvar.NewMap("db_tx")
+
 type TxManager interface {
 	WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error
+	WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error
 }
 
 type Tx struct {
@@ -22,7 +49,21 @@ func InitTx(db *sql.DB) TxManager {
 }
 
 func (t *Tx) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
-	tx, err := t.db.BeginTx(ctx, nil)
+	return t.WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions runs fn in a transaction begun with opts, e.g. Serializable
+// or read-only. When Postgres aborts it with a serialization failure or a
+// deadlock, fn runs again in a fresh transaction after a short backoff, so
+// fn must not have effects outside tx.
+func (t *Tx) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return retryTx(ctx, func() error {
+		return t.run(ctx, opts, fn)
+	})
+}
+
+func (t *Tx) run(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := t.db.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("tx begin failed: %w", err)
 	}
@@ -33,7 +74,85 @@ func (t *Tx) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 		return fmt.Errorf("tx function failed: %w", err)
 	}
 
-	return tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// retryTx runs attempt until it succeeds, fails for a reason other than a
+// serialization failure or deadlock, or runs maxTxAttempts times. It also
+// gives up early when ctx would expire before another attempt could finish,
+// so retries never eat the rest of the request's deadline.
+func retryTx(ctx context.Context, attempt func() error) error {
+	var (
+		err  error
+		took time.Duration
+	)
+
+	for n := 0; n < maxTxAttempts; n++ {
+		if n > 0 {
+			wait := txBackoff(n)
+			if !hasTimeFor(ctx, wait+took) {
+				txMetrics.Add("retries_out_of_time", 1)
+				return err
+			}
+
+			txMetrics.Add("retries", 1)
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+		}
+
+		start := time.Now()
+		err = attempt()
+		took = time.Since(start)
+
+		switch retryCode(err) {
+		case codeSerializationFailure:
+			txMetrics.Add("serialization_failures", 1)
+		case codeDeadlockDetected:
+			txMetrics.Add("deadlocks", 1)
+		default:
+			return err
+		}
+	}
+
+	txMetrics.Add("retries_exhausted", 1)
+	return err
+}
+
+// retryCode returns the SQLSTATE of err when the transaction may succeed if
+// it runs again, or "" otherwise.
+func retryCode(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+
+	switch code := string(pqErr.Code); code {
+	case codeSerializationFailure, codeDeadlockDetected:
+		return code
+	}
+
+	return ""
+}
+
+// hasTimeFor reports whether ctx has at least d left before its deadline.
+func hasTimeFor(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) >= d
+}
+
+// txBackoff doubles the wait with every retry and adds jitter so the
+// transactions that collided do not collide again.
+func txBackoff(n int) time.Duration {
+	d := min(baseTxBackoff<<(n-1), maxTxBackoff)
+	return d/2 + rand.N(d/2+1)
 }
 
 // TxMock for Test
@@ -42,4 +161,8 @@ type TxMock struct {
 
 func (m *TxMock) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	return fn(nil)
-}
\ No newline at end of file
+}
+
+func (m *TxMock) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return fn(nil)
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTx(t *testing.T) {
+	serialization := fmt.Errorf("tx function failed: %w", &pq.Error{Code: codeSerializationFailure})
+	deadlock := fmt.Errorf("tx commit failed: %w", &pq.Error{Code: codeDeadlockDetected})
+	uniqueViolation := &pq.Error{Code: "23505"}
+
+	testCases := []struct {
+		name     string
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{"success first time", []error{nil}, 1, nil},
+		{"retries serialization failure", []error{serialization, nil}, 2, nil},
+		{"retries deadlock", []error{deadlock, deadlock, nil}, 3, nil},
+		{"does not retry other errors", []error{uniqueViolation}, 1, uniqueViolation},
+		{"gives up after max attempts", nil, maxTxAttempts, serialization},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+
+			err := retryTx(context.Background(), func() error {
+				attempts++
+				if tc.errs == nil {
+					return serialization
+				}
+				return tc.errs[attempts-1]
+			})
+
+			assert.Equal(t, tc.attempts, attempts)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tc.wantErr))
+			}
+		})
+	}
+}
+
+func TestRetryTxStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	err := retryTx(ctx, func() error {
+		attempts++
+		cancel()
+		return &pq.Error{Code: codeSerializationFailure}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryTxStopsNearDeadline(t *testing.T) {
+	// The first backoff is at least baseTxBackoff/2, more than is left
+	ctx, cancel := context.WithTimeout(context.Background(), baseTxBackoff/4)
+	defer cancel()
+	attempts := 0
+	outOfTime := func() int64 {
+		if v, ok := txMetrics.Get("retries_out_of_time").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := outOfTime()
+
+	err := retryTx(ctx, func() error {
+		attempts++
+		return &pq.Error{Code: codeSerializationFailure}
+	})
+
+	assert.Equal(t, codeSerializationFailure, retryCode(err))
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, before+1, outOfTime())
+}
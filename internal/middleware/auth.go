@@ -1,35 +1,69 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 
-	"github.com/codepnw/simple-bank/config"
-
+	"github.com/codepnw/simple-bank/internal/modules/apikey"
+	"github.com/codepnw/simple-bank/internal/modules/role"
 	"github.com/codepnw/simple-bank/internal/modules/user"
 	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"github.com/codepnw/simple-bank/internal/utils/response"
 	"github.com/codepnw/simple-bank/internal/utils/security"
 	"github.com/gin-gonic/gin"
 )
 
 type Auth interface {
-	Authorized() gin.HandlerFunc
-	Permissions(roles ...user.UserRole) gin.HandlerFunc
+	Authorized(scopes ...string) gin.HandlerFunc
+	Permissions(perms ...role.Permission) gin.HandlerFunc
+	UserSession() gin.HandlerFunc
+}
+
+// TokenRevocation reports whether a verified access token was revoked
+// since it was issued, and tracks when its session was last used.
+type TokenRevocation interface {
+	CheckAccessToken(ctx context.Context, u *security.TokenUser) error
+	TouchSession(ctx context.Context, u *security.TokenUser, ip string)
+}
+
+// PermissionChecker returns the permissions granted to a role.
+type PermissionChecker interface {
+	Permissions(ctx context.Context, role string) ([]role.Permission, error)
+}
+
+// Credentials authenticates the API keys and OAuth2 clients of service
+// integrations.
+type Credentials interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*security.TokenUser, error)
+	CheckClient(ctx context.Context, u *security.TokenUser) error
 }
 
 type auth struct {
-	cfg   *config.EnvConfig
-	token *security.Token
+	token       *security.Token
+	revocations TokenRevocation
+	permissions PermissionChecker
+	credentials Credentials
 }
 
-func AuthMiddleware(cfg *config.EnvConfig) Auth {
+func AuthMiddleware(token *security.Token, revocations TokenRevocation, permissions PermissionChecker, credentials Credentials) Auth {
 	return &auth{
-		cfg:   cfg,
-		token: security.InitJWT(cfg),
+		token:       token,
+		revocations: revocations,
+		permissions: permissions,
+		credentials: credentials,
 	}
 }
 
-func (a *auth) Authorized() gin.HandlerFunc {
+// Authorized authenticates a user session, API key or OAuth2 client. An
+// API key or client needs read for safe requests and write for others,
+// unless it has one of scopes, which allow the route whatever the method.
+// Routes guarded by Permissions pass their permissions as scopes.
+func (a *auth) Authorized(scopes ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.Request.Header.Get("Authorization")
 		if authHeader == "" {
@@ -46,35 +80,139 @@ func (a *auth) Authorized() gin.HandlerFunc {
 		}
 
 		token := parts[1]
-		u, err := a.token.VerifyAccessToken(token)
+
+		var (
+			u   *security.TokenUser
+			err error
+		)
+		if apikey.IsAPIKey(token) {
+			u, err = a.credentials.AuthenticateAPIKey(ctx.Request.Context(), token)
+		} else {
+			u, err = a.verifyToken(ctx.Request.Context(), token)
+		}
 		if err != nil {
-			response.Unauthorized(ctx, err.Error())
+			switch {
+			case errors.Is(err, errTokenInvalid), errors.Is(err, errs.ErrAPIKeyInvalid), errors.Is(err, errs.ErrTokenRevoked):
+				response.Unauthorized(ctx, err.Error())
+			default:
+				response.ErrInternalServer(ctx, err)
+			}
+			ctx.Abort()
+			return
+		}
+
+		if !scopeAllows(u, ctx.Request.Method, scopes) {
+			ctx.Set(utils.ContextKeyToken, u)
+			response.Forbidden(ctx, errs.ErrInsufficientScope)
+			ctx.Abort()
+			return
+		}
+
+		a.revocations.TouchSession(ctx.Request.Context(), u, ctx.ClientIP())
+
+		perms, err := a.permissions.Permissions(ctx.Request.Context(), u.Role)
+		if err != nil {
+			response.ErrInternalServer(ctx, err)
 			ctx.Abort()
 			return
 		}
 
-		ctx.Set(utils.ContextKeyUser, u)
+		// A permission is only granted to an API key or client that has
+		// it as a scope. perms may be shared, so it is filtered into a copy.
+		if u.Restricted() {
+			perms = slices.DeleteFunc(slices.Clone(perms), func(p role.Permission) bool {
+				return !u.HasScope(string(p))
+			})
+		}
+
+		ctx.Set(utils.ContextKeyToken, u)
+		ctx.Set(utils.ContextKeyPermissions, perms)
+		ctx.Set(utils.ContextKeyUser, &user.User{
+			ID:    u.ID,
+			Email: u.Email,
+			Role:  user.UserRole(u.Role),
+		})
 		ctx.Next()
 	}
 }
 
-func (a *auth) Permissions(roles ...user.UserRole) gin.HandlerFunc {
+// UserSession refuses requests made with an API key or OAuth2 client, for
+// routes that manage credentials or the account itself. It runs after
+// Authorized.
+func (a *auth) UserSession() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		u, err := user.CurrentUser(ctx)
-		if err != nil {
+		val, ok := ctx.Get(utils.ContextKeyToken)
+		u, _ := val.(*security.TokenUser)
+		if !ok || u == nil {
+			response.Unauthorized(ctx, "user not found in context")
+			ctx.Abort()
+			return
+		}
+
+		if u.Restricted() {
+			response.Forbidden(ctx, errs.ErrUserSessionRequired)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// errTokenInvalid wraps the reason a bearer token failed to verify.
+var errTokenInvalid = errors.New("invalid token")
+
+// verifyToken checks a JWT access token, that it was not revoked and, for
+// a client token, that the client was not revoked either.
+func (a *auth) verifyToken(ctx context.Context, token string) (*security.TokenUser, error) {
+	u, err := a.token.VerifyAccessToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTokenInvalid, err)
+	}
+
+	if err = a.revocations.CheckAccessToken(ctx, u); err != nil {
+		return nil, err
+	}
+
+	if u.ClientID != "" {
+		if err = a.credentials.CheckClient(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// scopeAllows reports whether u may make a request with method to a route
+// that accepts scopes. Read scopes allow safe requests only, write scopes
+// all.
+func scopeAllows(u *security.TokenUser, method string, scopes []string) bool {
+	if u.HasScope(security.ScopeWrite) || (safeMethod(method) && u.HasScope(security.ScopeRead)) {
+		return true
+	}
+	return slices.ContainsFunc(scopes, u.HasScope)
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Permissions lets the request through when the role of the current user
+// grants all of perms. It runs after Authorized.
+func (a *auth) Permissions(perms ...role.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, err := user.CurrentUser(ctx); err != nil {
 			response.Unauthorized(ctx, err.Error())
 			ctx.Abort()
 			return
 		}
 
-		for _, role := range roles {
-			if u.Role == role {
-				ctx.Next()
-				return
-			}
+		if !role.Granted(ctx, perms...) {
+			response.Forbidden(ctx, errs.ErrPermissionDenied)
+			ctx.Abort()
+			return
 		}
 
-		response.Unauthorized(ctx, "permission denied")
-		ctx.Abort()
+		ctx.Next()
 	}
 }
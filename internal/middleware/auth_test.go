@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/modules/role"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type credentialsStub struct {
+	user *security.TokenUser
+}
+
+func (c *credentialsStub) AuthenticateAPIKey(ctx context.Context, key string) (*security.TokenUser, error) {
+	return c.user, nil
+}
+
+func (c *credentialsStub) CheckClient(ctx context.Context, u *security.TokenUser) error {
+	return nil
+}
+
+type revocationsStub struct{}
+
+func (revocationsStub) CheckAccessToken(ctx context.Context, u *security.TokenUser) error {
+	return nil
+}
+
+func (revocationsStub) TouchSession(ctx context.Context, u *security.TokenUser, ip string) {}
+
+type permissionsStub []role.Permission
+
+func (p permissionsStub) Permissions(ctx context.Context, role string) ([]role.Permission, error) {
+	return p, nil
+}
+
+func TestAuthorizedScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		granted  []role.Permission
+		method   string
+		path     string
+		expected int
+	}{
+		{name: "read key lists transactions", scopes: []string{security.ScopeRead}, method: http.MethodGet, path: "/transactions/", expected: http.StatusOK},
+		{name: "read key cannot transfer", scopes: []string{security.ScopeRead}, method: http.MethodPost, path: "/transactions/transfer", expected: http.StatusForbidden},
+		{name: "read key cannot withdraw", scopes: []string{security.ScopeRead}, method: http.MethodPost, path: "/transactions/withdraw", expected: http.StatusForbidden},
+		{name: "write key transfers", scopes: []string{security.ScopeWrite}, method: http.MethodPost, path: "/transactions/transfer", expected: http.StatusOK},
+		{name: "transfer key transfers", scopes: []string{security.ScopeTransfer}, method: http.MethodPost, path: "/transactions/transfer", expected: http.StatusOK},
+		{name: "transfer key cannot withdraw", scopes: []string{security.ScopeTransfer}, method: http.MethodPost, path: "/transactions/withdraw", expected: http.StatusForbidden},
+		{name: "transfer key cannot list transactions", scopes: []string{security.ScopeTransfer}, method: http.MethodGet, path: "/transactions/", expected: http.StatusForbidden},
+		{name: "transfer key cannot place holds", scopes: []string{security.ScopeTransfer}, granted: []role.Permission{role.PermHoldsManage}, method: http.MethodPost, path: "/holds/", expected: http.StatusForbidden},
+		{name: "permission key places holds", scopes: []string{string(role.PermHoldsManage)}, granted: []role.Permission{role.PermHoldsManage}, method: http.MethodPost, path: "/holds/", expected: http.StatusOK},
+		{name: "permission key cannot transfer", scopes: []string{string(role.PermHoldsManage)}, granted: []role.Permission{role.PermHoldsManage}, method: http.MethodPost, path: "/transactions/transfer", expected: http.StatusForbidden},
+		{name: "permission key without the role permission", scopes: []string{string(role.PermHoldsManage)}, method: http.MethodPost, path: "/holds/", expected: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &security.TokenUser{ID: 1, Email: "a@example.com", Role: "USER", Scopes: tc.scopes, APIKeyID: 1}
+			mid := AuthMiddleware(nil, revocationsStub{}, permissionsStub(tc.granted), &credentialsStub{user: u})
+			ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/transactions/", mid.Authorized(), ok)
+			r.POST("/transactions/transfer", mid.Authorized(security.ScopeTransfer), ok)
+			r.POST("/transactions/withdraw", mid.Authorized(security.ScopeWithdraw), ok)
+			r.POST("/holds/", mid.Authorized(string(role.PermHoldsManage)), mid.Permissions(role.PermHoldsManage), ok)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer sbp_test")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expected, rec.Code)
+		})
+	}
+}
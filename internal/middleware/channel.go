@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/codepnw/simple-bank/internal/modules/channel"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderAPIKey    = "X-API-Key"
+	HeaderChannelID = "X-Channel-ID"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderSignature = "X-Signature"
+)
+
+// DepositChannel authenticates machine-to-machine requests, either with an
+// API key or with an HMAC signature over the request, and stores the
+// channel on the context.
+func DepositChannel(uc channel.ChannelUsecase) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var (
+			ch  *channel.Channel
+			err error
+		)
+
+		if key := ctx.GetHeader(HeaderAPIKey); key != "" {
+			ch, err = uc.AuthenticateAPIKey(ctx.Request.Context(), key)
+		} else {
+			ch, err = authenticateHMAC(ctx, uc)
+		}
+
+		if err != nil {
+			if errors.Is(err, errs.ErrChannelUnauthorized) {
+				response.Unauthorized(ctx, err.Error())
+			} else {
+				response.ErrInternalServer(ctx, err)
+			}
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(utils.ContextKeyChannel, ch)
+		ctx.Next()
+	}
+}
+
+func authenticateHMAC(ctx *gin.Context, uc channel.ChannelUsecase) (*channel.Channel, error) {
+	id, err := strconv.ParseInt(ctx.GetHeader(HeaderChannelID), 10, 64)
+	if err != nil {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return uc.AuthenticateHMAC(ctx.Request.Context(), &channel.SignedRequest{
+		ChannelID: id,
+		Timestamp: ctx.GetHeader(HeaderTimestamp),
+		Signature: ctx.GetHeader(HeaderSignature),
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		Body:      body,
+	})
+}
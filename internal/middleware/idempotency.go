@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/codepnw/simple-bank/internal/modules/channel"
+	"github.com/codepnw/simple-bank/internal/modules/idempotency"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a handler safe to retry. Requests carrying an
+// Idempotency-Key header are stored with their response; a retry with the
+// same key and body replays that response instead of running the handler.
+func Idempotency(uc idempotency.IdempotencyUsecase) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			response.ErrBadRequest(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec, err := uc.Begin(ctx.Request.Context(), idempotencyScope(ctx), key, ctx.Request.Method, ctx.FullPath(), body)
+		if err != nil {
+			switch {
+			case errors.Is(err, errs.ErrIdempotencyKeyInvalid):
+				response.ErrBadRequest(ctx, err)
+			case errors.Is(err, errs.ErrIdempotencyKeyReused), errors.Is(err, errs.ErrIdempotencyInProgress):
+				response.ErrConflict(ctx, err)
+			default:
+				response.ErrInternalServer(ctx, err)
+			}
+			ctx.Abort()
+			return
+		}
+
+		// Replay
+		if rec.Completed() {
+			ctx.Header(HeaderIdempotencyReplayed, "true")
+			ctx.Data(*rec.StatusCode, "application/json; charset=utf-8", rec.Response)
+			ctx.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer, body: new(bytes.Buffer)}
+		ctx.Writer = recorder
+
+		// The outcome must be stored even if the client has already gone away,
+		// that is exactly the case where it will retry.
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+
+		succeeded := false
+		defer func() {
+			if succeeded {
+				return
+			}
+			if err := uc.Release(storeCtx, rec.ID); err != nil {
+				log.Printf("release idempotency key %d failed: %v", rec.ID, err)
+			}
+		}()
+
+		ctx.Next()
+
+		status := recorder.Status()
+		if status < 200 || status >= 300 {
+			return
+		}
+
+		// The request took effect, so the key is never released from here
+		// on. When its response cannot be stored the key stays in progress
+		// until it expires and retries get a conflict instead of running
+		// the request again.
+		succeeded = true
+
+		if err := uc.Complete(storeCtx, rec.ID, status, recorder.body.Bytes()); err != nil {
+			log.Printf("store idempotency key %d failed, it stays reserved: %v", rec.ID, err)
+		}
+	}
+}
+
+// idempotencyScope keys are scoped per user, and per deposit channel with
+// the negated channel ID; unauthenticated requests share scope 0.
+func idempotencyScope(ctx *gin.Context) int64 {
+	if ch, ok := channel.Current(ctx); ok {
+		return -ch.ID
+	}
+
+	val, ok := ctx.Get(utils.ContextKeyToken)
+	if !ok {
+		return 0
+	}
+
+	u, ok := val.(*security.TokenUser)
+	if !ok {
+		return 0
+	}
+
+	return u.ID
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/modules/channel"
+	"github.com/codepnw/simple-bank/internal/modules/idempotency"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryKeys keeps idempotency keys in memory, the way the table does.
+type memoryKeys struct {
+	mu          sync.Mutex
+	nextID      int64
+	keys        map[string]*idempotency.Record
+	completeErr error
+}
+
+func newMemoryKeys() *memoryKeys {
+	return &memoryKeys{keys: make(map[string]*idempotency.Record)}
+}
+
+func memoryKey(userID int64, key string) string {
+	return fmt.Sprintf("%d/%s", userID, key)
+}
+
+func (m *memoryKeys) Reserve(ctx context.Context, rec *idempotency.Record) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[memoryKey(rec.UserID, rec.Key)]; ok {
+		return false, nil
+	}
+
+	m.nextID++
+	rec.ID = m.nextID
+	saved := *rec
+	m.keys[memoryKey(rec.UserID, rec.Key)] = &saved
+	return true, nil
+}
+
+func (m *memoryKeys) Find(ctx context.Context, userID int64, key string) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.keys[memoryKey(userID, key)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *rec
+	return &found, nil
+}
+
+func (m *memoryKeys) Complete(ctx context.Context, id int64, statusCode int, response []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.completeErr != nil {
+		return m.completeErr
+	}
+
+	now := time.Now()
+	for _, rec := range m.keys {
+		if rec.ID == id {
+			rec.StatusCode = &statusCode
+			rec.Response = response
+			rec.CompletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *memoryKeys) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, rec := range m.keys {
+		if rec.ID == id {
+			delete(m.keys, k)
+		}
+	}
+	return nil
+}
+
+type idempotentRequest struct {
+	user     int64
+	channel  int64
+	body     string
+	status   int
+	replayed bool
+}
+
+// idempotencyRouter runs handler behind Idempotency. Requests are made by the
+// user or channel named in the X-User and X-Channel headers.
+func idempotencyRouter(handler gin.HandlerFunc) *gin.Engine {
+	return idempotencyRouterWith(newMemoryKeys(), handler)
+}
+
+func idempotencyRouterWith(keys *memoryKeys, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	identify := func(ctx *gin.Context) {
+		if id, err := strconv.ParseInt(ctx.GetHeader("X-Channel"), 10, 64); err == nil {
+			ctx.Set(utils.ContextKeyChannel, &channel.Channel{ID: id})
+		}
+		if id, err := strconv.ParseInt(ctx.GetHeader("X-User"), 10, 64); err == nil {
+			ctx.Set(utils.ContextKeyToken, &security.TokenUser{ID: id})
+		}
+	}
+
+	uc := idempotency.NewIdempotencyUsecase(keys)
+	r.POST("/transfer", identify, Idempotency(uc), handler)
+	return r
+}
+
+func sendIdempotent(r *gin.Engine, req idempotentRequest) *httptest.ResponseRecorder {
+	httpReq := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(req.body))
+	httpReq.Header.Set(HeaderIdempotencyKey, "key-1")
+	if req.user != 0 {
+		httpReq.Header.Set("X-User", strconv.FormatInt(req.user, 10))
+	}
+	if req.channel != 0 {
+		httpReq.Header.Set("X-Channel", strconv.FormatInt(req.channel, 10))
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httpReq)
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	tests := []struct {
+		name          string
+		handlerStatus []int
+		requests      []idempotentRequest
+		expectedCalls int
+	}{
+		{
+			name:          "completed key is replayed",
+			handlerStatus: []int{http.StatusCreated},
+			requests: []idempotentRequest{
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated, replayed: true},
+			},
+			expectedCalls: 1,
+		},
+		{
+			name:          "same key with a different body",
+			handlerStatus: []int{http.StatusCreated},
+			requests: []idempotentRequest{
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{user: 1, body: `{"amount":"20"}`, status: http.StatusConflict},
+			},
+			expectedCalls: 1,
+		},
+		{
+			name:          "key is released after a failed request",
+			handlerStatus: []int{http.StatusBadRequest, http.StatusCreated},
+			requests: []idempotentRequest{
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusBadRequest},
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:          "keys are scoped per user",
+			handlerStatus: []int{http.StatusCreated, http.StatusCreated},
+			requests: []idempotentRequest{
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{user: 2, body: `{"amount":"10"}`, status: http.StatusCreated},
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:          "keys are scoped per channel",
+			handlerStatus: []int{http.StatusCreated, http.StatusCreated, http.StatusCreated},
+			requests: []idempotentRequest{
+				{channel: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{channel: 2, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{user: 1, body: `{"amount":"10"}`, status: http.StatusCreated},
+				{channel: 1, body: `{"amount":"10"}`, status: http.StatusCreated, replayed: true},
+			},
+			expectedCalls: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			r := idempotencyRouter(func(ctx *gin.Context) {
+				status := tc.handlerStatus[calls]
+				calls++
+				ctx.JSON(status, gin.H{"call": calls})
+			})
+
+			var first string
+			for i, req := range tc.requests {
+				rec := sendIdempotent(r, req)
+
+				assert.Equal(t, req.status, rec.Code, "request %d", i)
+				if req.replayed {
+					assert.Equal(t, "true", rec.Header().Get(HeaderIdempotencyReplayed), "request %d", i)
+					assert.Equal(t, first, rec.Body.String(), "request %d", i)
+				} else {
+					assert.Empty(t, rec.Header().Get(HeaderIdempotencyReplayed), "request %d", i)
+				}
+				if i == 0 {
+					first = rec.Body.String()
+				}
+			}
+
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	var retry *httptest.ResponseRecorder
+	calls := 0
+
+	var r *gin.Engine
+	r = idempotencyRouter(func(ctx *gin.Context) {
+		calls++
+		if calls == 1 {
+			// The client retries while the first request is still running
+			retry = sendIdempotent(r, idempotentRequest{user: 1, body: `{"amount":"10"}`})
+		}
+		ctx.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	rec := sendIdempotent(r, idempotentRequest{user: 1, body: `{"amount":"10"}`})
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyCompleteFails(t *testing.T) {
+	keys := newMemoryKeys()
+	keys.completeErr = errors.New("connection reset")
+	calls := 0
+
+	r := idempotencyRouterWith(keys, func(ctx *gin.Context) {
+		calls++
+		ctx.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	rec := sendIdempotent(r, idempotentRequest{user: 1, body: `{"amount":"10"}`})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// The money moved, so a retry must not run the request again
+	retry := sendIdempotent(r, idempotentRequest{user: 1, body: `{"amount":"10"}`})
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.Equal(t, 1, calls)
+}
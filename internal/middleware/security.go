@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/gin-gonic/gin"
+)
+
+// securityEvents names the responses logged as security events.
+var securityEvents = map[int]string{
+	http.StatusForbidden:       "access_denied",
+	http.StatusTooManyRequests: "throttled",
+}
+
+// SecurityEvents logs every request answered with 403 Forbidden or 429 Too
+// Many Requests as a security event: who tried what, from where, and why
+// it was refused.
+func SecurityEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		event, ok := securityEvents[ctx.Writer.Status()]
+		if !ok {
+			return
+		}
+
+		attrs := []any{
+			"event", event,
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"route", ctx.FullPath(),
+			"ip", ctx.ClientIP(),
+		}
+
+		if val, ok := ctx.Get(utils.ContextKeyToken); ok {
+			if u, ok := val.(*security.TokenUser); ok {
+				attrs = append(attrs, "user_id", u.ID, "role", u.Role)
+			}
+		}
+
+		if err := ctx.Errors.Last(); err != nil {
+			attrs = append(attrs, "reason", err.Error())
+		}
+
+		slog.Warn("security event", attrs...)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+const HeaderOTP = "X-OTP"
+
+// OTPVerifier checks a one-time code of a user.
+type OTPVerifier interface {
+	VerifyTOTP(ctx context.Context, userID int64, code string) error
+}
+
+// StepUp verifies the one-time code in the X-OTP header, when there is one,
+// and marks the request as stepped up. Handlers decide whether they need
+// it. It runs after Authorized.
+func StepUp(verifier OTPVerifier) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		code := ctx.GetHeader(HeaderOTP)
+		if code == "" {
+			ctx.Next()
+			return
+		}
+
+		u, err := user.CurrentUser(ctx)
+		if err != nil {
+			response.Unauthorized(ctx, err.Error())
+			ctx.Abort()
+			return
+		}
+
+		if err = verifier.VerifyTOTP(ctx.Request.Context(), u.ID, code); err != nil {
+			var te *errs.ThrottledError
+			switch {
+			case errors.As(err, &te):
+				response.TooManyRequests(ctx, te.RetryAfter, err)
+			case errors.Is(err, errs.ErrTOTPInvalid), errors.Is(err, errs.ErrTOTPNotEnabled):
+				response.Forbidden(ctx, err)
+			default:
+				response.ErrInternalServer(ctx, err)
+			}
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(utils.ContextKeyStepUp, true)
+		ctx.Next()
+	}
+}
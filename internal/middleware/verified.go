@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// UserFinder loads the stored state of a user.
+type UserFinder interface {
+	GetUserByID(ctx context.Context, id int64) (*user.User, error)
+}
+
+// VerifiedEmail lets the request through only when the current user has
+// verified their email address. It runs after Authorized.
+func VerifiedEmail(users UserFinder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		u, err := user.CurrentUser(ctx)
+		if err != nil {
+			response.Unauthorized(ctx, err.Error())
+			ctx.Abort()
+			return
+		}
+
+		stored, err := users.GetUserByID(ctx.Request.Context(), u.ID)
+		if err != nil {
+			response.ErrInternalServer(ctx, err)
+			ctx.Abort()
+			return
+		}
+
+		if !stored.EmailVerified() {
+			response.Forbidden(ctx, errs.ErrEmailNotVerified)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -1,10 +1,65 @@
 package account
 
+import (
+	"slices"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
 type Account struct {
-	ID       int64         `json:"id"`
-	UserID   int64         `json:"user_id"`
-	Name     string        `json:"name"`
-	Balance  int           `json:"balance"`
-	Currency string        `json:"currency"`
-	Status   accountStatus `json:"status"`
+	ID               int64         `json:"id"`
+	UserID           int64         `json:"user_id"`
+	Name             string        `json:"name"`
+	Balance          money.Money   `json:"balance"`
+	AvailableBalance money.Money   `json:"available_balance"`
+	Currency         string        `json:"currency"`
+	Status           accountStatus `json:"status"`
+}
+
+// CanSend reports whether money may leave the account. Only active
+// accounts can be debited.
+func (a *Account) CanSend() error {
+	if a.Status != StatusActive {
+		return &errs.AccountStatusError{AccountID: a.ID, Status: string(a.Status), Err: errs.ErrAccountCannotSend}
+	}
+	return nil
+}
+
+// CanReceive reports whether money may enter the account. Dormant accounts
+// still receive, e.g. salary or interest.
+func (a *Account) CanReceive() error {
+	if a.Status != StatusActive && a.Status != StatusDormant {
+		return &errs.AccountStatusError{AccountID: a.ID, Status: string(a.Status), Err: errs.ErrAccountCannotReceive}
+	}
+	return nil
+}
+
+// OwnedBy reports whether the account belongs to userID.
+func (a *Account) OwnedBy(userID int64) error {
+	if a.UserID != userID {
+		return errs.ErrAccountNotOwner
+	}
+	return nil
+}
+
+func (a *Account) canTransition(to accountStatus) bool {
+	return slices.Contains(statusTransitions[a.Status], to)
+}
+
+// Hold reserves part of an account balance until it is captured into a
+// transaction, released, or expires.
+type Hold struct {
+	ID             int64       `json:"id"`
+	AccountID      int64       `json:"account_id"`
+	Amount         money.Money `json:"amount"`
+	CapturedAmount money.Money `json:"captured_amount"`
+	Currency       string      `json:"currency"`
+	Reference      *string     `json:"reference"`
+	Status         holdStatus  `json:"status"`
+	TransactionID  *int64      `json:"transaction_id"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      *time.Time  `json:"updated_at"`
 }
@@ -1,15 +1,51 @@
 package account
 
+import "github.com/codepnw/simple-bank/internal/utils/money"
+
 type accountStatus string
 
 const (
-	StatusPending  accountStatus = "PENDING"
-	StatusApproved accountStatus = "APPROVED"
-	StatusRejected accountStatus = "REJECTED"
+	StatusPending accountStatus = "PENDING"
+	StatusActive  accountStatus = "ACTIVE"
+	StatusFrozen  accountStatus = "FROZEN"
+	StatusDormant accountStatus = "DORMANT"
+	StatusClosed  accountStatus = "CLOSED"
 )
 
+// statusTransitions lists the statuses an account may move to from each
+// status. CLOSED is final.
+var statusTransitions = map[accountStatus][]accountStatus{
+	StatusPending: {StatusActive, StatusClosed},
+	StatusActive:  {StatusFrozen, StatusDormant, StatusClosed},
+	StatusFrozen:  {StatusActive, StatusClosed},
+	StatusDormant: {StatusActive, StatusFrozen, StatusClosed},
+}
+
+type holdStatus string
+
+const (
+	HoldActive   holdStatus = "ACTIVE"
+	HoldCaptured holdStatus = "CAPTURED"
+	HoldReleased holdStatus = "RELEASED"
+	HoldExpired  holdStatus = "EXPIRED"
+)
+
+// AccountRequest opens an account. Without a user it is for the current
+// user, and without a currency it is in THB.
 type AccountRequest struct {
-	UserID int64         `json:"user_id"`
-	Name   string        `json:"name"`
-	Status accountStatus `json:"status"`
+	UserID   int64         `json:"user_id" validate:"gte=0"`
+	Name     string        `json:"name" validate:"required,max=30,account_name"`
+	Currency string        `json:"currency" validate:"omitempty,currency"`
+	Status   accountStatus `json:"status"`
+}
+
+type StatusRequest struct {
+	Status accountStatus `json:"status" validate:"required,oneof=ACTIVE FROZEN DORMANT CLOSED"`
+}
+
+// HoldRequest reserves an amount in the account currency, e.g. "10.50".
+type HoldRequest struct {
+	AccountID int64         `json:"account_id" validate:"required"`
+	Amount    money.Decimal `json:"amount" validate:"required"`
+	Reference string        `json:"reference" validate:"max=255"`
 }
@@ -1,20 +1,37 @@
 package account
 
 import (
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/modules/role"
+	"github.com/codepnw/simple-bank/internal/modules/user"
 	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 type accountHandler struct {
-	uc AccountUsecase
+	uc       AccountUsecase
+	validate *validator.Validate
 }
 
 func NewAccountHandler(uc AccountUsecase) *accountHandler {
-	return &accountHandler{uc: uc}
+	return &accountHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
 }
 
 func (h *accountHandler) CreateAccount(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
 	req := new(AccountRequest)
 
 	if err := ctx.ShouldBindJSON(req); err != nil {
@@ -22,6 +39,20 @@ func (h *accountHandler) CreateAccount(ctx *gin.Context) {
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	// Users open accounts for themselves; staff may open one for a customer
+	if req.UserID == 0 {
+		req.UserID = u.ID
+	}
+	if req.UserID != u.ID && !role.Granted(ctx, role.PermAccountsApprove) {
+		response.Forbidden(ctx, errs.ErrAccountNotOwner)
+		return
+	}
+
 	result, err := h.uc.CreateAccount(ctx, req)
 	if err != nil {
 		response.ErrInternalServer(ctx, err)
@@ -38,23 +69,46 @@ func (h *accountHandler) GetAccountByID(ctx *gin.Context) {
 		return
 	}
 
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
 	result, err := h.uc.GetAccountByID(ctx, id)
 	if err != nil {
 		response.ErrInternalServer(ctx, err)
 		return
 	}
 
+	if !role.Granted(ctx, role.PermAccountsReadAll) {
+		if err = result.OwnedBy(u.ID); err != nil {
+			response.Forbidden(ctx, err)
+			return
+		}
+	}
+
 	response.Success(ctx, result)
 }
 
 func (h *accountHandler) ListAccounts(ctx *gin.Context) {
-	// TODO: get user_id from middleware later
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
 	userID, err := utils.GetParamID(ctx, "userID")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
 		return
 	}
 
+	if userID != u.ID && !role.Granted(ctx, role.PermAccountsReadAll) {
+		response.Forbidden(ctx, errs.ErrAccountNotOwner)
+		return
+	}
+
 	result, err := h.uc.ListAccounts(ctx, userID)
 	if err != nil {
 		response.ErrInternalServer(ctx, err)
@@ -64,47 +118,131 @@ func (h *accountHandler) ListAccounts(ctx *gin.Context) {
 	response.Success(ctx, result)
 }
 
-func (h *accountHandler) UpdateStatusPending(ctx *gin.Context) {
+func (h *accountHandler) UpdateStatus(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
 		return
 	}
 
-	if err = h.uc.UpdateStatusPending(ctx, id); err != nil {
-		response.ErrInternalServer(ctx, err)
+	req := new(StatusRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
 		return
 	}
 
-	response.Success(ctx, "updated account pending")
+	result, err := h.uc.UpdateStatus(ctx.Request.Context(), id, req.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrAccountStatusTransition),
+			errors.Is(err, errs.ErrAccountBalanceNotZero):
+			response.ErrConflict(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Success(ctx, result)
 }
 
-func (h *accountHandler) UpdateStatusApproved(ctx *gin.Context) {
+func (h *accountHandler) PlaceHold(ctx *gin.Context) {
+	req := new(HoldRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	// Hold Usecase
+	hold, err := h.uc.PlaceHold(ctx.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrAmountGreaterAccBalance),
+			errors.Is(err, errs.ErrAccountCannotSend):
+			response.ErrConflict(ctx, err)
+		case errors.Is(err, errs.ErrAmountGreaterThanZero),
+			errors.Is(err, errs.ErrCurrencyNotSupported):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Created(ctx, hold)
+}
+
+func (h *accountHandler) GetHold(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
 		return
 	}
 
-	if err = h.uc.UpdateStatusApproved(ctx, id); err != nil {
+	hold, err := h.uc.GetHold(ctx.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, errs.ErrHoldNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
 		response.ErrInternalServer(ctx, err)
 		return
 	}
 
-	response.Success(ctx, "updated account approved")
+	response.Success(ctx, hold)
 }
 
-func (h *accountHandler) UpdateStatusRejected(ctx *gin.Context) {
+func (h *accountHandler) ListHolds(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
 		return
 	}
 
-	if err = h.uc.UpdateStatusRejected(ctx, id); err != nil {
+	holds, err := h.uc.ListHolds(ctx.Request.Context(), id)
+	if err != nil {
 		response.ErrInternalServer(ctx, err)
 		return
 	}
 
-	response.Success(ctx, "updated account rejected")
+	response.Success(ctx, holds)
+}
+
+func (h *accountHandler) ReleaseHold(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	hold, err := h.uc.ReleaseHold(ctx.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrHoldNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrHoldNotActive):
+			response.ErrConflict(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Success(ctx, hold)
 }
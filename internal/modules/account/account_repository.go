@@ -3,18 +3,30 @@ package account
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 )
 
 type AccountRepository interface {
 	Create(ctx context.Context, acc *Account) (*Account, error)
 	FindByID(ctx context.Context, id int64) (*Account, error)
+	FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Account, error)
 	List(ctx context.Context, userID int64) ([]*Account, error)
-	UpdateStatus(ctx context.Context, id int64, status string) error
-	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, balance float64) error
-	GetAccountBalance(ctx context.Context, accountID int64) (float64, error)
-	GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (float64, error)
+	UpdateStatus(ctx context.Context, id int64, from, to accountStatus) error
+	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error
+	GetAccountBalance(ctx context.Context, accountID int64) (money.Money, error)
+	GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (money.Money, error)
+
+	CreateHold(ctx context.Context, hold *Hold) (*Hold, error)
+	FindHold(ctx context.Context, id int64) (*Hold, error)
+	ListHolds(ctx context.Context, accountID int64) ([]*Hold, error)
+	ReleaseHold(ctx context.Context, id int64, status holdStatus) error
+	FindHoldForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error)
+	CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold) error
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
 }
 
 type accountRepository struct {
@@ -27,15 +39,16 @@ func NewAccountRepository(db *sql.DB) AccountRepository {
 
 func (r *accountRepository) Create(ctx context.Context, acc *Account) (*Account, error) {
 	query := `
-		INSERT INTO accounts (user_id, name, balance)
-		VALUES ($1, $2, $3) RETURNING id, currency, status;
+		INSERT INTO accounts (user_id, name, balance, currency)
+		VALUES ($1, $2, $3, $4) RETURNING id, currency, status;
 	`
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		acc.UserID,
 		acc.Name,
-		acc.Balance,
+		acc.Balance.Minor(),
+		acc.Currency,
 	).Scan(
 		&acc.ID,
 		&acc.Currency,
@@ -44,35 +57,82 @@ func (r *accountRepository) Create(ctx context.Context, acc *Account) (*Account,
 	if err != nil {
 		return nil, err
 	}
+	acc.Balance = money.FromMinor(acc.Balance.Minor(), acc.Currency)
+	acc.AvailableBalance = acc.Balance
 
 	return acc, nil
 }
 
+// balanceColumn selects the balance of an account. System accounts are not
+// updated by postings, so theirs is the sum of their ledger entries.
+const balanceColumn = `
+	CASE WHEN system_code IS NULL THEN balance
+	ELSE (SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e WHERE e.account_id = accounts.id)
+	END
+`
+
 func (r *accountRepository) FindByID(ctx context.Context, id int64) (*Account, error) {
 	query := `
-		SELECT id, user_id, name, balance, currency, status
+		SELECT id, user_id, name, ` + balanceColumn + `, held_balance, currency, status
 		FROM accounts WHERE id = $1 LIMIT 1;
 	`
 	acc := new(Account)
+	var userID sql.NullInt64
+	var balance, held int64
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&acc.ID,
-		&acc.UserID,
+		&userID,
 		&acc.Name,
-		&acc.Balance,
+		&balance,
+		&held,
 		&acc.Currency,
 		&acc.Status,
 	)
 	if err != nil {
 		return nil, err
 	}
+	acc.UserID = userID.Int64
+	acc.Balance = money.FromMinor(balance, acc.Currency)
+	acc.AvailableBalance = money.FromMinor(balance-held, acc.Currency)
+
+	return acc, nil
+}
+
+func (r *accountRepository) FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Account, error) {
+	query := `
+		SELECT id, user_id, name, ` + balanceColumn + `, held_balance, currency, status
+		FROM accounts WHERE id = $1 FOR UPDATE
+	`
+	acc := new(Account)
+	var userID sql.NullInt64
+	var balance, held int64
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&acc.ID,
+		&userID,
+		&acc.Name,
+		&balance,
+		&held,
+		&acc.Currency,
+		&acc.Status,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	acc.UserID = userID.Int64
+	acc.Balance = money.FromMinor(balance, acc.Currency)
+	acc.AvailableBalance = money.FromMinor(balance-held, acc.Currency)
 
 	return acc, nil
 }
 
 func (r *accountRepository) List(ctx context.Context, userID int64) ([]*Account, error) {
 	query := `
-		SELECT id, user_id, name, balance, currency, status
+		SELECT id, user_id, name, balance, held_balance, currency, status
 		FROM accounts WHERE user_id = $1
 	`
 	rows, err := r.db.QueryContext(ctx, query, userID)
@@ -85,17 +145,21 @@ func (r *accountRepository) List(ctx context.Context, userID int64) ([]*Account,
 
 	for rows.Next() {
 		acc := new(Account)
+		var balance, held int64
 		err = rows.Scan(
 			&acc.ID,
 			&acc.UserID,
 			&acc.Name,
-			&acc.Balance,
+			&balance,
+			&held,
 			&acc.Currency,
 			&acc.Status,
 		)
 		if err != nil {
 			return nil, err
 		}
+		acc.Balance = money.FromMinor(balance, acc.Currency)
+		acc.AvailableBalance = money.FromMinor(balance-held, acc.Currency)
 		accs = append(accs, acc)
 	}
 
@@ -106,10 +170,43 @@ func (r *accountRepository) List(ctx context.Context, userID int64) ([]*Account,
 	return accs, nil
 }
 
-func (r *accountRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
-	query := `UPDATE accounts SET status = $1 WHERE id = $2`
+// UpdateStatus changes the status only if it is still from, so two staff
+// members cannot apply conflicting transitions. Closing also requires the
+// account to be empty at that moment.
+func (r *accountRepository) UpdateStatus(ctx context.Context, id int64, from, to accountStatus) error {
+	query := `
+		UPDATE accounts SET status = $1
+		WHERE id = $2 AND status = $3
+			AND ($1 <> 'CLOSED' OR (balance = 0 AND held_balance = 0))
+	`
+	res, err := r.db.ExecContext(ctx, query, to, id, from)
+	if err != nil {
+		return err
+	}
 
-	res, err := r.db.ExecContext(ctx, query, status, id)
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrAccountStatusTransition
+	}
+
+	return nil
+}
+
+// UpdateBalanceWithTx adds amount to the balance of an account. It fails
+// with ErrAmountGreaterAccBalance when that would take a customer account
+// below its held balance. System accounts are left alone: every cash
+// movement in a currency posts to the same vault, and writing its row would
+// make them all wait on one another. Their balance is read from the ledger.
+func (r *accountRepository) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
+	query := `
+		UPDATE accounts SET balance = balance + $1
+		WHERE id = $2 AND system_code IS NULL AND balance - held_balance + $1 >= 0
+	`
+	res, err := tx.ExecContext(ctx, query, amount.Minor(), id)
 	if err != nil {
 		return err
 	}
@@ -120,18 +217,169 @@ func (r *accountRepository) UpdateStatus(ctx context.Context, id int64, status s
 	}
 
 	if rows == 0 {
-		return errs.ErrAccountNotFound
+		var system bool
+		err = tx.QueryRowContext(ctx, "SELECT system_code IS NOT NULL FROM accounts WHERE id = $1", id).Scan(&system)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !system {
+			return errs.ErrAmountGreaterAccBalance
+		}
 	}
 
 	return nil
 }
 
-func (r *accountRepository) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, balance float64) error {
+func (r *accountRepository) GetAccountBalance(ctx context.Context, accountID int64) (money.Money, error) {
+	var balance int64
+	var currency string
+	query := `SELECT ` + balanceColumn + `, currency FROM accounts WHERE id = $1`
+
+	err := r.db.QueryRowContext(ctx, query, accountID).Scan(&balance, &currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return money.FromMinor(balance, currency), nil
+}
+
+func (r *accountRepository) GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (money.Money, error) {
+	var balance int64
+	var currency string
+	query := `SELECT balance, currency FROM accounts WHERE id = $1 AND user_id = $2`
+
+	err := r.db.QueryRowContext(ctx, query, accountID, userID).Scan(&balance, &currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return money.FromMinor(balance, currency), nil
+}
+
+const holdColumns = `
+	id, account_id, amount, captured_amount, currency, reference, status,
+	transaction_id, expires_at, created_at, updated_at
+`
+
+func scanHold(row interface{ Scan(dest ...any) error }) (*Hold, error) {
+	h := new(Hold)
+	var amount, captured int64
+
+	err := row.Scan(
+		&h.ID,
+		&h.AccountID,
+		&amount,
+		&captured,
+		&h.Currency,
+		&h.Reference,
+		&h.Status,
+		&h.TransactionID,
+		&h.ExpiresAt,
+		&h.CreatedAt,
+		&h.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	h.Amount = money.FromMinor(amount, h.Currency)
+	h.CapturedAmount = money.FromMinor(captured, h.Currency)
+
+	return h, nil
+}
+
+// CreateHold reserves the amount only while the available balance covers
+// it, so concurrent holds cannot overdraw the account.
+func (r *accountRepository) CreateHold(ctx context.Context, hold *Hold) (*Hold, error) {
 	query := `
-		UPDATE accounts SET balance = balance + $1 
-		WHERE id = $2 AND balance + $1 >= 0
+		WITH acc AS (
+			UPDATE accounts SET held_balance = held_balance + $2
+			WHERE id = $1 AND balance - held_balance >= $2
+			RETURNING id
+		)
+		INSERT INTO account_holds (account_id, amount, currency, reference, expires_at)
+		SELECT id, $2, $3, $4, $5 FROM acc
+		RETURNING id, status, created_at
 	`
-	res, err := tx.ExecContext(ctx, query, balance, id)
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		hold.AccountID,
+		hold.Amount.Minor(),
+		hold.Currency,
+		hold.Reference,
+		hold.ExpiresAt,
+	).Scan(
+		&hold.ID,
+		&hold.Status,
+		&hold.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrAmountGreaterAccBalance
+	}
+	if err != nil {
+		return nil, err
+	}
+	hold.CapturedAmount = money.Zero(hold.Currency)
+
+	return hold, nil
+}
+
+func (r *accountRepository) FindHold(ctx context.Context, id int64) (*Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM account_holds WHERE id = $1 LIMIT 1`
+
+	h, err := scanHold(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (r *accountRepository) ListHolds(ctx context.Context, accountID int64) ([]*Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM account_holds WHERE account_id = $1 ORDER BY id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []*Hold
+
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// ReleaseHold ends an active hold with the given status and gives its
+// amount back to the available balance.
+func (r *accountRepository) ReleaseHold(ctx context.Context, id int64, status holdStatus) error {
+	query := `
+		WITH h AS (
+			UPDATE account_holds SET status = $2, updated_at = NOW()
+			WHERE id = $1 AND status = 'ACTIVE'
+			RETURNING account_id, amount
+		)
+		UPDATE accounts a SET held_balance = a.held_balance - h.amount
+		FROM h WHERE a.id = h.account_id
+	`
+	res, err := r.db.ExecContext(ctx, query, id, status)
 	if err != nil {
 		return err
 	}
@@ -142,32 +390,90 @@ func (r *accountRepository) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx,
 	}
 
 	if rows == 0 {
-		return errs.ErrAccountNotFound
+		return errs.ErrHoldNotActive
 	}
 
 	return nil
 }
 
-func (r *accountRepository) GetAccountBalance(ctx context.Context, accountID int64) (float64, error) {
-	var balance float64
-	query := `SELECT balance FROM accounts WHERE id = $1`
+func (r *accountRepository) FindHoldForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM account_holds WHERE id = $1 FOR UPDATE`
 
-	err := r.db.QueryRowContext(ctx, query, accountID).Scan(&balance)
+	h, err := scanHold(tx.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrHoldNotFound
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return balance, nil
+	return h, nil
 }
 
-func (r *accountRepository) GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (float64, error) {
-	var balance float64
-	query := `SELECT balance FROM accounts WHERE id = $1 AND user_id = $2`
+// CaptureHoldWithTx stores the captured amount and transaction of a locked
+// hold and removes the whole hold from held_balance; any uncaptured rest
+// becomes available again.
+func (r *accountRepository) CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold) error {
+	query := `
+		UPDATE account_holds
+		SET status = 'CAPTURED', captured_amount = $1, transaction_id = $2, updated_at = NOW()
+		WHERE id = $3 AND status = 'ACTIVE'
+	`
+	res, err := tx.ExecContext(ctx, query, hold.CapturedAmount.Minor(), hold.TransactionID, hold.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrHoldNotActive
+	}
+
+	query = `UPDATE accounts SET held_balance = held_balance - $1 WHERE id = $2`
 
-	err := r.db.QueryRowContext(ctx, query, accountID, userID).Scan(&balance)
+	_, err = tx.ExecContext(ctx, query, hold.Amount.Minor(), hold.AccountID)
+	return err
+}
+
+// ExpireHolds releases every active hold that expired at or before now and
+// returns how many were expired.
+func (r *accountRepository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		WITH h AS (
+			UPDATE account_holds SET status = 'EXPIRED', updated_at = NOW()
+			WHERE status = 'ACTIVE' AND expires_at <= $1
+			RETURNING account_id, amount
+		), expired AS (
+			SELECT account_id, SUM(amount) AS amount, COUNT(*) AS holds
+			FROM h GROUP BY account_id
+		)
+		UPDATE accounts a SET held_balance = a.held_balance - e.amount
+		FROM expired e WHERE a.id = e.account_id
+		RETURNING e.holds
+	`
+	rows, err := r.db.QueryContext(ctx, query, now)
 	if err != nil {
 		return 0, err
 	}
+	defer rows.Close()
+
+	var total int64
+
+	for rows.Next() {
+		var holds int64
+		if err = rows.Scan(&holds); err != nil {
+			return 0, err
+		}
+		total += holds
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
 
-	return balance, nil
+	return total, nil
 }
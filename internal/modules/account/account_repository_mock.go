@@ -0,0 +1,141 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccountRepositoryMock struct {
+	mock.Mock
+}
+
+func NewAccountRepositoryMock() *AccountRepositoryMock {
+	return &AccountRepositoryMock{}
+}
+
+func (m *AccountRepositoryMock) Create(ctx context.Context, acc *Account) (*Account, error) {
+	args := m.Called(ctx, acc)
+
+	res, ok := args.Get(0).(*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) FindByID(ctx context.Context, id int64) (*Account, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Account, error) {
+	args := m.Called(ctx, tx, id)
+
+	res, ok := args.Get(0).(*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) List(ctx context.Context, userID int64) ([]*Account, error) {
+	args := m.Called(ctx, userID)
+
+	res, ok := args.Get(0).([]*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) UpdateStatus(ctx context.Context, id int64, from, to accountStatus) error {
+	args := m.Called(ctx, id, from, to)
+	return args.Error(0)
+}
+
+func (m *AccountRepositoryMock) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
+	args := m.Called(ctx, tx, id, amount)
+	return args.Error(0)
+}
+
+func (m *AccountRepositoryMock) GetAccountBalance(ctx context.Context, accountID int64) (money.Money, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(money.Money), args.Error(1)
+}
+
+func (m *AccountRepositoryMock) GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (money.Money, error) {
+	args := m.Called(ctx, accountID, userID)
+	return args.Get(0).(money.Money), args.Error(1)
+}
+
+func (m *AccountRepositoryMock) CreateHold(ctx context.Context, hold *Hold) (*Hold, error) {
+	args := m.Called(ctx, hold)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) FindHold(ctx context.Context, id int64) (*Hold, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) ListHolds(ctx context.Context, accountID int64) ([]*Hold, error) {
+	args := m.Called(ctx, accountID)
+
+	res, ok := args.Get(0).([]*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) ReleaseHold(ctx context.Context, id int64, status holdStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *AccountRepositoryMock) FindHoldForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error) {
+	args := m.Called(ctx, tx, id)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountRepositoryMock) CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold) error {
+	args := m.Called(ctx, tx, hold)
+	return args.Error(0)
+}
+
+func (m *AccountRepositoryMock) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,62 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountStatus(t *testing.T) {
+	testCases := []struct {
+		status     accountStatus
+		canSend    bool
+		canReceive bool
+	}{
+		{StatusPending, false, false},
+		{StatusActive, true, true},
+		{StatusFrozen, false, false},
+		{StatusDormant, false, true},
+		{StatusClosed, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.status), func(t *testing.T) {
+			acc := &Account{ID: 1, Status: tc.status}
+
+			if tc.canSend {
+				assert.NoError(t, acc.CanSend())
+			} else {
+				assert.ErrorIs(t, acc.CanSend(), errs.ErrAccountCannotSend)
+			}
+
+			if tc.canReceive {
+				assert.NoError(t, acc.CanReceive())
+			} else {
+				assert.ErrorIs(t, acc.CanReceive(), errs.ErrAccountCannotReceive)
+			}
+		})
+	}
+}
+
+func TestAccountTransition(t *testing.T) {
+	testCases := []struct {
+		from, to accountStatus
+		allowed  bool
+	}{
+		{StatusPending, StatusActive, true},
+		{StatusPending, StatusFrozen, false},
+		{StatusActive, StatusFrozen, true},
+		{StatusActive, StatusPending, false},
+		{StatusFrozen, StatusActive, true},
+		{StatusDormant, StatusActive, true},
+		{StatusClosed, StatusActive, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.from)+"->"+string(tc.to), func(t *testing.T) {
+			acc := &Account{Status: tc.from}
+			assert.Equal(t, tc.allowed, acc.canTransition(tc.to))
+		})
+	}
+}
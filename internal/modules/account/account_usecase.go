@@ -3,9 +3,11 @@ package account
 import (
 	"context"
 	"database/sql"
+	"slices"
 	"time"
 
 	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 )
 
 const queryTimeout = time.Second * 5
@@ -14,28 +16,52 @@ type AccountUsecase interface {
 	CreateAccount(ctx context.Context, req *AccountRequest) (*Account, error)
 	GetAccountByID(ctx context.Context, id int64) (*Account, error)
 	ListAccounts(ctx context.Context, userID int64) ([]*Account, error)
-	UpdateStatusPending(ctx context.Context, id int64) error
-	UpdateStatusApproved(ctx context.Context, id int64) error
-	UpdateStatusRejected(ctx context.Context, id int64) error
-	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, balance float64) error
+	UpdateStatus(ctx context.Context, id int64, status accountStatus) (*Account, error)
+	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error
+	LockAccountsWithTx(ctx context.Context, tx *sql.Tx, ids ...int64) (map[int64]*Account, error)
+
+	PlaceHold(ctx context.Context, req *HoldRequest) (*Hold, error)
+	GetHold(ctx context.Context, id int64) (*Hold, error)
+	ListHolds(ctx context.Context, accountID int64) ([]*Hold, error)
+	ReleaseHold(ctx context.Context, id int64) (*Hold, error)
+	LockHoldWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error)
+	CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold, amount money.Money, transactionID int64) error
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
 }
 
 type accountUsecase struct {
-	repo AccountRepository
+	repo       AccountRepository
+	holdExpiry time.Duration
 }
 
-func NewAccountUsecse(repo AccountRepository) AccountUsecase {
-	return &accountUsecase{repo: repo}
+// NewAccountUsecse creates the account usecase; holds placed through it
+// expire holdExpiry after they are placed.
+func NewAccountUsecse(repo AccountRepository, holdExpiry time.Duration) AccountUsecase {
+	return &accountUsecase{
+		repo:       repo,
+		holdExpiry: holdExpiry,
+	}
 }
 
 func (uc *accountUsecase) CreateAccount(ctx context.Context, req *AccountRequest) (*Account, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
+	currency := req.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	if !money.IsCurrency(currency) {
+		return nil, errs.ErrCurrencyNotSupported
+	}
+
 	acc := &Account{
-		UserID: req.UserID,
-		Name:   req.Name,
-		Status: StatusPending,
+		UserID:   req.UserID,
+		Name:     req.Name,
+		Balance:  money.Zero(currency),
+		Currency: currency,
+		Status:   StatusPending,
 	}
 
 	result, err := uc.repo.Create(ctx, acc)
@@ -60,40 +86,71 @@ func (uc *accountUsecase) ListAccounts(ctx context.Context, userID int64) ([]*Ac
 	return uc.repo.List(ctx, userID)
 }
 
-func (uc *accountUsecase) UpdateStatusPending(ctx context.Context, id int64) error {
+// UpdateStatus moves an account through its lifecycle. Only the transitions
+// in statusTransitions are allowed, and an account is closed only once it is
+// empty.
+func (uc *accountUsecase) UpdateStatus(ctx context.Context, id int64, status accountStatus) (*Account, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	return uc.repo.UpdateStatus(ctx, id, string(StatusApproved))
-}
+	acc, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errs.ErrAccountNotFound
+	}
 
-func (uc *accountUsecase) UpdateStatusApproved(ctx context.Context, id int64) error {
-	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
-	defer cancel()
+	if !acc.canTransition(status) {
+		return nil, errs.ErrAccountStatusTransition
+	}
 
-	return uc.repo.UpdateStatus(ctx, id, string(StatusApproved))
+	if status == StatusClosed && (!acc.Balance.IsZero() || !acc.AvailableBalance.IsZero()) {
+		return nil, errs.ErrAccountBalanceNotZero
+	}
+
+	if err = uc.repo.UpdateStatus(ctx, id, acc.Status, status); err != nil {
+		return nil, err
+	}
+	acc.Status = status
+
+	return acc, nil
 }
 
-func (uc *accountUsecase) UpdateStatusRejected(ctx context.Context, id int64) error {
+func (uc *accountUsecase) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	return uc.repo.UpdateStatus(ctx, id, string(StatusRejected))
+	if amount.IsZero() {
+		return errs.ErrAccountAmountNotZero
+	}
+
+	return uc.repo.UpdateBalanceWithTx(ctx, tx, id, amount)
 }
 
-func (uc *accountUsecase) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount float64) error {
+// LockAccountsWithTx locks the accounts with SELECT ... FOR UPDATE in
+// ascending ID order and returns them by ID. Every money movement locks
+// through here, including the system accounts it touches, so two
+// transactions never wait on each other's rows in opposite order.
+func (uc *accountUsecase) LockAccountsWithTx(ctx context.Context, tx *sql.Tx, ids ...int64) (map[int64]*Account, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	if amount == 0 {
-		return errs.ErrAccountAmountNotZero
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	accounts := make(map[int64]*Account, len(sorted))
+	for _, id := range sorted {
+		acc, err := uc.repo.FindByIDForUpdateWithTx(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = acc
 	}
 
-	return uc.repo.UpdateBalanceWithTx(ctx, tx, id, amount)
+	return accounts, nil
 }
 
 // GetAccountBalance For Admin
-func (uc *accountUsecase) GetAccountBalance(ctx context.Context, accountID, userID int64) (float64, error) {
+func (uc *accountUsecase) GetAccountBalance(ctx context.Context, accountID, userID int64) (money.Money, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
@@ -101,9 +158,135 @@ func (uc *accountUsecase) GetAccountBalance(ctx context.Context, accountID, user
 }
 
 // GetAccountBalanceByUserID For User
-func (uc *accountUsecase) GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (float64, error) {
+func (uc *accountUsecase) GetAccountBalanceByUserID(ctx context.Context, accountID, userID int64) (money.Money, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	return uc.repo.GetAccountBalanceByUserID(ctx, accountID, userID)
 }
+
+// PlaceHold reserves money on an account. The ledger balance is unchanged;
+// only the available balance goes down until the hold ends.
+func (uc *accountUsecase) PlaceHold(ctx context.Context, req *HoldRequest) (*Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	acc, err := uc.repo.FindByID(ctx, req.AccountID)
+	if err != nil {
+		return nil, errs.ErrAccountNotFound
+	}
+
+	if err = acc.CanSend(); err != nil {
+		return nil, err
+	}
+
+	amount, err := req.Amount.Parse(acc.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if !amount.IsPositive() {
+		return nil, errs.ErrAmountGreaterThanZero
+	}
+
+	hold := &Hold{
+		AccountID: acc.ID,
+		Amount:    amount,
+		Currency:  acc.Currency,
+		ExpiresAt: time.Now().Add(uc.holdExpiry),
+	}
+	if req.Reference != "" {
+		hold.Reference = &req.Reference
+	}
+
+	return uc.repo.CreateHold(ctx, hold)
+}
+
+func (uc *accountUsecase) GetHold(ctx context.Context, id int64) (*Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.FindHold(ctx, id)
+}
+
+func (uc *accountUsecase) ListHolds(ctx context.Context, accountID int64) ([]*Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	holds, err := uc.repo.ListHolds(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if holds == nil {
+		holds = []*Hold{}
+	}
+
+	return holds, nil
+}
+
+func (uc *accountUsecase) ReleaseHold(ctx context.Context, id int64) (*Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.repo.ReleaseHold(ctx, id, HoldReleased); err != nil {
+		if _, findErr := uc.repo.FindHold(ctx, id); findErr != nil {
+			return nil, findErr
+		}
+		return nil, err
+	}
+
+	return uc.repo.FindHold(ctx, id)
+}
+
+// LockHoldWithTx locks a hold for capture. Holds that have expired but were
+// not yet swept by the expiry worker cannot be captured either.
+func (uc *accountUsecase) LockHoldWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	hold, err := uc.repo.FindHoldForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hold.Status != HoldActive || !hold.ExpiresAt.After(time.Now()) {
+		return nil, errs.ErrHoldNotActive
+	}
+
+	return hold, nil
+}
+
+// CaptureHoldWithTx settles a locked hold for amount, which may be less than
+// the held amount. The whole hold is lifted so the caller can then debit the
+// captured amount from the balance in the same transaction.
+func (uc *accountUsecase) CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold, amount money.Money, transactionID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cmp, err := amount.Cmp(hold.Amount)
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return errs.ErrHoldCaptureExceeds
+	}
+
+	hold.CapturedAmount = amount
+	hold.TransactionID = &transactionID
+
+	if err = uc.repo.CaptureHoldWithTx(ctx, tx, hold); err != nil {
+		return err
+	}
+	hold.Status = HoldCaptured
+
+	return nil
+}
+
+func (uc *accountUsecase) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.ExpireHolds(ctx, now)
+}
@@ -3,7 +3,9 @@ package account
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	"github.com/codepnw/simple-bank/internal/utils/money"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -36,22 +38,88 @@ func (m *AccountUsecaseMock) ListAccounts(ctx context.Context, userID int64) ([]
 	return args.Get(0).([]*Account), args.Error(1)
 }
 
-func (m *AccountUsecaseMock) UpdateStatusPending(ctx context.Context, id int64) error {
-	args := m.Called(ctx, id)
+func (m *AccountUsecaseMock) UpdateStatus(ctx context.Context, id int64, status accountStatus) (*Account, error) {
+	args := m.Called(ctx, id, status)
+
+	res, ok := args.Get(0).(*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountUsecaseMock) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
+	args := m.Called(ctx, tx, id, amount)
 	return args.Error(0)
 }
 
-func (m *AccountUsecaseMock) UpdateStatusApproved(ctx context.Context, id int64) error {
+func (m *AccountUsecaseMock) LockAccountsWithTx(ctx context.Context, tx *sql.Tx, ids ...int64) (map[int64]*Account, error) {
+	args := m.Called(ctx, tx, ids)
+
+	res, ok := args.Get(0).(map[int64]*Account)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountUsecaseMock) PlaceHold(ctx context.Context, req *HoldRequest) (*Hold, error) {
+	args := m.Called(ctx, req)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountUsecaseMock) GetHold(ctx context.Context, id int64) (*Hold, error) {
 	args := m.Called(ctx, id)
-	return args.Error(0)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
 }
 
-func (m *AccountUsecaseMock) UpdateStatusRejected(ctx context.Context, id int64) error {
+func (m *AccountUsecaseMock) ListHolds(ctx context.Context, accountID int64) ([]*Hold, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).([]*Hold), args.Error(1)
+}
+
+func (m *AccountUsecaseMock) ReleaseHold(ctx context.Context, id int64) (*Hold, error) {
 	args := m.Called(ctx, id)
-	return args.Error(0)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
 }
 
-func (m *AccountUsecaseMock) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id int64, balance float64) error {
-	args := m.Called(ctx, tx, id, balance)
+func (m *AccountUsecaseMock) LockHoldWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error) {
+	args := m.Called(ctx, tx, id)
+
+	res, ok := args.Get(0).(*Hold)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *AccountUsecaseMock) CaptureHoldWithTx(ctx context.Context, tx *sql.Tx, hold *Hold, amount money.Money, transactionID int64) error {
+	args := m.Called(ctx, tx, hold, amount, transactionID)
 	return args.Error(0)
 }
+
+func (m *AccountUsecaseMock) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
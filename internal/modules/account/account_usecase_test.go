@@ -0,0 +1,197 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlaceHold(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      accountStatus
+		amount      money.Decimal
+		mockSetup   func(repo *AccountRepositoryMock)
+		expectedErr error
+	}{
+		{
+			name:   "hold on an active account",
+			status: StatusActive,
+			amount: "25.50",
+			mockSetup: func(repo *AccountRepositoryMock) {
+				repo.On("CreateHold", mock.Anything, mock.MatchedBy(func(h *Hold) bool {
+					return h.AccountID == 1 && h.Amount == money.FromMinor(2550, "THB") && h.ExpiresAt.After(time.Now())
+				})).Return(&Hold{ID: 5, AccountID: 1, Status: HoldActive}, nil)
+			},
+		},
+		{
+			name:   "hold larger than the available balance",
+			status: StatusActive,
+			amount: "1000",
+			mockSetup: func(repo *AccountRepositoryMock) {
+				repo.On("CreateHold", mock.Anything, mock.Anything).Return(nil, errs.ErrAmountGreaterAccBalance)
+			},
+			expectedErr: errs.ErrAmountGreaterAccBalance,
+		},
+		{
+			name:        "pending account",
+			status:      StatusPending,
+			amount:      "10",
+			mockSetup:   func(repo *AccountRepositoryMock) {},
+			expectedErr: errs.ErrAccountCannotSend,
+		},
+		{
+			name:        "frozen account",
+			status:      StatusFrozen,
+			amount:      "10",
+			mockSetup:   func(repo *AccountRepositoryMock) {},
+			expectedErr: errs.ErrAccountCannotSend,
+		},
+		{
+			name:        "dormant account",
+			status:      StatusDormant,
+			amount:      "10",
+			mockSetup:   func(repo *AccountRepositoryMock) {},
+			expectedErr: errs.ErrAccountCannotSend,
+		},
+		{
+			name:        "closed account",
+			status:      StatusClosed,
+			amount:      "10",
+			mockSetup:   func(repo *AccountRepositoryMock) {},
+			expectedErr: errs.ErrAccountCannotSend,
+		},
+		{
+			name:        "zero amount",
+			status:      StatusActive,
+			amount:      "0",
+			mockSetup:   func(repo *AccountRepositoryMock) {},
+			expectedErr: errs.ErrAmountGreaterThanZero,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewAccountRepositoryMock()
+			repo.On("FindByID", mock.Anything, int64(1)).Return(&Account{ID: 1, Currency: "THB", Status: tc.status}, nil)
+			tc.mockSetup(repo)
+
+			uc := NewAccountUsecse(repo, time.Hour)
+			hold, err := uc.PlaceHold(context.Background(), &HoldRequest{AccountID: 1, Amount: tc.amount})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, hold)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), hold.ID)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReleaseHold(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockSetup   func(repo *AccountRepositoryMock)
+		expectedErr error
+	}{
+		{
+			name: "active hold is released",
+			mockSetup: func(repo *AccountRepositoryMock) {
+				repo.On("ReleaseHold", mock.Anything, int64(5), HoldReleased).Return(nil)
+				repo.On("FindHold", mock.Anything, int64(5)).Return(&Hold{ID: 5, Status: HoldReleased}, nil)
+			},
+		},
+		{
+			name: "hold that is not active",
+			mockSetup: func(repo *AccountRepositoryMock) {
+				repo.On("ReleaseHold", mock.Anything, int64(5), HoldReleased).Return(errs.ErrHoldNotActive)
+				repo.On("FindHold", mock.Anything, int64(5)).Return(&Hold{ID: 5, Status: HoldCaptured}, nil)
+			},
+			expectedErr: errs.ErrHoldNotActive,
+		},
+		{
+			name: "hold not found",
+			mockSetup: func(repo *AccountRepositoryMock) {
+				repo.On("ReleaseHold", mock.Anything, int64(5), HoldReleased).Return(errs.ErrHoldNotActive)
+				repo.On("FindHold", mock.Anything, int64(5)).Return(nil, errs.ErrHoldNotFound)
+			},
+			expectedErr: errs.ErrHoldNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewAccountRepositoryMock()
+			tc.mockSetup(repo)
+
+			uc := NewAccountUsecse(repo, time.Hour)
+			hold, err := uc.ReleaseHold(context.Background(), 5)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, hold)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, HoldReleased, hold.Status)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLockHold(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		hold        *Hold
+		expectedErr error
+	}{
+		{
+			name: "active hold",
+			hold: &Hold{ID: 5, Status: HoldActive, ExpiresAt: now.Add(time.Hour)},
+		},
+		{
+			name:        "hold past expires_at not yet swept",
+			hold:        &Hold{ID: 5, Status: HoldActive, ExpiresAt: now.Add(-time.Second)},
+			expectedErr: errs.ErrHoldNotActive,
+		},
+		{
+			name:        "released hold",
+			hold:        &Hold{ID: 5, Status: HoldReleased, ExpiresAt: now.Add(time.Hour)},
+			expectedErr: errs.ErrHoldNotActive,
+		},
+		{
+			name:        "expired hold",
+			hold:        &Hold{ID: 5, Status: HoldExpired, ExpiresAt: now.Add(-time.Hour)},
+			expectedErr: errs.ErrHoldNotActive,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewAccountRepositoryMock()
+			repo.On("FindHoldForUpdateWithTx", mock.Anything, mock.Anything, int64(5)).Return(tc.hold, nil)
+
+			uc := NewAccountUsecse(repo, time.Hour)
+			hold, err := uc.LockHoldWithTx(context.Background(), nil, 5)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, hold)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.hold, hold)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package apikey
+
+import "time"
+
+type KeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ClientRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	UserID int64    `json:"user_id" validate:"required,gt=0"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+// TokenRequest is a token request of RFC 6749, section 4.4. The client
+// authenticates with HTTP Basic or with client_id and client_secret in the
+// form.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+// TokenResponse is an access token response of RFC 6749, section 5.1.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
@@ -0,0 +1,244 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type apiKeyHandler struct {
+	uc       APIKeyUsecase
+	validate *validator.Validate
+}
+
+func NewAPIKeyHandler(uc APIKeyUsecase) *apiKeyHandler {
+	return &apiKeyHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
+}
+
+func (h *apiKeyHandler) CreateKey(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	req := new(KeyRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	result, err := h.uc.CreateKey(ctx.Request.Context(), u.ID, req)
+	if err != nil {
+		if errors.Is(err, errs.ErrScopeInvalid) || errors.Is(err, errs.ErrAPIKeyExpiryInvalid) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
+func (h *apiKeyHandler) ListKeys(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	h.listKeys(ctx, u.ID)
+}
+
+func (h *apiKeyHandler) RevokeKey(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	h.revokeKey(ctx, u.ID)
+}
+
+// ListUserKeys lists the API keys of any user, for admins.
+func (h *apiKeyHandler) ListUserKeys(ctx *gin.Context) {
+	userID, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	h.listKeys(ctx, userID)
+}
+
+// RevokeUserKey revokes an API key of any user, for admins.
+func (h *apiKeyHandler) RevokeUserKey(ctx *gin.Context) {
+	userID, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	h.revokeKey(ctx, userID)
+}
+
+func (h *apiKeyHandler) listKeys(ctx *gin.Context, userID int64) {
+	result, err := h.uc.ListKeys(ctx.Request.Context(), userID)
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *apiKeyHandler) revokeKey(ctx *gin.Context, userID int64) {
+	id, err := utils.GetParamID(ctx, "keyID")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err = h.uc.RevokeKey(ctx.Request.Context(), userID, id); err != nil {
+		if errors.Is(err, errs.ErrAPIKeyNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "api key revoked")
+}
+
+func (h *apiKeyHandler) CreateClient(ctx *gin.Context) {
+	req := new(ClientRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	result, err := h.uc.CreateClient(ctx.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrScopeInvalid):
+			response.ErrBadRequest(ctx, err)
+		case errors.Is(err, errs.ErrUserNotFound):
+			response.ErrNotFound(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
+func (h *apiKeyHandler) ListClients(ctx *gin.Context) {
+	result, err := h.uc.ListClients(ctx.Request.Context())
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *apiKeyHandler) RevokeClient(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err = h.uc.RevokeClient(ctx.Request.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrClientNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "client revoked")
+}
+
+// Token is the OAuth2 token endpoint. It takes a form and answers in the
+// format of RFC 6749 rather than the response envelope, as OAuth2
+// libraries expect.
+func (h *apiKeyHandler) Token(ctx *gin.Context) {
+	req := &TokenRequest{
+		GrantType: ctx.PostForm("grant_type"),
+		Scope:     ctx.PostForm("scope"),
+	}
+
+	id, secret, basic := ctx.Request.BasicAuth()
+	if basic {
+		// RFC 6749, section 2.3.1: both are form-encoded before Basic.
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	} else {
+		req.ClientID = ctx.PostForm("client_id")
+		req.ClientSecret = ctx.PostForm("client_secret")
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	if req.ClientID == "" {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "client authentication is missing")
+		return
+	}
+
+	result, err := h.uc.Token(ctx.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrUnsupportedGrantType):
+			oauthError(ctx, http.StatusBadRequest, "unsupported_grant_type", err.Error())
+		case errors.Is(err, errs.ErrScopeInvalid):
+			oauthError(ctx, http.StatusBadRequest, "invalid_scope", err.Error())
+		case errors.Is(err, errs.ErrClientInvalid):
+			if basic {
+				ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			oauthError(ctx, http.StatusUnauthorized, "invalid_client", err.Error())
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// oauthError answers with an error response of RFC 6749, section 5.2.
+func oauthError(ctx *gin.Context, status int, code, description string) {
+	ctx.JSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
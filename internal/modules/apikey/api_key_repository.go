@@ -0,0 +1,237 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	CreateKey(ctx context.Context, key *APIKey) (*APIKey, error)
+	ListKeys(ctx context.Context, userID int64) ([]*APIKey, error)
+	RevokeKey(ctx context.Context, userID, id int64) error
+	UseKey(ctx context.Context, keyHash string) (*APIKey, error)
+
+	CreateClient(ctx context.Context, client *Client) (*Client, error)
+	FindClient(ctx context.Context, clientID string) (*Client, error)
+	ListClients(ctx context.Context) ([]*Client, error)
+	RevokeClient(ctx context.Context, id int64) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const keyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+func scanKey(row interface{ Scan(dest ...any) error }) (*APIKey, error) {
+	k := new(APIKey)
+
+	err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		pq.Array(&k.Scopes),
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.CreatedAt,
+		&k.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func (r *apiKeyRepository) CreateKey(ctx context.Context, key *APIKey) (*APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) ListKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) RevokeKey(ctx context.Context, userID, id int64) error {
+	query := `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// UseKey finds an active key by its hash and records that it was used.
+func (r *apiKeyRepository) UseKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING ` + keyColumns
+
+	return scanKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+const clientColumns = `id, client_id, secret_hash, name, user_id, scopes, created_at, revoked_at`
+
+func scanClient(row interface{ Scan(dest ...any) error }) (*Client, error) {
+	c := new(Client)
+
+	err := row.Scan(
+		&c.ID,
+		&c.ClientID,
+		&c.SecretHash,
+		&c.Name,
+		&c.UserID,
+		pq.Array(&c.Scopes),
+		&c.CreatedAt,
+		&c.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *apiKeyRepository) CreateClient(ctx context.Context, client *Client) (*Client, error) {
+	query := `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, user_id, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		client.ClientID,
+		client.SecretHash,
+		client.Name,
+		client.UserID,
+		pq.Array(client.Scopes),
+	).Scan(&client.ID, &client.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (r *apiKeyRepository) FindClient(ctx context.Context, clientID string) (*Client, error) {
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE client_id = $1`
+	return scanClient(r.db.QueryRowContext(ctx, query, clientID))
+}
+
+func (r *apiKeyRepository) ListClients(ctx context.Context) ([]*Client, error) {
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []*Client{}
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (r *apiKeyRepository) RevokeClient(ctx context.Context, id int64) error {
+	query := `
+		UPDATE oauth_clients SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrClientNotFound
+	}
+
+	return nil
+}
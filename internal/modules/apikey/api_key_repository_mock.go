@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type apiKeyRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *apiKeyRepositoryMock) CreateKey(ctx context.Context, key *APIKey) (*APIKey, error) {
+	args := m.Called(ctx, key)
+
+	res, ok := args.Get(0).(*APIKey)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) ListKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*APIKey), args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) RevokeKey(ctx context.Context, userID, id int64) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *apiKeyRepositoryMock) UseKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	args := m.Called(ctx, keyHash)
+
+	res, ok := args.Get(0).(*APIKey)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) CreateClient(ctx context.Context, client *Client) (*Client, error) {
+	args := m.Called(ctx, client)
+
+	res, ok := args.Get(0).(*Client)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) FindClient(ctx context.Context, clientID string) (*Client, error) {
+	args := m.Called(ctx, clientID)
+
+	res, ok := args.Get(0).(*Client)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) ListClients(ctx context.Context) ([]*Client, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*Client), args.Error(1)
+}
+
+func (m *apiKeyRepositoryMock) RevokeClient(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
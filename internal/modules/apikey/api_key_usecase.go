@@ -0,0 +1,258 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codepnw/simple-bank/config"
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+)
+
+const (
+	queryTimeout = time.Second * 5
+
+	GrantClientCredentials = "client_credentials"
+)
+
+type APIKeyUsecase interface {
+	CreateKey(ctx context.Context, userID int64, req *KeyRequest) (*KeyCredentials, error)
+	ListKeys(ctx context.Context, userID int64) ([]*APIKey, error)
+	RevokeKey(ctx context.Context, userID, id int64) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*security.TokenUser, error)
+
+	CreateClient(ctx context.Context, req *ClientRequest) (*ClientCredentials, error)
+	ListClients(ctx context.Context) ([]*Client, error)
+	RevokeClient(ctx context.Context, id int64) error
+	Token(ctx context.Context, req *TokenRequest) (*TokenResponse, error)
+	CheckClient(ctx context.Context, u *security.TokenUser) error
+}
+
+type apiKeyUsecase struct {
+	repo        APIKeyRepository
+	userUsecase user.UserUsecase
+	jwt         *security.Token
+	tokenExpiry time.Duration
+	now         func() time.Time
+}
+
+func NewAPIKeyUsecase(cfg *config.EnvConfig, token *security.Token, repo APIKeyRepository, userUsecase user.UserUsecase) APIKeyUsecase {
+	return &apiKeyUsecase{
+		repo:        repo,
+		userUsecase: userUsecase,
+		jwt:         token,
+		tokenExpiry: cfg.Auth.ClientTokenExpiry,
+		now:         time.Now,
+	}
+}
+
+// CreateKey creates an API key of a user. The key is only stored hashed
+// and is never shown again.
+func (uc *apiKeyUsecase) CreateKey(ctx context.Context, userID int64, req *KeyRequest) (*KeyCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if !validScopes(req.Scopes) {
+		return nil, errs.ErrScopeInvalid
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(uc.now()) {
+		return nil, errs.ErrAPIKeyExpiryInvalid
+	}
+
+	secret := KeyPrefix + rand.Text()
+	key := &APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:displayPrefixLength],
+		KeyHash:   hashSecret(secret),
+		Scopes:    normalizeScopes(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	created, err := uc.repo.CreateKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyCredentials{APIKey: created, Key: secret}, nil
+}
+
+func (uc *apiKeyUsecase) ListKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.ListKeys(ctx, userID)
+}
+
+func (uc *apiKeyUsecase) RevokeKey(ctx context.Context, userID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.RevokeKey(ctx, userID, id)
+}
+
+// AuthenticateAPIKey returns the user an active API key acts as, limited
+// to the scopes of the key.
+func (uc *apiKeyUsecase) AuthenticateAPIKey(ctx context.Context, secret string) (*security.TokenUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	key, err := uc.repo.UseKey(ctx, hashSecret(secret))
+	if err != nil {
+		if errors.Is(err, errs.ErrAPIKeyNotFound) {
+			return nil, errs.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	u, err := uc.userUsecase.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil, errs.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	return &security.TokenUser{
+		ID:       u.ID,
+		Email:    u.Email,
+		Role:     string(u.Role),
+		Scopes:   key.Scopes,
+		APIKeyID: key.ID,
+	}, nil
+}
+
+// CreateClient registers an OAuth2 client acting as an existing user. The
+// secret is only stored hashed and is never shown again.
+func (uc *apiKeyUsecase) CreateClient(ctx context.Context, req *ClientRequest) (*ClientCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if !validScopes(req.Scopes) {
+		return nil, errs.ErrScopeInvalid
+	}
+
+	if _, err := uc.userUsecase.GetUserByID(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	secret := rand.Text()
+	client := &Client{
+		ClientID:   clientIDPrefix + rand.Text(),
+		SecretHash: hashSecret(secret),
+		Name:       req.Name,
+		UserID:     req.UserID,
+		Scopes:     normalizeScopes(req.Scopes),
+	}
+
+	created, err := uc.repo.CreateClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientCredentials{Client: created, ClientSecret: secret}, nil
+}
+
+func (uc *apiKeyUsecase) ListClients(ctx context.Context) ([]*Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.ListClients(ctx)
+}
+
+// RevokeClient also ends the tokens the client already has; CheckClient
+// refuses them from then on.
+func (uc *apiKeyUsecase) RevokeClient(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.RevokeClient(ctx, id)
+}
+
+// Token issues an access token for the client-credentials grant. Without a
+// requested scope the token gets every scope of the client.
+func (uc *apiKeyUsecase) Token(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if req.GrantType != GrantClientCredentials {
+		return nil, errs.ErrUnsupportedGrantType
+	}
+
+	client, err := uc.repo.FindClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, errs.ErrClientNotFound) {
+			return nil, errs.ErrClientInvalid
+		}
+		return nil, err
+	}
+
+	hash := hashSecret(req.ClientSecret)
+	if client.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+		return nil, errs.ErrClientInvalid
+	}
+
+	scopes := client.Scopes
+	if req.Scope != "" {
+		scopes = normalizeScopes(strings.Fields(req.Scope))
+		for _, scope := range scopes {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, errs.ErrScopeInvalid
+			}
+		}
+	}
+
+	u, err := uc.userUsecase.GetUserByID(ctx, client.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil, errs.ErrClientInvalid
+		}
+		return nil, err
+	}
+
+	token, err := uc.jwt.GenerateClientToken(&security.TokenUser{
+		ID:       u.ID,
+		Email:    u.Email,
+		Role:     string(u.Role),
+		Scopes:   scopes,
+		ClientID: client.ClientID,
+	}, uc.tokenExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(uc.tokenExpiry / time.Second),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// CheckClient returns ErrTokenRevoked when the client of a token was
+// revoked since the token was issued.
+func (uc *apiKeyUsecase) CheckClient(ctx context.Context, u *security.TokenUser) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	client, err := uc.repo.FindClient(ctx, u.ClientID)
+	if err != nil {
+		if errors.Is(err, errs.ErrClientNotFound) {
+			return errs.ErrTokenRevoked
+		}
+		return err
+	}
+
+	if client.RevokedAt != nil || client.UserID != u.ID {
+		return errs.ErrTokenRevoked
+	}
+
+	return nil
+}
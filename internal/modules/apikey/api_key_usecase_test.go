@@ -0,0 +1,149 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateKey(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		req         *KeyRequest
+		expectedErr error
+	}{
+		{name: "read only", req: &KeyRequest{Name: "export", Scopes: []string{"read"}}},
+		{name: "with permission", req: &KeyRequest{Name: "audit", Scopes: []string{"read", "ledger:verify"}}},
+		{name: "unknown scope", req: &KeyRequest{Name: "x", Scopes: []string{"admin"}}, expectedErr: errs.ErrScopeInvalid},
+		{name: "expired", req: &KeyRequest{Name: "x", Scopes: []string{"read"}, ExpiresAt: &past}, expectedErr: errs.ErrAPIKeyExpiryInvalid},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stored *APIKey
+			repo := new(apiKeyRepositoryMock)
+			if tc.expectedErr == nil {
+				repo.On("CreateKey", mock.Anything, mock.MatchedBy(func(k *APIKey) bool {
+					stored = k
+					return true
+				})).Return(&APIKey{ID: 1}, nil)
+			}
+
+			uc := &apiKeyUsecase{repo: repo, now: func() time.Time { return now }}
+			creds, err := uc.CreateKey(context.Background(), 7, tc.req)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, creds)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, IsAPIKey(creds.Key))
+				assert.True(t, strings.HasPrefix(creds.Key, stored.Prefix))
+				assert.Less(t, len(stored.Prefix), len(creds.Key))
+				assert.Equal(t, hashSecret(creds.Key), stored.KeyHash)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTokenRejects(t *testing.T) {
+	revokedAt := time.Now()
+	active := &Client{ClientID: "sbc_active", SecretHash: hashSecret("secret"), UserID: 1, Scopes: []string{"read"}}
+	revoked := &Client{ClientID: "sbc_revoked", SecretHash: hashSecret("secret"), UserID: 1, Scopes: []string{"read"}, RevokedAt: &revokedAt}
+
+	tests := []struct {
+		name        string
+		req         *TokenRequest
+		client      *Client
+		findErr     error
+		expectedErr error
+	}{
+		{
+			name:        "password grant",
+			req:         &TokenRequest{GrantType: "password", ClientID: active.ClientID, ClientSecret: "secret"},
+			expectedErr: errs.ErrUnsupportedGrantType,
+		},
+		{
+			name:        "unknown client",
+			req:         &TokenRequest{GrantType: GrantClientCredentials, ClientID: "sbc_unknown", ClientSecret: "secret"},
+			findErr:     errs.ErrClientNotFound,
+			expectedErr: errs.ErrClientInvalid,
+		},
+		{
+			name:        "wrong secret",
+			req:         &TokenRequest{GrantType: GrantClientCredentials, ClientID: active.ClientID, ClientSecret: "guess"},
+			client:      active,
+			expectedErr: errs.ErrClientInvalid,
+		},
+		{
+			name:        "revoked client",
+			req:         &TokenRequest{GrantType: GrantClientCredentials, ClientID: revoked.ClientID, ClientSecret: "secret"},
+			client:      revoked,
+			expectedErr: errs.ErrClientInvalid,
+		},
+		{
+			name:        "scope beyond client",
+			req:         &TokenRequest{GrantType: GrantClientCredentials, ClientID: active.ClientID, ClientSecret: "secret", Scope: "read write"},
+			client:      active,
+			expectedErr: errs.ErrScopeInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(apiKeyRepositoryMock)
+			if tc.client != nil || tc.findErr != nil {
+				repo.On("FindClient", mock.Anything, tc.req.ClientID).Return(tc.client, tc.findErr)
+			}
+
+			uc := &apiKeyUsecase{repo: repo, now: time.Now}
+			_, err := uc.Token(context.Background(), tc.req)
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCheckClient(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		client      *Client
+		findErr     error
+		expectedErr error
+	}{
+		{name: "active", client: &Client{ClientID: "sbc_a", UserID: 1}},
+		{name: "revoked", client: &Client{ClientID: "sbc_a", UserID: 1, RevokedAt: &revokedAt}, expectedErr: errs.ErrTokenRevoked},
+		{name: "other user", client: &Client{ClientID: "sbc_a", UserID: 2}, expectedErr: errs.ErrTokenRevoked},
+		{name: "deleted", findErr: errs.ErrClientNotFound, expectedErr: errs.ErrTokenRevoked},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(apiKeyRepositoryMock)
+			repo.On("FindClient", mock.Anything, "sbc_a").Return(tc.client, tc.findErr)
+
+			uc := &apiKeyUsecase{repo: repo, now: time.Now}
+			err := uc.CheckClient(context.Background(), &security.TokenUser{ID: 1, ClientID: "sbc_a", Scopes: []string{}})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
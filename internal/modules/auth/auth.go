@@ -1,6 +1,115 @@
 package auth
 
-type authRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+import "time"
+
+// JWTTokenResponse is the result of a login. With two-factor
+// authentication enabled the password check only returns a challenge
+// token, which LoginTOTP exchanges for the token pair.
+type JWTTokenResponse struct {
+	AccessToken    string `json:",omitempty"`
+	RefreshToken   string `json:",omitempty"`
+	MFARequired    bool   `json:",omitempty"`
+	ChallengeToken string `json:",omitempty"`
+}
+
+// RefreshTokenRecord is the stored state of an issued refresh token.
+type RefreshTokenRecord struct {
+	ID        string
+	FamilyID  string
+	UserID    int64
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Session is one login of a user on a device: a refresh token family and
+// where it was last used from.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Current marks the session the list was requested from.
+	Current bool `json:"current"`
+}
+
+// TokenState is what the revocation store knows about an access token.
+type TokenState struct {
+	ValidAfter *time.Time
+	Revoked    bool
+}
+
+// TOTP is the two-factor secret of a user, enabled once confirmed.
+type TOTP struct {
+	UserID       int64
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep *int64
+	CreatedAt    time.Time
+}
+
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// MFAChallenge is a login waiting for its one-time code. ID is the hash of
+// the token given to the client.
+type MFAChallenge struct {
+	ID        string
+	UserID    int64
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+type tokenPurpose string
+
+const (
+	PurposePasswordReset tokenPurpose = "PASSWORD_RESET"
+	PurposeEmailVerify   tokenPurpose = "EMAIL_VERIFY"
+)
+
+// UserToken is a single-use token sent to a user by email. ID is the hash
+// of the token in the link.
+type UserToken struct {
+	ID        string
+	UserID    int64
+	Purpose   tokenPurpose
+	ExpiresAt time.Time
+}
+
+type throttleScope string
+
+const (
+	ScopeEmail throttleScope = "email"
+	ScopeIP    throttleScope = "ip"
+	ScopeOTP   throttleScope = "otp"
+)
+
+// LoginThrottle counts the recent attempts of one email address, IP or
+// user. Attempts are counted before they are checked, and successful ones
+// are cleared or taken back, so what is left are the failures.
+type LoginThrottle struct {
+	Scope        throttleScope
+	Subject      string
+	Failures     int
+	LastFailedAt time.Time
+	RetryAt      *time.Time
+	LockedUntil  *time.Time
+}
+
+// LoginAttempt is one attempt to count against a throttle: when it was
+// made, when the count starts over, the wait before the next attempt after
+// n attempts in a row (Delays[n-1], the last one repeating) and until when
+// the subject is locked out once MaxFailures is reached.
+type LoginAttempt struct {
+	At          time.Time
+	WindowStart time.Time
+	Delays      []time.Duration
+	MaxFailures int
+	LockedUntil time.Time
 }
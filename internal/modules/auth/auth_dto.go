@@ -1,6 +1,69 @@
 package auth
 
-type JWTTokenResponse struct {
-	AccessToken  string
-	RefreshToken string
+import "github.com/codepnw/simple-bank/internal/modules/user"
+
+// Device is where a request comes from, set by the handler. The IP is used
+// for throttling; both are shown in the list of sessions.
+type Device struct {
+	IP        string
+	UserAgent string
+}
+
+type authRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+
+	Device `json:"-"`
+}
+
+type registerRequest struct {
+	user.UserRequest
+
+	Device `json:"-"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+
+	Device `json:"-"`
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+// totpRequest carries a one-time code: a TOTP code or a recovery code.
+type totpRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type totpLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+
+	Device `json:"-"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodesResponse is shown once; only hashes of the codes are kept.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
 }
@@ -1,17 +1,29 @@
 package auth
 
 import (
+	"errors"
+	"net/http"
+
 	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 type authHandler struct {
-	uc AuthUsecase
+	uc       AuthUsecase
+	validate *validator.Validate
 }
 
 func NewAuthHandler(uc AuthUsecase) *authHandler {
-	return &authHandler{uc: uc}
+	return &authHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
 }
 
 func (h *authHandler) Login(ctx *gin.Context) {
@@ -22,8 +34,22 @@ func (h *authHandler) Login(ctx *gin.Context) {
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req.Device = device(ctx)
+
 	result, err := h.uc.Login(ctx, req)
 	if err != nil {
+		if throttled(ctx, err) {
+			return
+		}
+		if errors.Is(err, errs.ErrInvalidCredentials) {
+			response.Unauthorized(ctx, err.Error())
+			return
+		}
 		response.ErrInternalServer(ctx, err)
 		return
 	}
@@ -32,18 +58,474 @@ func (h *authHandler) Login(ctx *gin.Context) {
 }
 
 func (h *authHandler) Register(ctx *gin.Context) {
-	req := new(user.UserRequest)
+	req := new(registerRequest)
 
 	if err := ctx.ShouldBindJSON(req); err != nil {
 		response.ErrBadRequest(ctx, err)
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req.Device = device(ctx)
+
 	result, err := h.uc.Register(ctx, req)
 	if err != nil {
+		if errors.Is(err, errs.ErrPasswordWeak) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
 		response.ErrInternalServer(ctx, err)
 		return
 	}
 
 	response.Created(ctx, result)
 }
+
+func (h *authHandler) Refresh(ctx *gin.Context) {
+	req := new(refreshRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req.Device = device(ctx)
+
+	result, err := h.uc.Refresh(ctx.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, errs.ErrRefreshTokenInvalid) || errors.Is(err, errs.ErrRefreshTokenReused) {
+			response.Unauthorized(ctx, err.Error())
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *authHandler) Logout(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	req := new(logoutRequest)
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(req); err != nil {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
+	}
+
+	if err := h.uc.Logout(ctx.Request.Context(), u, req); err != nil {
+		if errors.Is(err, errs.ErrRefreshTokenInvalid) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "logged out")
+}
+
+func (h *authHandler) LoginTOTP(ctx *gin.Context) {
+	req := new(totpLoginRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req.Device = device(ctx)
+
+	result, err := h.uc.LoginTOTP(ctx.Request.Context(), req)
+	if err != nil {
+		if throttled(ctx, err) {
+			return
+		}
+		if errors.Is(err, errs.ErrMFAChallengeInvalid) || errors.Is(err, errs.ErrTOTPInvalid) {
+			response.Unauthorized(ctx, err.Error())
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *authHandler) EnrollTOTP(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	result, err := h.uc.EnrollTOTP(ctx.Request.Context(), u)
+	if err != nil {
+		if errors.Is(err, errs.ErrTOTPAlreadyEnabled) {
+			response.ErrConflict(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
+func (h *authHandler) ConfirmTOTP(ctx *gin.Context) {
+	u, req, ok := h.totpRequest(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := h.uc.ConfirmTOTP(ctx.Request.Context(), u.ID, req)
+	if err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *authHandler) DisableTOTP(ctx *gin.Context) {
+	u, req, ok := h.totpRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.uc.DisableTOTP(ctx.Request.Context(), u.ID, req); err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "two-factor authentication disabled")
+}
+
+func (h *authHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	u, req, ok := h.totpRequest(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := h.uc.RegenerateRecoveryCodes(ctx.Request.Context(), u.ID, req)
+	if err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *authHandler) ForgotPassword(ctx *gin.Context) {
+	req := new(forgotPasswordRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.uc.ForgotPassword(ctx.Request.Context(), req); err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "if the email address has an account, a reset link was sent")
+}
+
+func (h *authHandler) ResetPassword(ctx *gin.Context) {
+	req := new(resetPasswordRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.uc.ResetPassword(ctx.Request.Context(), req); err != nil {
+		if errors.Is(err, errs.ErrUserTokenInvalid) || errors.Is(err, errs.ErrPasswordWeak) || errors.Is(err, errs.ErrPasswordReused) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "password changed; log in again")
+}
+
+// ChangePassword sets a new password of the current user. Other sessions
+// of the user end; this one stays logged in.
+func (h *authHandler) ChangePassword(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	req := new(user.PasswordChangeRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.uc.ChangePassword(ctx.Request.Context(), u, req); err != nil {
+		if throttled(ctx, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, errs.ErrPasswordIncorrect):
+			response.Forbidden(ctx, err)
+		case errors.Is(err, errs.ErrPasswordWeak), errors.Is(err, errs.ErrPasswordReused):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Success(ctx, "password changed; other sessions were logged out")
+}
+
+func (h *authHandler) VerifyEmail(ctx *gin.Context) {
+	req := new(verifyEmailRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.uc.VerifyEmail(ctx.Request.Context(), req); err != nil {
+		if errors.Is(err, errs.ErrUserTokenInvalid) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "email address verified")
+}
+
+func (h *authHandler) ResendVerification(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	if err := h.uc.ResendVerification(ctx.Request.Context(), u.ID); err != nil {
+		if errors.Is(err, errs.ErrEmailAlreadyVerified) {
+			response.ErrConflict(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "verification email sent")
+}
+
+// totpRequest reads the current token and the one-time code of a
+// two-factor settings request. It has responded when ok is false.
+func (h *authHandler) totpRequest(ctx *gin.Context) (*security.TokenUser, *totpRequest, bool) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return nil, nil, false
+	}
+
+	req := new(totpRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return nil, nil, false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return nil, nil, false
+	}
+
+	return u, req, true
+}
+
+func totpError(ctx *gin.Context, err error) {
+	if throttled(ctx, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, errs.ErrTOTPInvalid):
+		response.ErrBadRequest(ctx, err)
+	case errors.Is(err, errs.ErrTOTPNotEnabled),
+		errors.Is(err, errs.ErrTOTPNotEnrolled),
+		errors.Is(err, errs.ErrTOTPAlreadyEnabled):
+		response.ErrConflict(ctx, err)
+	default:
+		response.ErrInternalServer(ctx, err)
+	}
+}
+
+func (h *authHandler) UnlockUser(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err = h.uc.UnlockUser(ctx.Request.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "user unlocked")
+}
+
+func (h *authHandler) ListSessions(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	h.listSessions(ctx, u.ID, u.SessionID)
+}
+
+func (h *authHandler) EndSession(ctx *gin.Context) {
+	u, ok := currentToken(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "token not found in context")
+		return
+	}
+
+	h.endSession(ctx, u.ID, ctx.Param("id"))
+}
+
+// ListUserSessions lists the sessions of any user, for admins.
+func (h *authHandler) ListUserSessions(ctx *gin.Context) {
+	userID, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	h.listSessions(ctx, userID, "")
+}
+
+// EndUserSession ends a session of any user, for admins.
+func (h *authHandler) EndUserSession(ctx *gin.Context) {
+	userID, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	h.endSession(ctx, userID, ctx.Param("sessionID"))
+}
+
+func (h *authHandler) listSessions(ctx *gin.Context, userID int64, currentID string) {
+	result, err := h.uc.ListSessions(ctx.Request.Context(), userID, currentID)
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *authHandler) endSession(ctx *gin.Context, userID int64, id string) {
+	if err := h.uc.EndSession(ctx.Request.Context(), userID, id); err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "session ended")
+}
+
+// JWKS publishes the public keys of the token signing keys, including
+// retired ones still valid for unexpired tokens. It is served as is
+// rather than in the response envelope, as JWKS clients expect.
+func (h *authHandler) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.uc.JWKS())
+}
+
+// throttled answers with 429 when err is a ThrottledError and reports
+// whether it did.
+func throttled(ctx *gin.Context, err error) bool {
+	var te *errs.ThrottledError
+	if !errors.As(err, &te) {
+		return false
+	}
+
+	response.TooManyRequests(ctx, te.RetryAfter, err)
+	return true
+}
+
+// device describes the client a request comes from.
+func device(ctx *gin.Context) Device {
+	return Device{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
+
+// currentToken returns the verified access token of the request, set by
+// the Authorized middleware.
+func currentToken(ctx *gin.Context) (*security.TokenUser, bool) {
+	val, ok := ctx.Get(utils.ContextKeyToken)
+	if !ok {
+		return nil, false
+	}
+
+	u, ok := val.(*security.TokenUser)
+	return u, ok
+}
@@ -0,0 +1,669 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/lib/pq"
+)
+
+type AuthRepository interface {
+	CreateRefreshToken(ctx context.Context, rt *security.RefreshToken) error
+	FindRefreshToken(ctx context.Context, id string) (*RefreshTokenRecord, error)
+	UseRefreshToken(ctx context.Context, id string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAccessToken(ctx context.Context, u *security.TokenUser) error
+	RevokeUserTokens(ctx context.Context, userID int64) error
+	RevokeOtherSessions(ctx context.Context, userID int64, sessionID string) error
+	FindTokenState(ctx context.Context, userID int64, tokenID, sessionID string) (*TokenState, error)
+	DeleteExpiredTokens(ctx context.Context, now time.Time) (int64, error)
+
+	SaveSession(ctx context.Context, s *Session) error
+	ListSessions(ctx context.Context, userID int64) ([]*Session, error)
+	EndSession(ctx context.Context, userID int64, id string) error
+	TouchSession(ctx context.Context, id, ip string) error
+
+	FindTOTP(ctx context.Context, userID int64) (*TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID int64, secret string) error
+	ConfirmTOTP(ctx context.Context, userID, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+
+	CreateChallenge(ctx context.Context, c *MFAChallenge) error
+	AttemptChallenge(ctx context.Context, id string, maxAttempts int) (*MFAChallenge, error)
+	CompleteChallenge(ctx context.Context, id string) (bool, error)
+
+	CreateUserToken(ctx context.Context, t *UserToken) error
+	UseUserToken(ctx context.Context, id string, purpose tokenPurpose) (int64, error)
+
+	FindLoginThrottle(ctx context.Context, scope throttleScope, subject string) (*LoginThrottle, error)
+	CountLoginAttempt(ctx context.Context, scope throttleScope, subject string, a *LoginAttempt) (bool, error)
+	ForgiveLoginAttempt(ctx context.Context, scope throttleScope, subject string, maxFailures int) error
+	ClearLoginFailures(ctx context.Context, scope throttleScope, subject string) error
+	DeleteStaleLoginThrottles(ctx context.Context, before time.Time) (int64, error)
+}
+
+type authRepository struct {
+	db *sql.DB
+}
+
+func NewAuthRepository(db *sql.DB) AuthRepository {
+	return &authRepository{db: db}
+}
+
+func (r *authRepository) CreateRefreshToken(ctx context.Context, rt *security.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.ExecContext(ctx, query, rt.ID, rt.FamilyID, rt.User.ID, rt.ExpiresAt)
+	return err
+}
+
+func (r *authRepository) FindRefreshToken(ctx context.Context, id string) (*RefreshTokenRecord, error) {
+	query := `
+		SELECT id, family_id, user_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WHERE id = $1 LIMIT 1
+	`
+	rec := new(RefreshTokenRecord)
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&rec.ID,
+		&rec.FamilyID,
+		&rec.UserID,
+		&rec.ExpiresAt,
+		&rec.UsedAt,
+		&rec.RevokedAt,
+		&rec.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// UseRefreshToken marks a live token as used and reports whether this call
+// did so. Of two concurrent refreshes with the same token only one wins.
+func (r *authRepository) UseRefreshToken(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE refresh_tokens SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// RevokeFamily revokes the refresh tokens of a login and ends its session,
+// which also rejects its access tokens.
+func (r *authRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		WITH ended AS (
+			UPDATE sessions SET revoked_at = NOW()
+			WHERE id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *authRepository) RevokeAccessToken(ctx context.Context, u *security.TokenUser) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query, u.TokenID, u.ID, u.ExpiresAt)
+	return err
+}
+
+// RevokeUserTokens ends every session of a user: access tokens issued up to
+// now are rejected and all refresh tokens are revoked.
+func (r *authRepository) RevokeUserTokens(ctx context.Context, userID int64) error {
+	query := `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		), ended AS (
+			UPDATE sessions SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE users SET tokens_valid_after = date_trunc('second', NOW())
+		WHERE id = $1
+	`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions ends every session of a user but sessionID. Access
+// tokens of the ended sessions are rejected with them.
+func (r *authRepository) RevokeOtherSessions(ctx context.Context, userID int64, sessionID string) error {
+	query := `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+		)
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, sessionID)
+	return err
+}
+
+// FindTokenState reports a token as revoked when it was logged out by
+// itself or its session was ended.
+func (r *authRepository) FindTokenState(ctx context.Context, userID int64, tokenID, sessionID string) (*TokenState, error) {
+	query := `
+		SELECT u.tokens_valid_after,
+			EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $2) OR
+			EXISTS (SELECT 1 FROM sessions WHERE id = $3 AND revoked_at IS NOT NULL)
+		FROM users u WHERE u.id = $1
+	`
+	state := new(TokenState)
+
+	err := r.db.QueryRowContext(ctx, query, userID, tokenID, sessionID).Scan(
+		&state.ValidAfter,
+		&state.Revoked,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// DeleteExpiredTokens removes revoked access tokens, refresh tokens, login
+// challenges and emailed tokens that have expired and can no longer be
+// presented.
+func (r *authRepository) DeleteExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		WITH revoked AS (
+			DELETE FROM revoked_tokens WHERE expires_at < $1 RETURNING 1
+		), refresh AS (
+			DELETE FROM refresh_tokens WHERE expires_at < $1 RETURNING 1
+		), challenges AS (
+			DELETE FROM mfa_challenges WHERE expires_at < $1 RETURNING 1
+		), emailed AS (
+			DELETE FROM user_tokens WHERE expires_at < $1 RETURNING 1
+		), sessions AS (
+			DELETE FROM sessions WHERE expires_at < $1 RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM revoked) + (SELECT COUNT(*) FROM refresh) +
+			(SELECT COUNT(*) FROM challenges) + (SELECT COUNT(*) FROM emailed) +
+			(SELECT COUNT(*) FROM sessions)
+	`
+	var n int64
+	if err := r.db.QueryRowContext(ctx, query, now).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// SaveSession records a new login, or a refresh of an existing one, which
+// moves its expiry and where it was last seen.
+func (r *authRepository) SaveSession(ctx context.Context, s *Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			user_agent = EXCLUDED.user_agent,
+			ip = EXCLUDED.ip,
+			last_seen_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE sessions.user_id = EXCLUDED.user_id
+	`
+	_, err := r.db.ExecContext(ctx, query, s.ID, s.UserID, s.UserAgent, s.IP, s.ExpiresAt)
+	return err
+}
+
+// ListSessions returns the sessions of a user that were not ended and have
+// not expired, most recently seen first.
+func (r *authRepository) ListSessions(ctx context.Context, userID int64) ([]*Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s := new(Session)
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// EndSession ends an active session of a user and revokes its refresh
+// tokens.
+func (r *authRepository) EndSession(ctx context.Context, userID int64, id string) error {
+	query := `
+		WITH ended AS (
+			UPDATE sessions SET revoked_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+			RETURNING id
+		), revoked AS (
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE family_id IN (SELECT id FROM ended) AND revoked_at IS NULL
+		)
+		SELECT COUNT(*) FROM ended
+	`
+	var n int
+	if err := r.db.QueryRowContext(ctx, query, id, userID).Scan(&n); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errs.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (r *authRepository) TouchSession(ctx context.Context, id, ip string) error {
+	query := `
+		UPDATE sessions SET last_seen_at = NOW(), ip = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, id, ip)
+	return err
+}
+
+func (r *authRepository) FindTOTP(ctx context.Context, userID int64) (*TOTP, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM user_totp WHERE user_id = $1 LIMIT 1
+	`
+	t := new(TOTP)
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.ConfirmedAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// SaveTOTPSecret starts an enrollment, replacing one that was never
+// confirmed. An enabled secret is kept.
+func (r *authRepository) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP enables a pending secret, marks step as used and stores the
+// first recovery codes.
+func (r *authRepository) ConfirmTOTP(ctx context.Context, userID, step int64, codeHashes []string) error {
+	query := `
+		WITH confirmed AS (
+			UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL
+			RETURNING user_id
+		)
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT user_id, unnest($3::text[]) FROM confirmed
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, step, pq.Array(codeHashes))
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// UseTOTPStep records step as used and reports whether it was newer than
+// the last one, so each code is accepted once.
+func (r *authRepository) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+			AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *authRepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	query := `DELETE FROM user_totp WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrTOTPNotEnabled
+	}
+
+	return nil
+}
+
+func (r *authRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	query := `
+		WITH cleared AS (
+			DELETE FROM recovery_codes WHERE user_id = $1
+		)
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, pq.Array(codeHashes))
+	return err
+}
+
+func (r *authRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *authRepository) CreateChallenge(ctx context.Context, c *MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.db.ExecContext(ctx, query, c.ID, c.UserID, c.ExpiresAt)
+	return err
+}
+
+// AttemptChallenge counts an attempt at a live challenge. Once maxAttempts
+// are used up the challenge is dead and the user has to log in again.
+func (r *authRepository) AttemptChallenge(ctx context.Context, id string, maxAttempts int) (*MFAChallenge, error) {
+	query := `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+		RETURNING id, user_id, attempts, expires_at
+	`
+	c := new(MFAChallenge)
+
+	err := r.db.QueryRowContext(ctx, query, id, maxAttempts).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.Attempts,
+		&c.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *authRepository) CompleteChallenge(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE mfa_challenges SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// CreateUserToken stores a new token and retires the unused ones of the
+// same purpose, so only the latest link works.
+func (r *authRepository) CreateUserToken(ctx context.Context, t *UserToken) error {
+	query := `
+		WITH retired AS (
+			UPDATE user_tokens SET used_at = NOW()
+			WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL
+		)
+		INSERT INTO user_tokens (id, user_id, purpose, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.ExecContext(ctx, query, t.ID, t.UserID, string(t.Purpose), t.ExpiresAt)
+	return err
+}
+
+// UseUserToken marks a live token as used and returns its user.
+func (r *authRepository) UseUserToken(ctx context.Context, id string, purpose tokenPurpose) (int64, error) {
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+	var userID int64
+
+	err := r.db.QueryRowContext(ctx, query, id, string(purpose)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errs.ErrUserTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// FindLoginThrottle returns an empty throttle when the subject has no
+// failed attempts.
+func (r *authRepository) FindLoginThrottle(ctx context.Context, scope throttleScope, subject string) (*LoginThrottle, error) {
+	query := `
+		SELECT failures, last_failed_at, retry_at, locked_until
+		FROM login_throttles WHERE scope = $1 AND subject = $2 LIMIT 1
+	`
+	t := &LoginThrottle{Scope: scope, Subject: subject}
+
+	err := r.db.QueryRowContext(ctx, query, string(scope), subject).Scan(
+		&t.Failures,
+		&t.LastFailedAt,
+		&t.RetryAt,
+		&t.LockedUntil,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// CountLoginAttempt counts an attempt before it is checked, unless the
+// subject is locked out or has to wait after its last attempt. The count
+// starts over when the last attempt is before a.WindowStart. Counting, the
+// wait before the next attempt and the lockout are one statement, so
+// parallel attempts cannot all pass a check none of them has counted yet.
+// It reports whether the attempt was counted.
+func (r *authRepository) CountLoginAttempt(ctx context.Context, scope throttleScope, subject string, a *LoginAttempt) (bool, error) {
+	query := `
+		INSERT INTO login_throttles AS t (scope, subject, failures, last_failed_at, retry_at, locked_until)
+		VALUES ($1, $2, 1, $3::TIMESTAMPTZ,
+			$3::TIMESTAMPTZ + COALESCE(($5::BIGINT[])[1], 0) * INTERVAL '1 millisecond',
+			CASE WHEN $6::INT <= 1 THEN $7::TIMESTAMPTZ END)
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE WHEN t.last_failed_at < $4 THEN 1 ELSE t.failures + 1 END,
+			last_failed_at = $3::TIMESTAMPTZ,
+			retry_at = $3::TIMESTAMPTZ + COALESCE(($5::BIGINT[])[LEAST(
+				CASE WHEN t.last_failed_at < $4 THEN 1 ELSE t.failures + 1 END,
+				CARDINALITY($5::BIGINT[]))], 0) * INTERVAL '1 millisecond',
+			locked_until = CASE
+				WHEN (CASE WHEN t.last_failed_at < $4 THEN 1 ELSE t.failures + 1 END) >= $6::INT
+				THEN $7::TIMESTAMPTZ ELSE t.locked_until END
+		WHERE (t.locked_until IS NULL OR t.locked_until <= $3::TIMESTAMPTZ)
+			AND (t.retry_at IS NULL OR t.retry_at <= $3::TIMESTAMPTZ)
+	`
+	delays := make([]int64, len(a.Delays))
+	for i, d := range a.Delays {
+		delays[i] = d.Milliseconds()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, string(scope), subject, a.At, a.WindowStart,
+		pq.Array(delays), a.MaxFailures, a.LockedUntil)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// ForgiveLoginAttempt takes back a counted attempt that succeeded, and the
+// lockout it caused, for subjects that are not cleared on success.
+func (r *authRepository) ForgiveLoginAttempt(ctx context.Context, scope throttleScope, subject string, maxFailures int) error {
+	query := `
+		UPDATE login_throttles SET
+			failures = failures - 1,
+			locked_until = CASE WHEN failures - 1 < $3 THEN NULL ELSE locked_until END
+		WHERE scope = $1 AND subject = $2 AND failures > 0
+	`
+	_, err := r.db.ExecContext(ctx, query, string(scope), subject, maxFailures)
+	return err
+}
+
+func (r *authRepository) ClearLoginFailures(ctx context.Context, scope throttleScope, subject string) error {
+	query := `DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`
+
+	_, err := r.db.ExecContext(ctx, query, string(scope), subject)
+	return err
+}
+
+// DeleteStaleLoginThrottles removes counters of subjects that have not
+// tried since before and are not locked.
+func (r *authRepository) DeleteStaleLoginThrottles(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_throttles
+		WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < NOW())
+	`
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/stretchr/testify/mock"
+)
+
+type authRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *authRepositoryMock) CreateRefreshToken(ctx context.Context, rt *security.RefreshToken) error {
+	args := m.Called(ctx, rt)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) FindRefreshToken(ctx context.Context, id string) (*RefreshTokenRecord, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*RefreshTokenRecord)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *authRepositoryMock) UseRefreshToken(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *authRepositoryMock) RevokeFamily(ctx context.Context, familyID string) error {
+	args := m.Called(ctx, familyID)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) RevokeAccessToken(ctx context.Context, u *security.TokenUser) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) RevokeUserTokens(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) RevokeOtherSessions(ctx context.Context, userID int64, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) FindTokenState(ctx context.Context, userID int64, tokenID, sessionID string) (*TokenState, error) {
+	args := m.Called(ctx, userID, tokenID, sessionID)
+
+	res, ok := args.Get(0).(*TokenState)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *authRepositoryMock) DeleteExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *authRepositoryMock) FindTOTP(ctx context.Context, userID int64) (*TOTP, error) {
+	args := m.Called(ctx, userID)
+
+	res, ok := args.Get(0).(*TOTP)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *authRepositoryMock) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) ConfirmTOTP(ctx context.Context, userID, step int64, codeHashes []string) error {
+	args := m.Called(ctx, userID, step, codeHashes)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *authRepositoryMock) DeleteTOTP(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *authRepositoryMock) CreateChallenge(ctx context.Context, c *MFAChallenge) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) AttemptChallenge(ctx context.Context, id string, maxAttempts int) (*MFAChallenge, error) {
+	args := m.Called(ctx, id, maxAttempts)
+
+	res, ok := args.Get(0).(*MFAChallenge)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *authRepositoryMock) CompleteChallenge(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *authRepositoryMock) CreateUserToken(ctx context.Context, t *UserToken) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) UseUserToken(ctx context.Context, id string, purpose tokenPurpose) (int64, error) {
+	args := m.Called(ctx, id, purpose)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *authRepositoryMock) FindLoginThrottle(ctx context.Context, scope throttleScope, subject string) (*LoginThrottle, error) {
+	args := m.Called(ctx, scope, subject)
+
+	res, ok := args.Get(0).(*LoginThrottle)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *authRepositoryMock) CountLoginAttempt(ctx context.Context, scope throttleScope, subject string, a *LoginAttempt) (bool, error) {
+	args := m.Called(ctx, scope, subject, a)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *authRepositoryMock) ForgiveLoginAttempt(ctx context.Context, scope throttleScope, subject string, maxFailures int) error {
+	args := m.Called(ctx, scope, subject, maxFailures)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) ClearLoginFailures(ctx context.Context, scope throttleScope, subject string) error {
+	args := m.Called(ctx, scope, subject)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) DeleteStaleLoginThrottles(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *authRepositoryMock) SaveSession(ctx context.Context, s *Session) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) ListSessions(ctx context.Context, userID int64) ([]*Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*Session), args.Error(1)
+}
+
+func (m *authRepositoryMock) EndSession(ctx context.Context, userID int64, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *authRepositoryMock) TouchSession(ctx context.Context, id, ip string) error {
+	args := m.Called(ctx, id, ip)
+	return args.Error(0)
+}
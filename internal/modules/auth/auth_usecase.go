@@ -2,52 +2,176 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/codepnw/simple-bank/config"
 	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/mailer"
 	"github.com/codepnw/simple-bank/internal/utils/security"
 )
 
-const queryTimeout = time.Second * 5
+const (
+	queryTimeout = time.Second * 5
+
+	// maxChallengeAttempts is how many codes can be tried on one login
+	// challenge before the password has to be entered again.
+	maxChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+
+	// mailTimeout bounds sending one email in the background.
+	mailTimeout = time.Second * 30
+
+	// maxLoginDelay caps the progressive delay between failed attempts.
+	maxLoginDelay = time.Second * 30
+
+	// sessionTouchInterval is how often requests of a session update when
+	// it was last seen.
+	sessionTouchInterval = time.Minute
+
+	maxUserAgentLength = 512
+)
 
 type AuthUsecase interface {
 	Login(ctx context.Context, req *authRequest) (*JWTTokenResponse, error)
-	Register(ctx context.Context, req *user.UserRequest) (*JWTTokenResponse, error)
+	Register(ctx context.Context, req *registerRequest) (*JWTTokenResponse, error)
+	Refresh(ctx context.Context, req *refreshRequest) (*JWTTokenResponse, error)
+	Logout(ctx context.Context, u *security.TokenUser, req *logoutRequest) error
+	CheckAccessToken(ctx context.Context, u *security.TokenUser) error
+	DeleteExpiredTokens(ctx context.Context, now time.Time) (int64, error)
+
+	ListSessions(ctx context.Context, userID int64, currentID string) ([]*Session, error)
+	EndSession(ctx context.Context, userID int64, id string) error
+	TouchSession(ctx context.Context, u *security.TokenUser, ip string)
+
+	LoginTOTP(ctx context.Context, req *totpLoginRequest) (*JWTTokenResponse, error)
+	EnrollTOTP(ctx context.Context, u *security.TokenUser) (*TOTPEnrollResponse, error)
+	ConfirmTOTP(ctx context.Context, userID int64, req *totpRequest) (*RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userID int64, req *totpRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, req *totpRequest) (*RecoveryCodesResponse, error)
+	VerifyTOTP(ctx context.Context, userID int64, code string) error
+
+	ForgotPassword(ctx context.Context, req *forgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *resetPasswordRequest) error
+	ChangePassword(ctx context.Context, u *security.TokenUser, req *user.PasswordChangeRequest) error
+	VerifyEmail(ctx context.Context, req *verifyEmailRequest) error
+	ResendVerification(ctx context.Context, userID int64) error
+
+	UnlockUser(ctx context.Context, userID int64) error
+
+	JWKS() *security.JWKS
+}
+
+// throttleLimit is how many failures in a row lock a subject out, and
+// whether attempts before that are slowed down.
+type throttleLimit struct {
+	maxFailures int
+	progressive bool
 }
 
 type authUsecase struct {
-	userUsecase user.UserUsecase
-	jwt         *security.Token
+	repo            AuthRepository
+	userUsecase     user.UserUsecase
+	jwt             *security.Token
+	mail            mailer.Mailer
+	issuer          string
+	challengeExpiry time.Duration
+	resetExpiry     time.Duration
+	verifyExpiry    time.Duration
+	linkBaseURL     string
+	limits          map[throttleScope]throttleLimit
+	loginWindow     time.Duration
+	loginLockout    time.Duration
+	now             func() time.Time
+
+	// touched holds when each session was last marked as seen by this
+	// instance, to write that at most once per sessionTouchInterval.
+	touched sync.Map
 }
 
-func NewAuthUsecase(cfg *config.EnvConfig, userUsecase user.UserUsecase) AuthUsecase {
+func NewAuthUsecase(cfg *config.EnvConfig, token *security.Token, repo AuthRepository, userUsecase user.UserUsecase, mail mailer.Mailer) AuthUsecase {
 	return &authUsecase{
-		userUsecase: userUsecase,
-		jwt:         security.InitJWT(cfg),
+		repo:            repo,
+		userUsecase:     userUsecase,
+		jwt:             token,
+		mail:            mail,
+		issuer:          cfg.MFA.Issuer,
+		challengeExpiry: cfg.MFA.ChallengeExpiry,
+		resetExpiry:     cfg.Auth.PasswordResetExpiry,
+		verifyExpiry:    cfg.Auth.EmailVerifyExpiry,
+		linkBaseURL:     strings.TrimSuffix(cfg.Mail.LinkBaseURL, "/"),
+		limits: map[throttleScope]throttleLimit{
+			ScopeEmail: {maxFailures: cfg.Auth.LoginMaxFailures, progressive: true},
+			ScopeOTP:   {maxFailures: cfg.Auth.LoginMaxFailures, progressive: true},
+			// Many users may share an IP, so it is only locked out.
+			ScopeIP: {maxFailures: cfg.Auth.LoginIPMaxFailures},
+		},
+		loginWindow:  cfg.Auth.LoginWindow,
+		loginLockout: cfg.Auth.LoginLockout,
+		now:          time.Now,
 	}
 }
 
+// Login checks the password of a user. Unknown email addresses and wrong
+// passwords fail alike with ErrInvalidCredentials, and repeated failures
+// for an address or from an IP are slowed down and then locked out.
 func (uc *authUsecase) Login(ctx context.Context, req *authRequest) (*JWTTokenResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	user, err := uc.userUsecase.GetUserByEmail(ctx, req.Email)
+	email := throttleKey{ScopeEmail, strings.ToLower(strings.TrimSpace(req.Email))}
+	keys := []throttleKey{email}
+	if req.IP != "" {
+		keys = append(keys, throttleKey{ScopeIP, req.IP})
+	}
+
+	for _, key := range keys {
+		if err := uc.countAttempt(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := uc.userUsecase.Authenticate(ctx, req.Email, req.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	err = security.ComparePassword(user.Password, req.Password)
-	if err != nil {
+	if err = uc.repo.ClearLoginFailures(ctx, email.scope, email.subject); err != nil {
+		return nil, err
+	}
+
+	// Logging in to one account must not lift the lockout of an IP that
+	// guesses at others, so only this attempt is taken back.
+	for _, key := range keys[1:] {
+		if err = uc.repo.ForgiveLoginAttempt(ctx, key.scope, key.subject, uc.limits[key.scope].maxFailures); err != nil {
+			return nil, err
+		}
+	}
+
+	totp, err := uc.repo.FindTOTP(ctx, user.ID)
+	switch {
+	case err == nil && totp.Enabled():
+		return uc.newChallenge(ctx, user.ID)
+	case err != nil && !errors.Is(err, errs.ErrTOTPNotEnabled):
 		return nil, err
 	}
 
-	token, err := uc.jwtTokenResponse(&security.TokenUser{
+	token, err := uc.jwtTokenResponse(ctx, &security.TokenUser{
 		ID:    user.ID,
 		Email: user.Email,
-		Role:  "user", // TODO: change later
-	})
+		Role:  string(user.Role),
+	}, "", &req.Device)
 	if err != nil {
 		return nil, err
 	}
@@ -55,36 +179,709 @@ func (uc *authUsecase) Login(ctx context.Context, req *authRequest) (*JWTTokenRe
 	return token, nil
 }
 
-func (uc *authUsecase) Register(ctx context.Context, req *user.UserRequest) (*JWTTokenResponse, error) {
+func (uc *authUsecase) Register(ctx context.Context, req *registerRequest) (*JWTTokenResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	created, err := uc.userUsecase.Create(ctx, req)
+	created, err := uc.userUsecase.Create(ctx, &req.UserRequest)
 	if err != nil {
 		return nil, err
 	}
 
+	// The account exists either way; the user can ask for another link.
+	if err = uc.sendVerification(ctx, created); err != nil {
+		log.Printf("send verification email failed: %v", err)
+	}
+
 	user := &security.TokenUser{
 		ID:    created.ID,
 		Email: created.Email,
-		Role:  "user", // TODO: change later
+		Role:  string(created.Role),
 	}
 
-	return uc.jwtTokenResponse(user)
+	return uc.jwtTokenResponse(ctx, user, "", &req.Device)
 }
 
-func (uc *authUsecase) jwtTokenResponse(user *security.TokenUser) (*JWTTokenResponse, error) {
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// works once; presenting one that was already rotated means it leaked, so
+// every token of its family is revoked and the user has to log in again.
+func (uc *authUsecase) Refresh(ctx context.Context, req *refreshRequest) (*JWTTokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rt, err := uc.jwt.VerifyRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+
+	used, err := uc.repo.UseRefreshToken(ctx, rt.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		rec, err := uc.repo.FindRefreshToken(ctx, rt.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if rec.UsedAt != nil && rec.RevokedAt == nil {
+			if err = uc.repo.RevokeFamily(ctx, rec.FamilyID); err != nil {
+				return nil, fmt.Errorf("revoke token family failed: %w", err)
+			}
+			return nil, errs.ErrRefreshTokenReused
+		}
+
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+
+	u, err := uc.userUsecase.GetUserByID(ctx, rt.User.ID)
+	if err != nil {
+		if revokeErr := uc.repo.RevokeFamily(ctx, rt.FamilyID); revokeErr != nil {
+			return nil, errors.Join(err, revokeErr)
+		}
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+
+	return uc.jwtTokenResponse(ctx, &security.TokenUser{
+		ID:    u.ID,
+		Email: u.Email,
+		Role:  string(u.Role),
+	}, rt.FamilyID, &req.Device)
+}
+
+// Logout revokes the access token it is called with and ends its session,
+// so the refresh tokens of that login stop working too. A refresh token
+// given in the request is revoked as well. With All set every session of
+// the user ends, including ones on other devices.
+func (uc *authUsecase) Logout(ctx context.Context, u *security.TokenUser, req *logoutRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.repo.RevokeAccessToken(ctx, u); err != nil {
+		return fmt.Errorf("revoke access token failed: %w", err)
+	}
+
+	if req.All {
+		return uc.repo.RevokeUserTokens(ctx, u.ID)
+	}
+
+	if u.SessionID != "" {
+		if err := uc.repo.RevokeFamily(ctx, u.SessionID); err != nil {
+			return fmt.Errorf("end session failed: %w", err)
+		}
+	}
+
+	if req.RefreshToken == "" {
+		return nil
+	}
+
+	rt, err := uc.jwt.VerifyRefreshToken(req.RefreshToken)
+	if err != nil || rt.User.ID != u.ID {
+		return errs.ErrRefreshTokenInvalid
+	}
+
+	return uc.repo.RevokeFamily(ctx, rt.FamilyID)
+}
+
+// CheckAccessToken returns ErrTokenRevoked when a verified access token was
+// logged out, belongs to a user that no longer exists, or was issued before
+// all sessions of its user were ended.
+func (uc *authUsecase) CheckAccessToken(ctx context.Context, u *security.TokenUser) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	state, err := uc.repo.FindTokenState(ctx, u.ID, u.TokenID, u.SessionID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return errs.ErrTokenRevoked
+		}
+		return err
+	}
+
+	if state.Revoked {
+		return errs.ErrTokenRevoked
+	}
+
+	if state.ValidAfter != nil && u.IssuedAt.Before(*state.ValidAfter) {
+		return errs.ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// ListSessions returns the active sessions of a user. currentID marks the
+// session of the caller when listing their own.
+func (uc *authUsecase) ListSessions(ctx context.Context, userID int64, currentID string) ([]*Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	sessions, err := uc.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range sessions {
+		s.Current = currentID != "" && s.ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// EndSession logs a user out on one device: the refresh tokens of the
+// session are revoked and its access tokens rejected from now on.
+func (uc *authUsecase) EndSession(ctx context.Context, userID int64, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.EndSession(ctx, userID, id)
+}
+
+// TouchSession notes that the session of an access token is in use from
+// ip. Only a failure to record that is logged; the request goes on.
+func (uc *authUsecase) TouchSession(ctx context.Context, u *security.TokenUser, ip string) {
+	if u.SessionID == "" {
+		return
+	}
+
+	now := uc.now()
+	if last, ok := uc.touched.Load(u.SessionID); ok && now.Sub(last.(time.Time)) < sessionTouchInterval {
+		return
+	}
+	uc.touched.Store(u.SessionID, now)
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.repo.TouchSession(ctx, u.SessionID, ip); err != nil {
+		log.Printf("touch session failed: %v", err)
+	}
+}
+
+// DeleteExpiredTokens also forgets failed logins older than the window.
+func (uc *authUsecase) DeleteExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	uc.touched.Range(func(id, last any) bool {
+		if now.Sub(last.(time.Time)) >= sessionTouchInterval {
+			uc.touched.Delete(id)
+		}
+		return true
+	})
+
+	tokens, err := uc.repo.DeleteExpiredTokens(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	throttles, err := uc.repo.DeleteStaleLoginThrottles(ctx, now.Add(-uc.loginWindow))
+	if err != nil {
+		return tokens, err
+	}
+
+	return tokens + throttles, nil
+}
+
+// LoginTOTP completes a login of a user with two-factor authentication:
+// the challenge token from Login plus a TOTP or recovery code give the
+// token pair.
+func (uc *authUsecase) LoginTOTP(ctx context.Context, req *totpLoginRequest) (*JWTTokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	c, err := uc.repo.AttemptChallenge(ctx, hashSecret(req.ChallengeToken), maxChallengeAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = uc.verifyCode(ctx, c.UserID, req.Code); err != nil {
+		return nil, err
+	}
+
+	completed, err := uc.repo.CompleteChallenge(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, errs.ErrMFAChallengeInvalid
+	}
+
+	u, err := uc.userUsecase.GetUserByID(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.jwtTokenResponse(ctx, &security.TokenUser{
+		ID:    u.ID,
+		Email: u.Email,
+		Role:  string(u.Role),
+	}, "", &req.Device)
+}
+
+// EnrollTOTP creates a new secret for the user. It is not used for login
+// until ConfirmTOTP proves the authenticator app was set up.
+func (uc *authUsecase) EnrollTOTP(ctx context.Context, u *security.TokenUser) (*TOTPEnrollResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	secret := security.NewTOTPSecret()
+
+	if err := uc.repo.SaveTOTPSecret(ctx, u.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollResponse{
+		Secret: secret,
+		URI:    security.TOTPURI(uc.issuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with the first code of the
+// new secret and returns the recovery codes.
+func (uc *authUsecase) ConfirmTOTP(ctx context.Context, userID int64, req *totpRequest) (*RecoveryCodesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	totp, err := uc.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrTOTPNotEnabled) {
+			return nil, errs.ErrTOTPNotEnrolled
+		}
+		return nil, err
+	}
+
+	if totp.Enabled() {
+		return nil, errs.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := security.VerifyTOTP(totp.Secret, normalizeCode(req.Code), uc.now())
+	if !ok {
+		return nil, errs.ErrTOTPInvalid
+	}
+
+	codes, hashes := newRecoveryCodes()
+
+	if err = uc.repo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (uc *authUsecase) DisableTOTP(ctx context.Context, userID int64, req *totpRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.verifyCode(ctx, userID, req.Code); err != nil {
+		return err
+	}
+
+	return uc.repo.DeleteTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not.
+func (uc *authUsecase) RegenerateRecoveryCodes(ctx context.Context, userID int64, req *totpRequest) (*RecoveryCodesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.verifyCode(ctx, userID, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes := newRecoveryCodes()
+
+	if err := uc.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyTOTP checks a one-time code of a logged in user, e.g. before a
+// high-value action.
+func (uc *authUsecase) VerifyTOTP(ctx context.Context, userID int64, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.verifyCode(ctx, userID, code)
+}
+
+// verifyCode accepts a TOTP code or an unused recovery code. Either works
+// once. Wrong codes count as failed attempts of the user.
+func (uc *authUsecase) verifyCode(ctx context.Context, userID int64, code string) error {
+	key := throttleKey{ScopeOTP, strconv.FormatInt(userID, 10)}
+
+	if err := uc.countAttempt(ctx, key); err != nil {
+		return err
+	}
+
+	ok, err := uc.checkCode(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errs.ErrTOTPInvalid
+	}
+
+	return uc.repo.ClearLoginFailures(ctx, key.scope, key.subject)
+}
+
+func (uc *authUsecase) checkCode(ctx context.Context, userID int64, code string) (bool, error) {
+	totp, err := uc.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if !totp.Enabled() {
+		return false, errs.ErrTOTPNotEnabled
+	}
+
+	code = normalizeCode(code)
+
+	if step, ok := security.VerifyTOTP(totp.Secret, code, uc.now()); ok {
+		return uc.repo.UseTOTPStep(ctx, userID, step)
+	}
+
+	return uc.repo.UseRecoveryCode(ctx, userID, hashSecret(code))
+}
+
+// UnlockUser lifts a lockout of the user after failed logins or one-time
+// codes. Lockouts of IPs stay.
+func (uc *authUsecase) UnlockUser(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	u, err := uc.userUsecase.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = uc.repo.ClearLoginFailures(ctx, ScopeEmail, strings.ToLower(u.Email)); err != nil {
+		return err
+	}
+
+	return uc.repo.ClearLoginFailures(ctx, ScopeOTP, strconv.FormatInt(u.ID, 10))
+}
+
+func (uc *authUsecase) JWKS() *security.JWKS {
+	return uc.jwt.JWKS()
+}
+
+type throttleKey struct {
+	scope   throttleScope
+	subject string
+}
+
+// countAttempt counts an attempt for key before it is checked, so that
+// parallel attempts are throttled like ones in a row. It returns a
+// ThrottledError while key is locked out or within the delay after its
+// last attempt. Callers clear the count when the attempt succeeds.
+func (uc *authUsecase) countAttempt(ctx context.Context, key throttleKey) error {
+	limit := uc.limits[key.scope]
+	now := uc.now()
+
+	attempt := &LoginAttempt{
+		At:          now,
+		WindowStart: now.Add(-uc.loginWindow),
+		MaxFailures: limit.maxFailures,
+		LockedUntil: now.Add(uc.loginLockout),
+	}
+	if limit.progressive {
+		attempt.Delays = loginDelays()
+	}
+
+	counted, err := uc.repo.CountLoginAttempt(ctx, key.scope, key.subject, attempt)
+	if err != nil {
+		return fmt.Errorf("count login attempt: %w", err)
+	}
+	if counted {
+		return nil
+	}
+
+	t, err := uc.repo.FindLoginThrottle(ctx, key.scope, key.subject)
+	if err != nil {
+		return err
+	}
+
+	// The wait may have just ended; the client still has to try again.
+	retryAfter := time.Second
+	for _, until := range []*time.Time{t.LockedUntil, t.RetryAt} {
+		if until != nil && until.Sub(now) > retryAfter {
+			retryAfter = until.Sub(now)
+		}
+	}
+
+	return &errs.ThrottledError{RetryAfter: retryAfter}
+}
+
+// loginDelays lists loginDelay for one failure in a row up to the first
+// that reaches maxLoginDelay.
+func loginDelays() []time.Duration {
+	var delays []time.Duration
+	for failures := 1; ; failures++ {
+		delays = append(delays, loginDelay(failures))
+		if delays[len(delays)-1] >= maxLoginDelay {
+			return delays
+		}
+	}
+}
+
+// loginDelay is the wait after failures in a row: none for the first two,
+// then 1s, 2s, 4s and so on up to maxLoginDelay.
+func loginDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+	return min(time.Second<<min(failures-3, 5), maxLoginDelay)
+}
+
+// ForgotPassword emails a password reset link. It succeeds for unknown
+// addresses too, so it cannot be used to find out who has an account.
+func (uc *authUsecase) ForgotPassword(ctx context.Context, req *forgotPasswordRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	u, err := uc.userUsecase.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := uc.newUserToken(ctx, u.ID, PurposePasswordReset, uc.resetExpiry)
+	if err != nil {
+		return err
+	}
+
+	uc.sendMail(&mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse this link to choose a new password:\n\n%s\n\nThe link works once and expires in %s. If you did not ask for it, ignore this email.\n",
+			u.FirstName, uc.link("/reset-password", token), uc.resetExpiry,
+		),
+	})
+
+	return nil
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword and
+// ends every session of the user. Receiving the link also proves the email
+// address.
+func (uc *authUsecase) ResetPassword(ctx context.Context, req *resetPasswordRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	userID, err := uc.repo.UseUserToken(ctx, hashSecret(req.Token), PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	if err = uc.userUsecase.UpdatePassword(ctx, userID, req.Password); err != nil {
+		return err
+	}
+
+	if err = uc.repo.RevokeUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("revoke sessions failed: %w", err)
+	}
+
+	return uc.userUsecase.VerifyEmail(ctx, userID)
+}
+
+// ChangePassword sets a new password of a logged in user after checking the
+// current one and ends every other session of the user. Wrong current
+// passwords count as failed logins of the email address.
+func (uc *authUsecase) ChangePassword(ctx context.Context, u *security.TokenUser, req *user.PasswordChangeRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	found, err := uc.userUsecase.GetUserByID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	key := throttleKey{ScopeEmail, strings.ToLower(found.Email)}
+	if err = uc.countAttempt(ctx, key); err != nil {
+		return err
+	}
+
+	err = uc.userUsecase.ChangePassword(ctx, u.ID, req)
+	if errors.Is(err, errs.ErrPasswordIncorrect) {
+		return err
+	}
+
+	// The current password was right, even when the new one is refused.
+	if clearErr := uc.repo.ClearLoginFailures(ctx, key.scope, key.subject); clearErr != nil {
+		return errors.Join(err, clearErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = uc.repo.RevokeOtherSessions(ctx, u.ID, u.SessionID); err != nil {
+		return fmt.Errorf("end other sessions failed: %w", err)
+	}
+
+	return nil
+}
+
+func (uc *authUsecase) VerifyEmail(ctx context.Context, req *verifyEmailRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	userID, err := uc.repo.UseUserToken(ctx, hashSecret(req.Token), PurposeEmailVerify)
+	if err != nil {
+		return err
+	}
+
+	return uc.userUsecase.VerifyEmail(ctx, userID)
+}
+
+func (uc *authUsecase) ResendVerification(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	u, err := uc.userUsecase.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if u.EmailVerified() {
+		return errs.ErrEmailAlreadyVerified
+	}
+
+	return uc.sendVerification(ctx, u)
+}
+
+func (uc *authUsecase) sendVerification(ctx context.Context, u *user.User) error {
+	token, err := uc.newUserToken(ctx, u.ID, PurposeEmailVerify, uc.verifyExpiry)
+	if err != nil {
+		return err
+	}
+
+	uc.sendMail(&mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nPlease confirm your email address with this link:\n\n%s\n\nThe link expires in %s.\n",
+			u.FirstName, uc.link("/verify-email", token), uc.verifyExpiry,
+		),
+	})
+
+	return nil
+}
+
+// newUserToken stores a single-use token and returns it; only its hash is
+// kept.
+func (uc *authUsecase) newUserToken(ctx context.Context, userID int64, purpose tokenPurpose, expiry time.Duration) (string, error) {
+	token := rand.Text()
+
+	err := uc.repo.CreateUserToken(ctx, &UserToken{
+		ID:        hashSecret(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: uc.now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("create %s token failed: %w", strings.ToLower(string(purpose)), err)
+	}
+
+	return token, nil
+}
+
+func (uc *authUsecase) link(path, token string) string {
+	return uc.linkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendMail delivers in the background, so a response neither waits for the
+// mail server nor takes longer for known addresses than for unknown ones.
+func (uc *authUsecase) sendMail(msg *mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := uc.mail.Send(ctx, msg); err != nil {
+			log.Printf("send mail %q failed: %v", msg.Subject, err)
+		}
+	}()
+}
+
+func (uc *authUsecase) newChallenge(ctx context.Context, userID int64) (*JWTTokenResponse, error) {
+	token := rand.Text()
+
+	err := uc.repo.CreateChallenge(ctx, &MFAChallenge{
+		ID:        hashSecret(token),
+		UserID:    userID,
+		ExpiresAt: uc.now().Add(uc.challengeExpiry),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create login challenge failed: %w", err)
+	}
+
+	return &JWTTokenResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+	}, nil
+}
+
+// newRecoveryCodes returns codes formatted as XXXXX-XXXXX and their hashes.
+func newRecoveryCodes() (codes, hashes []string) {
+	for range recoveryCodeCount {
+		code := rand.Text()[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashSecret(code))
+	}
+	return codes, hashes
+}
+
+// normalizeCode accepts codes as typed: with spaces, dashes or lowercase.
+func normalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func hashSecret(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// jwtTokenResponse issues an access token and a refresh token in familyID,
+// or in a new family when familyID is empty. The family is the session of
+// the login, recorded with the device it was last used from.
+func (uc *authUsecase) jwtTokenResponse(ctx context.Context, user *security.TokenUser, familyID string, device *Device) (*JWTTokenResponse, error) {
+	if familyID == "" {
+		familyID = security.NewTokenID()
+	}
+	user.SessionID = familyID
+
 	accessToken, err := uc.jwt.GenerateAccessToken(user)
-	log.Println("acc", accessToken)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := uc.jwt.GenerateRefreshToken(user)
+	refreshToken, rt, err := uc.jwt.GenerateRefreshToken(user, familyID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = uc.repo.CreateRefreshToken(ctx, rt); err != nil {
+		return nil, fmt.Errorf("store refresh token failed: %w", err)
+	}
+
+	err = uc.repo.SaveSession(ctx, &Session{
+		ID:        familyID,
+		UserID:    user.ID,
+		UserAgent: truncate(device.UserAgent, maxUserAgentLength),
+		IP:        device.IP,
+		ExpiresAt: rt.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store session failed: %w", err)
+	}
+
 	token := &JWTTokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -92,3 +889,11 @@ func (uc *authUsecase) jwtTokenResponse(user *security.TokenUser) (*JWTTokenResp
 
 	return token, nil
 }
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/config"
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testConfig(t *testing.T) *config.EnvConfig {
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	cfg, err := config.LoadEnvConfig(file)
+	assert.NoError(t, err)
+
+	return cfg
+}
+
+func testToken(t *testing.T, cfg *config.EnvConfig) *security.Token {
+	cfg.JWT.KeysDir = t.TempDir()
+
+	keys, err := security.NewKeySet(cfg)
+	assert.NoError(t, err)
+
+	return security.InitJWT(cfg, keys)
+}
+
+func TestCheckAccessToken(t *testing.T) {
+	issued := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	before := issued.Add(-time.Minute)
+	after := issued.Add(time.Minute)
+	token := &security.TokenUser{ID: 1, TokenID: "jti", SessionID: "sid", IssuedAt: issued}
+
+	tests := []struct {
+		name        string
+		state       *TokenState
+		stateErr    error
+		expectedErr error
+	}{
+		{name: "valid", state: &TokenState{}},
+		{name: "sessions ended before issue", state: &TokenState{ValidAfter: &before}},
+		{name: "logged out", state: &TokenState{Revoked: true}, expectedErr: errs.ErrTokenRevoked},
+		{name: "sessions ended after issue", state: &TokenState{ValidAfter: &after}, expectedErr: errs.ErrTokenRevoked},
+		{name: "user deleted", stateErr: errs.ErrUserNotFound, expectedErr: errs.ErrTokenRevoked},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(authRepositoryMock)
+			repo.On("FindTokenState", mock.Anything, token.ID, token.TokenID, token.SessionID).Return(tc.state, tc.stateErr)
+
+			uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil)
+			err := uc.CheckAccessToken(context.Background(), token)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	cfg := testConfig(t)
+	token := testToken(t, cfg)
+	refreshToken, rt, err := token.GenerateRefreshToken(&security.TokenUser{ID: 1, Email: "a@example.com", Role: "USER"}, "")
+	assert.NoError(t, err)
+
+	usedAt := time.Now()
+	repo := new(authRepositoryMock)
+	repo.On("UseRefreshToken", mock.Anything, rt.ID).Return(false, nil)
+	repo.On("FindRefreshToken", mock.Anything, rt.ID).Return(&RefreshTokenRecord{ID: rt.ID, FamilyID: rt.FamilyID, UsedAt: &usedAt}, nil)
+	repo.On("RevokeFamily", mock.Anything, rt.FamilyID).Return(nil)
+
+	uc := NewAuthUsecase(cfg, token, repo, nil, nil)
+	_, err = uc.Refresh(context.Background(), &refreshRequest{RefreshToken: refreshToken})
+
+	assert.ErrorIs(t, err, errs.ErrRefreshTokenReused)
+	repo.AssertExpectations(t)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	step := security.TOTPStep(now)
+	secret := security.NewTOTPSecret()
+	code, err := security.TOTPCode(secret, step)
+	assert.NoError(t, err)
+
+	enabled := &TOTP{UserID: 1, Secret: secret, ConfirmedAt: &now}
+	pending := &TOTP{UserID: 1, Secret: secret}
+	cleared := func(repo *authRepositoryMock) {
+		repo.On("ClearLoginFailures", mock.Anything, ScopeOTP, "1").Return(nil)
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		mockSetup   func(repo *authRepositoryMock)
+		expectedErr error
+	}{
+		{
+			name: "current code",
+			code: code,
+			mockSetup: func(repo *authRepositoryMock) {
+				repo.On("FindTOTP", mock.Anything, int64(1)).Return(enabled, nil)
+				repo.On("UseTOTPStep", mock.Anything, int64(1), step).Return(true, nil)
+				cleared(repo)
+			},
+		},
+		{
+			name: "code already used",
+			code: code,
+			mockSetup: func(repo *authRepositoryMock) {
+				repo.On("FindTOTP", mock.Anything, int64(1)).Return(enabled, nil)
+				repo.On("UseTOTPStep", mock.Anything, int64(1), step).Return(false, nil)
+			},
+			expectedErr: errs.ErrTOTPInvalid,
+		},
+		{
+			name: "recovery code as typed",
+			code: "abcde-fghij",
+			mockSetup: func(repo *authRepositoryMock) {
+				repo.On("FindTOTP", mock.Anything, int64(1)).Return(enabled, nil)
+				repo.On("UseRecoveryCode", mock.Anything, int64(1), hashSecret("ABCDEFGHIJ")).Return(true, nil)
+				cleared(repo)
+			},
+		},
+		{
+			name: "wrong code",
+			code: "ABCDEFGHIJ",
+			mockSetup: func(repo *authRepositoryMock) {
+				repo.On("FindTOTP", mock.Anything, int64(1)).Return(enabled, nil)
+				repo.On("UseRecoveryCode", mock.Anything, int64(1), mock.Anything).Return(false, nil)
+			},
+			expectedErr: errs.ErrTOTPInvalid,
+		},
+		{
+			name: "enrollment not confirmed",
+			code: code,
+			mockSetup: func(repo *authRepositoryMock) {
+				repo.On("FindTOTP", mock.Anything, int64(1)).Return(pending, nil)
+			},
+			expectedErr: errs.ErrTOTPNotEnabled,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(authRepositoryMock)
+			repo.On("CountLoginAttempt", mock.Anything, ScopeOTP, "1", mock.Anything).Return(true, nil)
+			tc.mockSetup(repo)
+
+			uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil).(*authUsecase)
+			uc.now = func() time.Time { return now }
+			err := uc.VerifyTOTP(context.Background(), 1, tc.code)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	repo := new(authRepositoryMock)
+	repo.On("UseUserToken", mock.Anything, hashSecret("used"), PurposePasswordReset).Return(int64(0), errs.ErrUserTokenInvalid)
+
+	uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil)
+	err := uc.ResetPassword(context.Background(), &resetPasswordRequest{Token: "used", Password: "new-password"})
+
+	assert.ErrorIs(t, err, errs.ErrUserTokenInvalid)
+	repo.AssertExpectations(t)
+}
+
+func TestChangePassword(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(time.Minute)
+	req := &user.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "new-password"}
+
+	tests := []struct {
+		name        string
+		mockSetup   func(repo *authRepositoryMock, users *user.UserUsecaseMock)
+		expectedErr error
+	}{
+		{
+			name: "other sessions end",
+			mockSetup: func(repo *authRepositoryMock, users *user.UserUsecaseMock) {
+				repo.On("CountLoginAttempt", mock.Anything, ScopeEmail, "a@example.com", mock.Anything).Return(true, nil)
+				users.On("ChangePassword", mock.Anything, int64(1), req).Return(nil)
+				repo.On("ClearLoginFailures", mock.Anything, ScopeEmail, "a@example.com").Return(nil)
+				repo.On("RevokeOtherSessions", mock.Anything, int64(1), "sid").Return(nil)
+			},
+		},
+		{
+			name: "wrong current password counts as a failure",
+			mockSetup: func(repo *authRepositoryMock, users *user.UserUsecaseMock) {
+				repo.On("CountLoginAttempt", mock.Anything, ScopeEmail, "a@example.com", mock.Anything).Return(true, nil)
+				users.On("ChangePassword", mock.Anything, int64(1), req).Return(errs.ErrPasswordIncorrect)
+			},
+			expectedErr: errs.ErrPasswordIncorrect,
+		},
+		{
+			name: "locked out",
+			mockSetup: func(repo *authRepositoryMock, users *user.UserUsecaseMock) {
+				repo.On("CountLoginAttempt", mock.Anything, ScopeEmail, "a@example.com", mock.Anything).Return(false, nil)
+				repo.On("FindLoginThrottle", mock.Anything, ScopeEmail, "a@example.com").Return(&LoginThrottle{LockedUntil: &lockedUntil}, nil)
+			},
+			expectedErr: errs.ErrLoginThrottled,
+		},
+		{
+			name: "refused new password keeps sessions",
+			mockSetup: func(repo *authRepositoryMock, users *user.UserUsecaseMock) {
+				repo.On("CountLoginAttempt", mock.Anything, ScopeEmail, "a@example.com", mock.Anything).Return(true, nil)
+				users.On("ChangePassword", mock.Anything, int64(1), req).Return(errs.ErrPasswordReused)
+				repo.On("ClearLoginFailures", mock.Anything, ScopeEmail, "a@example.com").Return(nil)
+			},
+			expectedErr: errs.ErrPasswordReused,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(authRepositoryMock)
+			users := user.NewUserUsecaseMock()
+			users.On("GetUserByID", mock.Anything, int64(1)).Return(&user.User{ID: 1, Email: "A@example.com"}, nil)
+			tc.mockSetup(repo, users)
+
+			uc := NewAuthUsecase(testConfig(t), nil, repo, users, nil).(*authUsecase)
+			uc.now = func() time.Time { return now }
+			err := uc.ChangePassword(context.Background(), &security.TokenUser{ID: 1, SessionID: "sid"}, req)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+			users.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCountAttempt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(time.Minute)
+	retryAt := now.Add(2 * time.Second)
+	justEnded := now.Add(-time.Millisecond)
+
+	tests := []struct {
+		name       string
+		key        throttleKey
+		counted    bool
+		throttle   *LoginThrottle
+		retryAfter time.Duration
+	}{
+		{name: "counted", key: throttleKey{ScopeEmail, "a@example.com"}, counted: true},
+		{name: "locked", key: throttleKey{ScopeEmail, "a@example.com"}, throttle: &LoginThrottle{Failures: 5, RetryAt: &retryAt, LockedUntil: &lockedUntil}, retryAfter: time.Minute},
+		{name: "within delay", key: throttleKey{ScopeEmail, "a@example.com"}, throttle: &LoginThrottle{Failures: 4, RetryAt: &retryAt}, retryAfter: 2 * time.Second},
+		{name: "delay just ended", key: throttleKey{ScopeEmail, "a@example.com"}, throttle: &LoginThrottle{Failures: 4, RetryAt: &justEnded}, retryAfter: time.Second},
+		{name: "ip locked", key: throttleKey{ScopeIP, "10.0.0.1"}, throttle: &LoginThrottle{Failures: 20, LockedUntil: &lockedUntil}, retryAfter: time.Minute},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(authRepositoryMock)
+			repo.On("CountLoginAttempt", mock.Anything, tc.key.scope, tc.key.subject, mock.Anything).Return(tc.counted, nil)
+			if tc.throttle != nil {
+				repo.On("FindLoginThrottle", mock.Anything, tc.key.scope, tc.key.subject).Return(tc.throttle, nil)
+			}
+
+			uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil).(*authUsecase)
+			uc.now = func() time.Time { return now }
+			err := uc.countAttempt(context.Background(), tc.key)
+
+			if tc.retryAfter > 0 {
+				var te *errs.ThrottledError
+				assert.ErrorAs(t, err, &te)
+				assert.ErrorIs(t, err, errs.ErrLoginThrottled)
+				assert.Equal(t, tc.retryAfter, te.RetryAfter)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCountAttemptLimits(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		key      throttleKey
+		expected *LoginAttempt
+	}{
+		{
+			name: "email is delayed and locked",
+			key:  throttleKey{ScopeEmail, "a@example.com"},
+			expected: &LoginAttempt{
+				At:          now,
+				WindowStart: now.Add(-15 * time.Minute),
+				Delays:      []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second},
+				MaxFailures: 5,
+				LockedUntil: now.Add(15 * time.Minute),
+			},
+		},
+		{
+			name: "ip is only locked",
+			key:  throttleKey{ScopeIP, "10.0.0.1"},
+			expected: &LoginAttempt{
+				At:          now,
+				WindowStart: now.Add(-15 * time.Minute),
+				MaxFailures: 20,
+				LockedUntil: now.Add(15 * time.Minute),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(authRepositoryMock)
+			repo.On("CountLoginAttempt", mock.Anything, tc.key.scope, tc.key.subject, tc.expected).Return(true, nil)
+
+			uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil).(*authUsecase)
+			uc.now = func() time.Time { return now }
+			err := uc.countAttempt(context.Background(), tc.key)
+
+			assert.NoError(t, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	repo := new(authRepositoryMock)
+	repo.On("ListSessions", mock.Anything, int64(1)).Return([]*Session{{ID: "a"}, {ID: "b"}}, nil)
+
+	uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil)
+	sessions, err := uc.ListSessions(context.Background(), 1, "b")
+
+	assert.NoError(t, err)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+	repo.AssertExpectations(t)
+}
+
+func TestTouchSessionAtMostOncePerInterval(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	u := &security.TokenUser{ID: 1, SessionID: "sid"}
+
+	repo := new(authRepositoryMock)
+	repo.On("TouchSession", mock.Anything, "sid", "10.0.0.1").Return(nil).Twice()
+
+	uc := NewAuthUsecase(testConfig(t), nil, repo, nil, nil).(*authUsecase)
+	uc.now = func() time.Time { return now }
+
+	uc.TouchSession(context.Background(), u, "10.0.0.1")
+	uc.TouchSession(context.Background(), u, "10.0.0.1")
+
+	now = now.Add(sessionTouchInterval)
+	uc.TouchSession(context.Background(), u, "10.0.0.1")
+
+	// Tokens of API keys and clients have no session.
+	uc.TouchSession(context.Background(), &security.TokenUser{ID: 1}, "10.0.0.1")
+
+	repo.AssertExpectations(t)
+}
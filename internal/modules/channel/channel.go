@@ -0,0 +1,73 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type channelType string
+
+const (
+	TypeAPIKey channelType = "API_KEY"
+	TypeHMAC   channelType = "HMAC"
+)
+
+// Channel is a trusted system allowed to deposit money, e.g. a payment
+// gateway or an ATM network.
+type Channel struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Type      channelType `json:"type"`
+	KeyHash   *string     `json:"-"`
+	Secret    *string     `json:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+	RevokedAt *time.Time  `json:"revoked_at"`
+}
+
+// Credentials are shown once, when the channel is created.
+type Credentials struct {
+	Channel *Channel `json:"channel"`
+	APIKey  string   `json:"api_key,omitempty"`
+	Secret  string   `json:"secret,omitempty"`
+}
+
+// SignedRequest is what an HMAC channel signs: the unix timestamp, the
+// method, the path and the raw body of the request.
+type SignedRequest struct {
+	ChannelID int64
+	Timestamp string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of a request.
+func Sign(secret, timestamp, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{timestamp, method, path, ""}, "\n")))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Current returns the channel that authenticated the request.
+func Current(ctx *gin.Context) (*Channel, bool) {
+	val, ok := ctx.Get(utils.ContextKeyChannel)
+	if !ok {
+		return nil, false
+	}
+
+	ch, ok := val.(*Channel)
+	return ch, ok
+}
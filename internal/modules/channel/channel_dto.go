@@ -0,0 +1,6 @@
+package channel
+
+type ChannelRequest struct {
+	Name string      `json:"name" validate:"required,max=100"`
+	Type channelType `json:"type" validate:"required,oneof=API_KEY HMAC"`
+}
@@ -0,0 +1,82 @@
+package channel
+
+import (
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type channelHandler struct {
+	uc       ChannelUsecase
+	validate *validator.Validate
+}
+
+func NewChannelHandler(uc ChannelUsecase) *channelHandler {
+	return &channelHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
+}
+
+func (h *channelHandler) CreateChannel(ctx *gin.Context) {
+	req := new(ChannelRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	result, err := h.uc.Create(ctx.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrChannelTypeInvalid):
+			response.ErrBadRequest(ctx, err)
+		case errors.Is(err, errs.ErrChannelNameTaken):
+			response.ErrConflict(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
+func (h *channelHandler) ListChannels(ctx *gin.Context) {
+	result, err := h.uc.List(ctx.Request.Context())
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *channelHandler) RevokeChannel(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.uc.Revoke(ctx.Request.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrChannelNotFound) {
+			response.ErrNotFound(ctx, err)
+			return
+		}
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "channel revoked")
+}
@@ -0,0 +1,158 @@
+package channel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/lib/pq"
+)
+
+type ChannelRepository interface {
+	Create(ctx context.Context, ch *Channel) (*Channel, error)
+	FindByID(ctx context.Context, id int64) (*Channel, error)
+	FindByKeyHash(ctx context.Context, keyHash string) (*Channel, error)
+	List(ctx context.Context) ([]*Channel, error)
+	Revoke(ctx context.Context, id int64) error
+	UseNonce(ctx context.Context, channelID int64, signature string, expiresAt time.Time) (bool, error)
+}
+
+type channelRepository struct {
+	db *sql.DB
+}
+
+func NewChannelRepository(db *sql.DB) ChannelRepository {
+	return &channelRepository{db: db}
+}
+
+func scanChannel(row interface{ Scan(dest ...any) error }) (*Channel, error) {
+	ch := new(Channel)
+
+	err := row.Scan(
+		&ch.ID,
+		&ch.Name,
+		&ch.Type,
+		&ch.KeyHash,
+		&ch.Secret,
+		&ch.CreatedAt,
+		&ch.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+func (r *channelRepository) Create(ctx context.Context, ch *Channel) (*Channel, error) {
+	query := `
+		INSERT INTO deposit_channels (name, type, key_hash, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, ch.Name, ch.Type, ch.KeyHash, ch.Secret).Scan(&ch.ID, &ch.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, errs.ErrChannelNameTaken
+		}
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+func (r *channelRepository) FindByID(ctx context.Context, id int64) (*Channel, error) {
+	query := `
+		SELECT id, name, type, key_hash, secret, created_at, revoked_at
+		FROM deposit_channels WHERE id = $1
+	`
+	return scanChannel(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *channelRepository) FindByKeyHash(ctx context.Context, keyHash string) (*Channel, error) {
+	query := `
+		SELECT id, name, type, key_hash, secret, created_at, revoked_at
+		FROM deposit_channels WHERE key_hash = $1
+	`
+	return scanChannel(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+func (r *channelRepository) List(ctx context.Context) ([]*Channel, error) {
+	query := `
+		SELECT id, name, type, key_hash, secret, created_at, revoked_at
+		FROM deposit_channels ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []*Channel
+	for rows.Next() {
+		ch, err := scanChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+func (r *channelRepository) Revoke(ctx context.Context, id int64) error {
+	query := `
+		UPDATE deposit_channels SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrChannelNotFound
+	}
+
+	return nil
+}
+
+// UseNonce records the signature of a request and reports whether it was
+// new. Expired signatures of the channel are removed on the way.
+func (r *channelRepository) UseNonce(ctx context.Context, channelID int64, signature string, expiresAt time.Time) (bool, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM channel_nonces
+			WHERE channel_id = $1 AND expires_at < NOW()
+		)
+		INSERT INTO channel_nonces (channel_id, signature, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel_id, signature) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, channelID, signature, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
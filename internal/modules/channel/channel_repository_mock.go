@@ -0,0 +1,60 @@
+package channel
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type channelRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *channelRepositoryMock) Create(ctx context.Context, ch *Channel) (*Channel, error) {
+	args := m.Called(ctx, ch)
+
+	res, ok := args.Get(0).(*Channel)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *channelRepositoryMock) FindByID(ctx context.Context, id int64) (*Channel, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*Channel)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *channelRepositoryMock) FindByKeyHash(ctx context.Context, keyHash string) (*Channel, error) {
+	args := m.Called(ctx, keyHash)
+
+	res, ok := args.Get(0).(*Channel)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *channelRepositoryMock) List(ctx context.Context) ([]*Channel, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*Channel), args.Error(1)
+}
+
+func (m *channelRepositoryMock) Revoke(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *channelRepositoryMock) UseNonce(ctx context.Context, channelID int64, signature string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, channelID, signature, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
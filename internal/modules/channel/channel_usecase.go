@@ -0,0 +1,151 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+)
+
+const (
+	queryTimeout = time.Second * 5
+
+	// maxClockSkew is how far the timestamp of a signed request may be
+	// from now. Signatures are remembered this long after the timestamp, so
+	// a captured request cannot be replayed.
+	maxClockSkew = time.Minute * 5
+
+	apiKeyPrefix = "sbk_"
+)
+
+type ChannelUsecase interface {
+	Create(ctx context.Context, req *ChannelRequest) (*Credentials, error)
+	List(ctx context.Context) ([]*Channel, error)
+	Revoke(ctx context.Context, id int64) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*Channel, error)
+	AuthenticateHMAC(ctx context.Context, req *SignedRequest) (*Channel, error)
+}
+
+type channelUsecase struct {
+	repo ChannelRepository
+	now  func() time.Time
+}
+
+func NewChannelUsecase(repo ChannelRepository) ChannelUsecase {
+	return &channelUsecase{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Create registers a channel and returns its credentials. They are not
+// stored in a readable form for API keys and are never shown again.
+func (uc *channelUsecase) Create(ctx context.Context, req *ChannelRequest) (*Credentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	ch := &Channel{Name: req.Name, Type: req.Type}
+	creds := &Credentials{Channel: ch}
+
+	switch req.Type {
+	case TypeAPIKey:
+		creds.APIKey = apiKeyPrefix + rand.Text()
+		hash := hashKey(creds.APIKey)
+		ch.KeyHash = &hash
+	case TypeHMAC:
+		creds.Secret = rand.Text()
+		ch.Secret = &creds.Secret
+	default:
+		return nil, errs.ErrChannelTypeInvalid
+	}
+
+	if _, err := uc.repo.Create(ctx, ch); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+func (uc *channelUsecase) List(ctx context.Context) ([]*Channel, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx)
+}
+
+func (uc *channelUsecase) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.Revoke(ctx, id)
+}
+
+func (uc *channelUsecase) AuthenticateAPIKey(ctx context.Context, key string) (*Channel, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	ch, err := uc.repo.FindByKeyHash(ctx, hashKey(key))
+	if err != nil {
+		return nil, unauthorized(err)
+	}
+
+	if ch.Type != TypeAPIKey || ch.RevokedAt != nil {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	return ch, nil
+}
+
+// AuthenticateHMAC checks the signature of a request, that it was signed
+// recently and that it was not received before.
+func (uc *channelUsecase) AuthenticateHMAC(ctx context.Context, req *SignedRequest) (*Channel, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	unix, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	skew := uc.now().Sub(time.Unix(unix, 0))
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	ch, err := uc.repo.FindByID(ctx, req.ChannelID)
+	if err != nil {
+		return nil, unauthorized(err)
+	}
+
+	if ch.Type != TypeHMAC || ch.RevokedAt != nil || ch.Secret == nil {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	expected := Sign(*ch.Secret, req.Timestamp, req.Method, req.Path, req.Body)
+	if !hmac.Equal([]byte(expected), []byte(req.Signature)) {
+		return nil, errs.ErrChannelUnauthorized
+	}
+
+	fresh, err := uc.repo.UseNonce(ctx, ch.ID, req.Signature, time.Unix(unix, 0).Add(maxClockSkew))
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, fmt.Errorf("%w: request was already received", errs.ErrChannelUnauthorized)
+	}
+
+	return ch, nil
+}
+
+// unauthorized hides whether a channel exists from the caller.
+func unauthorized(err error) error {
+	if errors.Is(err, errs.ErrChannelNotFound) {
+		return errs.ErrChannelUnauthorized
+	}
+	return err
+}
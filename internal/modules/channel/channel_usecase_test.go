@@ -0,0 +1,101 @@
+package channel
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthenticateHMAC(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	secret := "shared-secret"
+	revokedAt := now.Add(-time.Hour)
+
+	active := &Channel{ID: 1, Type: TypeHMAC, Secret: &secret}
+	revoked := &Channel{ID: 2, Type: TypeHMAC, Secret: &secret, RevokedAt: &revokedAt}
+
+	body := []byte(`{"to_account":1,"amount":"100"}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+
+	signed := func(ch *Channel, ts string) *SignedRequest {
+		return &SignedRequest{
+			ChannelID: ch.ID,
+			Timestamp: ts,
+			Signature: Sign(secret, ts, "POST", "/transactions/deposit/channel", body),
+			Method:    "POST",
+			Path:      "/transactions/deposit/channel",
+			Body:      body,
+		}
+	}
+
+	fresh, seen := true, false
+
+	tampered := signed(active, ts)
+	tampered.Body = []byte(`{"to_account":1,"amount":"100000"}`)
+
+	tests := []struct {
+		name        string
+		req         *SignedRequest
+		channel     *Channel
+		nonce       *bool
+		expectedErr error
+	}{
+		{name: "valid signature", req: signed(active, ts), channel: active, nonce: &fresh},
+		{name: "replayed", req: signed(active, ts), channel: active, nonce: &seen, expectedErr: errs.ErrChannelUnauthorized},
+		{name: "tampered body", req: tampered, channel: active, expectedErr: errs.ErrChannelUnauthorized},
+		{name: "stale timestamp", req: signed(active, stale), expectedErr: errs.ErrChannelUnauthorized},
+		{name: "revoked channel", req: signed(revoked, ts), channel: revoked, expectedErr: errs.ErrChannelUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(channelRepositoryMock)
+			if tc.channel != nil {
+				repo.On("FindByID", mock.Anything, tc.channel.ID).Return(tc.channel, nil)
+			}
+			if tc.nonce != nil {
+				// The signature is remembered until its timestamp is too old.
+				repo.On("UseNonce", mock.Anything, tc.channel.ID, tc.req.Signature, mock.MatchedBy(func(expiresAt time.Time) bool {
+					return expiresAt.Equal(now.Add(maxClockSkew))
+				})).Return(*tc.nonce, nil)
+			}
+
+			uc := &channelUsecase{repo: repo, now: func() time.Time { return now }}
+			ch, err := uc.AuthenticateHMAC(context.Background(), tc.req)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.channel, ch)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	repo := new(channelRepositoryMock)
+	repo.On("Create", mock.Anything, mock.Anything).Return(&Channel{}, nil)
+
+	uc := NewChannelUsecase(repo)
+	creds, err := uc.Create(context.Background(), &ChannelRequest{Name: "gateway", Type: TypeAPIKey})
+	assert.NoError(t, err)
+	assert.Nil(t, creds.Channel.Secret)
+
+	repo.On("FindByKeyHash", mock.Anything, *creds.Channel.KeyHash).Return(creds.Channel, nil)
+	repo.On("FindByKeyHash", mock.Anything, mock.Anything).Return(nil, errs.ErrChannelNotFound)
+
+	ch, err := uc.AuthenticateAPIKey(context.Background(), creds.APIKey)
+	assert.NoError(t, err)
+	assert.Equal(t, creds.Channel, ch)
+
+	_, err = uc.AuthenticateAPIKey(context.Background(), "sbk_wrong")
+	assert.ErrorIs(t, err, errs.ErrChannelUnauthorized)
+}
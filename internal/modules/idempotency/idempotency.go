@@ -0,0 +1,21 @@
+package idempotency
+
+import "time"
+
+type Record struct {
+	ID          int64      `json:"id"`
+	Key         string     `json:"key"`
+	UserID      int64      `json:"user_id"`
+	Fingerprint string     `json:"fingerprint"`
+	StatusCode  *int       `json:"status_code"`
+	Response    []byte     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// Completed reports whether the original request finished and its response
+// can be replayed.
+func (r *Record) Completed() bool {
+	return r.CompletedAt != nil
+}
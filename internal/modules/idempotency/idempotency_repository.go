@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, rec *Record) (bool, error)
+	Find(ctx context.Context, userID int64, key string) (*Record, error)
+	Complete(ctx context.Context, id int64, statusCode int, response []byte) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve inserts the key if it is free and reports whether it was reserved.
+// Expired keys are removed first so they can be reused.
+func (r *idempotencyRepository) Reserve(ctx context.Context, rec *Record) (bool, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at < NOW()",
+		rec.UserID,
+		rec.Key,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (key, user_id, fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO NOTHING
+		RETURNING id, created_at
+	`
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		rec.Key,
+		rec.UserID,
+		rec.Fingerprint,
+		rec.ExpiresAt,
+	).Scan(
+		&rec.ID,
+		&rec.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *idempotencyRepository) Find(ctx context.Context, userID int64, key string) (*Record, error) {
+	query := `
+		SELECT id, key, user_id, fingerprint, status_code, response, created_at, completed_at, expires_at
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2 LIMIT 1;
+	`
+	rec := new(Record)
+
+	err := r.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&rec.ID,
+		&rec.Key,
+		&rec.UserID,
+		&rec.Fingerprint,
+		&rec.StatusCode,
+		&rec.Response,
+		&rec.CreatedAt,
+		&rec.CompletedAt,
+		&rec.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, id int64, statusCode int, response []byte) error {
+	query := `
+		UPDATE idempotency_keys SET status_code = $1, response = $2, completed_at = NOW()
+		WHERE id = $3
+	`
+	_, err := r.db.ExecContext(ctx, query, statusCode, response, id)
+	return err
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,37 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type idempotencyRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *idempotencyRepositoryMock) Reserve(ctx context.Context, rec *Record) (bool, error) {
+	args := m.Called(ctx, rec)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *idempotencyRepositoryMock) Find(ctx context.Context, userID int64, key string) (*Record, error) {
+	args := m.Called(ctx, userID, key)
+
+	res, ok := args.Get(0).(*Record)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *idempotencyRepositoryMock) Complete(ctx context.Context, id int64, statusCode int, response []byte) error {
+	args := m.Called(ctx, id, statusCode, response)
+	return args.Error(0)
+}
+
+func (m *idempotencyRepositoryMock) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+)
+
+const (
+	queryTimeout = time.Second * 5
+	keyTTL       = time.Hour * 24
+	maxKeyLength = 255
+)
+
+type IdempotencyUsecase interface {
+	Begin(ctx context.Context, userID int64, key, method, path string, body []byte) (*Record, error)
+	Complete(ctx context.Context, id int64, statusCode int, response []byte) error
+	Release(ctx context.Context, id int64) error
+}
+
+type idempotencyUsecase struct {
+	repo IdempotencyRepository
+}
+
+func NewIdempotencyUsecase(repo IdempotencyRepository) IdempotencyUsecase {
+	return &idempotencyUsecase{repo: repo}
+}
+
+// Begin reserves the key for a new request. When the key was already used
+// with the same request the stored record is returned so its response can be
+// replayed; a different request under the same key is rejected.
+func (uc *idempotencyUsecase) Begin(ctx context.Context, userID int64, key, method, path string, body []byte) (*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if key == "" || len(key) > maxKeyLength {
+		return nil, errs.ErrIdempotencyKeyInvalid
+	}
+
+	rec := &Record{
+		Key:         key,
+		UserID:      userID,
+		Fingerprint: fingerprint(method, path, body),
+		ExpiresAt:   time.Now().Add(keyTTL),
+	}
+
+	reserved, err := uc.repo.Reserve(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return rec, nil
+	}
+
+	existing, err := uc.repo.Find(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.Fingerprint != rec.Fingerprint {
+		return nil, errs.ErrIdempotencyKeyReused
+	}
+
+	if !existing.Completed() {
+		return nil, errs.ErrIdempotencyInProgress
+	}
+
+	return existing, nil
+}
+
+func (uc *idempotencyUsecase) Complete(ctx context.Context, id int64, statusCode int, response []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.Complete(ctx, id, statusCode, response)
+}
+
+// Release frees a reserved key whose request did not succeed, so the client
+// can retry it.
+func (uc *idempotencyUsecase) Release(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.Delete(ctx, id)
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
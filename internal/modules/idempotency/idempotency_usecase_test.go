@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBegin(t *testing.T) {
+	body := []byte(`{"amount":"10"}`)
+	sum := fingerprint(http.MethodPost, "/transactions/transfer", body)
+	status := http.StatusCreated
+	completedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		key         string
+		body        []byte
+		mockSetup   func(repo *idempotencyRepositoryMock)
+		expectedID  int64
+		expectedErr error
+	}{
+		{
+			name: "new key is reserved",
+			key:  "key-1",
+			body: body,
+			mockSetup: func(repo *idempotencyRepositoryMock) {
+				repo.On("Reserve", mock.Anything, mock.MatchedBy(func(rec *Record) bool {
+					return rec.UserID == 7 && rec.Key == "key-1" && rec.Fingerprint == sum
+				})).Run(func(args mock.Arguments) {
+					args.Get(1).(*Record).ID = 1
+				}).Return(true, nil)
+			},
+			expectedID: 1,
+		},
+		{
+			name: "completed key is replayed",
+			key:  "key-1",
+			body: body,
+			mockSetup: func(repo *idempotencyRepositoryMock) {
+				repo.On("Reserve", mock.Anything, mock.Anything).Return(false, nil)
+				repo.On("Find", mock.Anything, int64(7), "key-1").Return(&Record{ID: 2, Fingerprint: sum, StatusCode: &status, CompletedAt: &completedAt}, nil)
+			},
+			expectedID: 2,
+		},
+		{
+			name: "same key with a different body",
+			key:  "key-1",
+			body: []byte(`{"amount":"20"}`),
+			mockSetup: func(repo *idempotencyRepositoryMock) {
+				repo.On("Reserve", mock.Anything, mock.Anything).Return(false, nil)
+				repo.On("Find", mock.Anything, int64(7), "key-1").Return(&Record{ID: 2, Fingerprint: sum, StatusCode: &status, CompletedAt: &completedAt}, nil)
+			},
+			expectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			name: "key still in progress",
+			key:  "key-1",
+			body: body,
+			mockSetup: func(repo *idempotencyRepositoryMock) {
+				repo.On("Reserve", mock.Anything, mock.Anything).Return(false, nil)
+				repo.On("Find", mock.Anything, int64(7), "key-1").Return(&Record{ID: 2, Fingerprint: sum}, nil)
+			},
+			expectedErr: errs.ErrIdempotencyInProgress,
+		},
+		{
+			name:        "empty key",
+			body:        body,
+			mockSetup:   func(repo *idempotencyRepositoryMock) {},
+			expectedErr: errs.ErrIdempotencyKeyInvalid,
+		},
+		{
+			name:        "key too long",
+			key:         strings.Repeat("k", maxKeyLength+1),
+			body:        body,
+			mockSetup:   func(repo *idempotencyRepositoryMock) {},
+			expectedErr: errs.ErrIdempotencyKeyInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(idempotencyRepositoryMock)
+			tc.mockSetup(repo)
+
+			uc := NewIdempotencyUsecase(repo)
+			rec, err := uc.Begin(context.Background(), 7, tc.key, http.MethodPost, "/transactions/transfer", tc.body)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, rec)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedID, rec.ID)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
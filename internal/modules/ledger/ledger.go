@@ -0,0 +1,25 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+// System accounts are internal accounts without an owner that act as the
+// counterparty for money entering or leaving the bank.
+const (
+	SystemCashVault = "CASH_VAULT"
+)
+
+// Entry is one leg of a transaction. A positive amount credits the account
+// (increases its balance) and a negative amount debits it. The entries of a
+// transaction share one currency and always sum to zero.
+type Entry struct {
+	ID            int64       `json:"id"`
+	TransactionID int64       `json:"transaction_id"`
+	AccountID     int64       `json:"account_id"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
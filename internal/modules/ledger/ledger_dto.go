@@ -0,0 +1,28 @@
+package ledger
+
+import "github.com/codepnw/simple-bank/internal/utils/money"
+
+type BalanceMismatch struct {
+	AccountID     int64       `json:"account_id"`
+	Currency      string      `json:"currency"`
+	Balance       money.Money `json:"balance"`
+	LedgerBalance money.Money `json:"ledger_balance"`
+}
+
+type UnbalancedTransaction struct {
+	TransactionID int64       `json:"transaction_id"`
+	Currency      string      `json:"currency"`
+	Total         money.Money `json:"total"`
+}
+
+type CurrencyTotal struct {
+	Currency string      `json:"currency"`
+	Total    money.Money `json:"total"`
+}
+
+type VerifyResult struct {
+	Balanced               bool                     `json:"balanced"`
+	Totals                 []*CurrencyTotal         `json:"totals"`
+	UnbalancedTransactions []*UnbalancedTransaction `json:"unbalanced_transactions"`
+	BalanceMismatches      []*BalanceMismatch       `json:"balance_mismatches"`
+}
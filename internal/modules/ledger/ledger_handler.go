@@ -0,0 +1,24 @@
+package ledger
+
+import (
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type ledgerHandler struct {
+	uc LedgerUsecase
+}
+
+func NewLedgerHandler(uc LedgerUsecase) *ledgerHandler {
+	return &ledgerHandler{uc: uc}
+}
+
+func (h *ledgerHandler) Verify(ctx *gin.Context) {
+	result, err := h.uc.Verify(ctx.Request.Context())
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
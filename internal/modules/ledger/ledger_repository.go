@@ -0,0 +1,205 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+type LedgerRepository interface {
+	CreateEntriesWithTx(ctx context.Context, tx *sql.Tx, entries []*Entry) error
+	EntriesByTransactionWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error)
+	SystemAccountID(ctx context.Context, code, currency string) (int64, error)
+	Totals(ctx context.Context) ([]*CurrencyTotal, error)
+	UnbalancedTransactions(ctx context.Context) ([]*UnbalancedTransaction, error)
+	BalanceMismatches(ctx context.Context) ([]*BalanceMismatch, error)
+}
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) CreateEntriesWithTx(ctx context.Context, tx *sql.Tx, entries []*Entry) error {
+	query := `
+		INSERT INTO ledger_entries (transaction_id, account_id, amount, currency)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	for _, e := range entries {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			e.TransactionID,
+			e.AccountID,
+			e.Amount.Minor(),
+			e.Currency,
+		).Scan(
+			&e.ID,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ledgerRepository) EntriesByTransactionWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error) {
+	query := `
+		SELECT id, transaction_id, account_id, amount, currency, created_at
+		FROM ledger_entries WHERE transaction_id = $1
+		ORDER BY id
+	`
+	rows, err := tx.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+
+	for rows.Next() {
+		e := new(Entry)
+		var amount int64
+
+		err = rows.Scan(
+			&e.ID,
+			&e.TransactionID,
+			&e.AccountID,
+			&amount,
+			&e.Currency,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Amount = money.FromMinor(amount, e.Currency)
+
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *ledgerRepository) SystemAccountID(ctx context.Context, code, currency string) (int64, error) {
+	var id int64
+	query := `SELECT id FROM accounts WHERE system_code = $1 AND currency = $2`
+
+	err := r.db.QueryRowContext(ctx, query, code, currency).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *ledgerRepository) Totals(ctx context.Context) ([]*CurrencyTotal, error) {
+	query := `
+		SELECT currency, SUM(amount)
+		FROM ledger_entries
+		GROUP BY currency
+		ORDER BY currency
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*CurrencyTotal
+
+	for rows.Next() {
+		var total int64
+		t := new(CurrencyTotal)
+		if err = rows.Scan(&t.Currency, &total); err != nil {
+			return nil, err
+		}
+		t.Total = money.FromMinor(total, t.Currency)
+		result = append(result, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *ledgerRepository) UnbalancedTransactions(ctx context.Context) ([]*UnbalancedTransaction, error) {
+	query := `
+		SELECT transaction_id, currency, SUM(amount)
+		FROM ledger_entries
+		GROUP BY transaction_id, currency
+		HAVING SUM(amount) <> 0
+		ORDER BY transaction_id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*UnbalancedTransaction
+
+	for rows.Next() {
+		var total int64
+		u := new(UnbalancedTransaction)
+		if err = rows.Scan(&u.TransactionID, &u.Currency, &total); err != nil {
+			return nil, err
+		}
+		u.Total = money.FromMinor(total, u.Currency)
+		result = append(result, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *ledgerRepository) BalanceMismatches(ctx context.Context) ([]*BalanceMismatch, error) {
+	query := `
+		SELECT a.id, a.currency, a.balance, COALESCE(SUM(e.amount), 0)
+		FROM accounts a
+		LEFT JOIN ledger_entries e ON e.account_id = a.id
+		WHERE a.system_code IS NULL
+		GROUP BY a.id, a.currency, a.balance
+		HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+		ORDER BY a.id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*BalanceMismatch
+
+	for rows.Next() {
+		var balance, ledgerBalance int64
+		m := new(BalanceMismatch)
+		if err = rows.Scan(&m.AccountID, &m.Currency, &balance, &ledgerBalance); err != nil {
+			return nil, err
+		}
+		m.Balance = money.FromMinor(balance, m.Currency)
+		m.LedgerBalance = money.FromMinor(ledgerBalance, m.Currency)
+		result = append(result, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ledgerRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ledgerRepositoryMock) CreateEntriesWithTx(ctx context.Context, tx *sql.Tx, entries []*Entry) error {
+	args := m.Called(ctx, tx, entries)
+	return args.Error(0)
+}
+
+func (m *ledgerRepositoryMock) EntriesByTransactionWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error) {
+	args := m.Called(ctx, tx, transactionID)
+
+	res, ok := args.Get(0).([]*Entry)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *ledgerRepositoryMock) SystemAccountID(ctx context.Context, code, currency string) (int64, error) {
+	args := m.Called(ctx, code, currency)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ledgerRepositoryMock) Totals(ctx context.Context) ([]*CurrencyTotal, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).([]*CurrencyTotal)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *ledgerRepositoryMock) UnbalancedTransactions(ctx context.Context) ([]*UnbalancedTransaction, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).([]*UnbalancedTransaction)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *ledgerRepositoryMock) BalanceMismatches(ctx context.Context) ([]*BalanceMismatch, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).([]*BalanceMismatch)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
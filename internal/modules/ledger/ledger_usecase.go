@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+const queryTimeout = time.Second * 5
+
+type LedgerUsecase interface {
+	PostWithTx(ctx context.Context, tx *sql.Tx, transactionID int64, entries []*Entry) error
+	EntriesWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error)
+	SystemAccountID(ctx context.Context, code, currency string) (int64, error)
+	Verify(ctx context.Context) (*VerifyResult, error)
+}
+
+type ledgerUsecase struct {
+	repo       LedgerRepository
+	accUsecase account.AccountUsecase
+}
+
+func NewLedgerUsecase(repo LedgerRepository, accUsecase account.AccountUsecase) LedgerUsecase {
+	return &ledgerUsecase{
+		repo:       repo,
+		accUsecase: accUsecase,
+	}
+}
+
+// PostWithTx records balanced entries for a transaction and applies them to
+// the account balances inside the caller's transaction.
+func (uc *ledgerUsecase) PostWithTx(ctx context.Context, tx *sql.Tx, transactionID int64, entries []*Entry) error {
+	if len(entries) < 2 {
+		return errs.ErrLedgerUnbalanced
+	}
+
+	var total money.Money
+	for _, e := range entries {
+		if e.Amount.IsZero() {
+			return errs.ErrAccountAmountNotZero
+		}
+
+		sum, err := total.Add(e.Amount)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrLedgerUnbalanced, err)
+		}
+		total = sum
+
+		e.TransactionID = transactionID
+		e.Currency = e.Amount.Currency()
+	}
+
+	if !total.IsZero() {
+		return errs.ErrLedgerUnbalanced
+	}
+
+	if err := uc.repo.CreateEntriesWithTx(ctx, tx, entries); err != nil {
+		return fmt.Errorf("insert ledger entries failed: %w", err)
+	}
+
+	// Apply balances in account ID order, the same order the callers lock
+	// accounts in.
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b *Entry) int {
+		return cmp.Compare(a.AccountID, b.AccountID)
+	})
+
+	for _, e := range ordered {
+		if err := uc.accUsecase.UpdateBalanceWithTx(ctx, tx, e.AccountID, e.Amount); err != nil {
+			return fmt.Errorf("update balance of account %d failed: %w", e.AccountID, err)
+		}
+	}
+
+	return nil
+}
+
+func (uc *ledgerUsecase) EntriesWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error) {
+	return uc.repo.EntriesByTransactionWithTx(ctx, tx, transactionID)
+}
+
+// SystemAccountID returns the system account with code in currency. There
+// is one per supported currency; others fail with ErrCurrencyNotSupported.
+func (uc *ledgerUsecase) SystemAccountID(ctx context.Context, code, currency string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	id, err := uc.repo.SystemAccountID(ctx, code, currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("system account %s %s: %w", code, currency, errs.ErrCurrencyNotSupported)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("system account %s %s: %w", code, currency, err)
+	}
+
+	return id, nil
+}
+
+// Verify proves the books: entries sum to zero per currency, every
+// transaction is balanced and every customer account balance equals the sum
+// of its entries. System account balances are that sum by definition.
+func (uc *ledgerUsecase) Verify(ctx context.Context) (*VerifyResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	totals, err := uc.repo.Totals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unbalanced, err := uc.repo.UnbalancedTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches, err := uc.repo.BalanceMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balanced := len(unbalanced) == 0 && len(mismatches) == 0
+	for _, t := range totals {
+		if !t.Total.IsZero() {
+			balanced = false
+		}
+	}
+
+	result := &VerifyResult{
+		Balanced:               balanced,
+		Totals:                 totals,
+		UnbalancedTransactions: unbalanced,
+		BalanceMismatches:      mismatches,
+	}
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type LedgerUsecaseMock struct {
+	mock.Mock
+}
+
+func NewLedgerUsecaseMock() *LedgerUsecaseMock {
+	return &LedgerUsecaseMock{}
+}
+
+func (m *LedgerUsecaseMock) PostWithTx(ctx context.Context, tx *sql.Tx, transactionID int64, entries []*Entry) error {
+	args := m.Called(ctx, tx, transactionID, entries)
+	return args.Error(0)
+}
+
+func (m *LedgerUsecaseMock) EntriesWithTx(ctx context.Context, tx *sql.Tx, transactionID int64) ([]*Entry, error) {
+	args := m.Called(ctx, tx, transactionID)
+
+	res, ok := args.Get(0).([]*Entry)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *LedgerUsecaseMock) SystemAccountID(ctx context.Context, code, currency string) (int64, error) {
+	args := m.Called(ctx, code, currency)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *LedgerUsecaseMock) Verify(ctx context.Context) (*VerifyResult, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).(*VerifyResult)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
@@ -0,0 +1,167 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostWithTx(t *testing.T) {
+	thb := func(minor int64) money.Money { return money.FromMinor(minor, "THB") }
+	usd := func(minor int64) money.Money { return money.FromMinor(minor, "USD") }
+
+	tests := []struct {
+		name          string
+		entries       []*Entry
+		updateErr     error
+		expectedOrder []int64
+		expectedErr   error
+	}{
+		{
+			name: "balances applied in account ID order",
+			entries: []*Entry{
+				{AccountID: 9, Amount: thb(-1500)},
+				{AccountID: 3, Amount: thb(1000)},
+				{AccountID: 5, Amount: thb(500)},
+			},
+			expectedOrder: []int64{3, 5, 9},
+		},
+		{
+			name:        "no entries",
+			expectedErr: errs.ErrLedgerUnbalanced,
+		},
+		{
+			name:        "single entry",
+			entries:     []*Entry{{AccountID: 3, Amount: thb(1000)}},
+			expectedErr: errs.ErrLedgerUnbalanced,
+		},
+		{
+			name: "entries do not sum to zero",
+			entries: []*Entry{
+				{AccountID: 3, Amount: thb(1000)},
+				{AccountID: 9, Amount: thb(-999)},
+			},
+			expectedErr: errs.ErrLedgerUnbalanced,
+		},
+		{
+			name: "zero entry",
+			entries: []*Entry{
+				{AccountID: 3, Amount: thb(1000)},
+				{AccountID: 5, Amount: thb(0)},
+				{AccountID: 9, Amount: thb(-1000)},
+			},
+			expectedErr: errs.ErrAccountAmountNotZero,
+		},
+		{
+			name: "currencies mixed",
+			entries: []*Entry{
+				{AccountID: 3, Amount: thb(1000)},
+				{AccountID: 9, Amount: usd(-1000)},
+			},
+			expectedErr: errs.ErrLedgerUnbalanced,
+		},
+		{
+			name: "overdraft stops the posting",
+			entries: []*Entry{
+				{AccountID: 3, Amount: thb(-1000)},
+				{AccountID: 9, Amount: thb(1000)},
+			},
+			updateErr:     errs.ErrAmountGreaterAccBalance,
+			expectedOrder: []int64{3},
+			expectedErr:   errs.ErrAmountGreaterAccBalance,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(ledgerRepositoryMock)
+			accUsecase := account.NewAccountUsecaseMock()
+
+			var order []int64
+			if tc.expectedOrder != nil {
+				repo.On("CreateEntriesWithTx", mock.Anything, mock.Anything, tc.entries).Return(nil)
+				accUsecase.On("UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						order = append(order, args.Get(2).(int64))
+					}).
+					Return(tc.updateErr)
+			}
+
+			uc := NewLedgerUsecase(repo, accUsecase)
+			err := uc.PostWithTx(context.Background(), nil, 42, tc.entries)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				for _, e := range tc.entries {
+					assert.Equal(t, int64(42), e.TransactionID)
+					assert.Equal(t, "THB", e.Currency)
+				}
+			}
+			assert.Equal(t, tc.expectedOrder, order)
+			repo.AssertExpectations(t)
+			accUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	balancedTotals := []*CurrencyTotal{
+		{Currency: "THB", Total: money.Zero("THB")},
+		{Currency: "USD", Total: money.Zero("USD")},
+	}
+
+	tests := []struct {
+		name       string
+		totals     []*CurrencyTotal
+		unbalanced []*UnbalancedTransaction
+		mismatches []*BalanceMismatch
+		balanced   bool
+	}{
+		{
+			name:     "every currency sums to zero",
+			totals:   balancedTotals,
+			balanced: true,
+		},
+		{
+			name: "one currency off",
+			totals: []*CurrencyTotal{
+				{Currency: "THB", Total: money.Zero("THB")},
+				{Currency: "USD", Total: money.FromMinor(100, "USD")},
+			},
+		},
+		{
+			name:       "unbalanced transaction",
+			totals:     balancedTotals,
+			unbalanced: []*UnbalancedTransaction{{TransactionID: 7, Currency: "THB", Total: money.FromMinor(1, "THB")}},
+		},
+		{
+			name:       "balance does not match its entries",
+			totals:     balancedTotals,
+			mismatches: []*BalanceMismatch{{AccountID: 3, Currency: "THB", Balance: money.FromMinor(100, "THB"), LedgerBalance: money.Zero("THB")}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(ledgerRepositoryMock)
+			repo.On("Totals", mock.Anything).Return(tc.totals, nil)
+			repo.On("UnbalancedTransactions", mock.Anything).Return(tc.unbalanced, nil)
+			repo.On("BalanceMismatches", mock.Anything).Return(tc.mismatches, nil)
+
+			uc := NewLedgerUsecase(repo, nil)
+			result, err := uc.Verify(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.balanced, result.Balanced)
+			assert.Equal(t, tc.totals, result.Totals)
+			repo.AssertExpectations(t)
+		})
+	}
+}
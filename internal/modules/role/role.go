@@ -0,0 +1,87 @@
+package role
+
+import (
+	"slices"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type Permission string
+
+const (
+	PermAccountsApprove     Permission = "accounts:approve"
+	PermAccountsReadAll     Permission = "accounts:read_all"
+	PermHoldsManage         Permission = "holds:manage"
+	PermTransactionsReadAll Permission = "transactions:read_all"
+	PermTransactionsReverse Permission = "transactions:reverse"
+	PermTransactionsDeposit Permission = "transactions:deposit"
+	PermChannelsManage      Permission = "channels:manage"
+	PermLedgerVerify        Permission = "ledger:verify"
+	PermUsersReadPII        Permission = "users:read_pii"
+	PermUsersManage         Permission = "users:manage"
+	PermRolesManage         Permission = "roles:manage"
+	PermSystemMetrics       Permission = "system:metrics"
+	PermClientsManage       Permission = "clients:manage"
+)
+
+// AllPermissions lists every permission the application checks. The
+// permissions table is seeded with the same codes.
+var AllPermissions = []Permission{
+	PermAccountsApprove,
+	PermAccountsReadAll,
+	PermHoldsManage,
+	PermTransactionsReadAll,
+	PermTransactionsReverse,
+	PermTransactionsDeposit,
+	PermChannelsManage,
+	PermLedgerVerify,
+	PermUsersReadPII,
+	PermUsersManage,
+	PermRolesManage,
+	PermSystemMetrics,
+	PermClientsManage,
+}
+
+func (p Permission) Valid() bool {
+	return slices.Contains(AllPermissions, p)
+}
+
+// RoleAdmin must keep PermRolesManage, otherwise nobody could edit roles
+// anymore.
+const RoleAdmin = "ADMIN"
+
+type Role struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   *time.Time   `json:"updated_at"`
+}
+
+// HasAll reports whether perms contains every permission of want.
+func HasAll(perms []Permission, want ...Permission) bool {
+	for _, p := range want {
+		if !slices.Contains(perms, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Granted reports whether the current user has every permission of perms,
+// as loaded by the Authorized middleware.
+func Granted(ctx *gin.Context, perms ...Permission) bool {
+	val, ok := ctx.Get(utils.ContextKeyPermissions)
+	if !ok {
+		return false
+	}
+
+	granted, ok := val.([]Permission)
+	if !ok {
+		return false
+	}
+
+	return HasAll(granted, perms...)
+}
@@ -0,0 +1,11 @@
+package role
+
+type RoleRequest struct {
+	Description string       `json:"description" validate:"max=255"`
+	Permissions []Permission `json:"permissions" validate:"required"`
+}
+
+type PermissionsResponse struct {
+	Role        string       `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
@@ -0,0 +1,104 @@
+package role
+
+import (
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type roleHandler struct {
+	uc       RoleUsecase
+	validate *validator.Validate
+}
+
+func NewRoleHandler(uc RoleUsecase) *roleHandler {
+	return &roleHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
+}
+
+func (h *roleHandler) ListRoles(ctx *gin.Context) {
+	roles, err := h.uc.ListRoles(ctx)
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, roles)
+}
+
+func (h *roleHandler) GetRole(ctx *gin.Context) {
+	result, err := h.uc.GetRole(ctx, ctx.Param("name"))
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+func (h *roleHandler) SaveRole(ctx *gin.Context) {
+	req := new(RoleRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	result, err := h.uc.SaveRole(ctx, ctx.Param("name"), req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+// MyPermissions lists the effective permissions of the caller.
+func (h *roleHandler) MyPermissions(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	perms, err := h.uc.Permissions(ctx, string(u.Role))
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	if perms == nil {
+		perms = []Permission{}
+	}
+
+	response.Success(ctx, &PermissionsResponse{
+		Role:        string(u.Role),
+		Permissions: perms,
+	})
+}
+
+func (h *roleHandler) handleError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrRoleNotFound):
+		response.ErrNotFound(ctx, err)
+	case errors.Is(err, errs.ErrRoleNameInvalid),
+		errors.Is(err, errs.ErrPermissionUnknown):
+		response.ErrBadRequest(ctx, err)
+	case errors.Is(err, errs.ErrRoleAdminLockout):
+		response.ErrConflict(ctx, err)
+	default:
+		response.ErrInternalServer(ctx, err)
+	}
+}
@@ -0,0 +1,154 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/lib/pq"
+)
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]*Role, error)
+	FindByName(ctx context.Context, name string) (*Role, error)
+	Save(ctx context.Context, r *Role) (*Role, error)
+	Permissions(ctx context.Context, name string) ([]Permission, error)
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+const roleColumns = `
+	r.name, r.description, r.created_at, r.updated_at,
+	COALESCE(ARRAY(
+		SELECT rp.permission FROM role_permissions rp
+		WHERE rp.role = r.name ORDER BY rp.permission
+	), '{}')
+`
+
+func (r *roleRepository) List(ctx context.Context) ([]*Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles r ORDER BY r.name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles r WHERE r.name = $1`
+
+	role, err := scanRole(r.db.QueryRowContext(ctx, query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// Save creates the role or updates its description, and replaces its
+// permissions with r.Permissions in the same statement.
+func (r *roleRepository) Save(ctx context.Context, role *Role) (*Role, error) {
+	query := `
+		WITH saved AS (
+			INSERT INTO roles (name, description) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE
+			SET description = EXCLUDED.description, updated_at = NOW()
+		), removed AS (
+			DELETE FROM role_permissions
+			WHERE role = $1 AND permission <> ALL($3::TEXT[])
+		)
+		INSERT INTO role_permissions (role, permission)
+		SELECT $1, unnest($3::TEXT[])
+		ON CONFLICT DO NOTHING
+	`
+	perms := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		perms[i] = string(p)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, role.Name, role.Description, pq.Array(perms)); err != nil {
+		return nil, err
+	}
+
+	return r.FindByName(ctx, role.Name)
+}
+
+func (r *roleRepository) Permissions(ctx context.Context, name string) ([]Permission, error) {
+	query := `SELECT permission FROM role_permissions WHERE role = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []Permission
+	for rows.Next() {
+		var p Permission
+		if err = rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return perms, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRole(row rowScanner) (*Role, error) {
+	var (
+		role  Role
+		perms []string
+	)
+
+	err := row.Scan(
+		&role.Name,
+		&role.Description,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+		pq.Array(&perms),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = make([]Permission, len(perms))
+	for i, p := range perms {
+		role.Permissions[i] = Permission(p)
+	}
+
+	return &role, nil
+}
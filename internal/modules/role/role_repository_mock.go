@@ -0,0 +1,49 @@
+package role
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type roleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *roleRepositoryMock) List(ctx context.Context) ([]*Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*Role), args.Error(1)
+}
+
+func (m *roleRepositoryMock) FindByName(ctx context.Context, name string) (*Role, error) {
+	args := m.Called(ctx, name)
+
+	res, ok := args.Get(0).(*Role)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *roleRepositoryMock) Save(ctx context.Context, r *Role) (*Role, error) {
+	args := m.Called(ctx, r)
+
+	res, ok := args.Get(0).(*Role)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *roleRepositoryMock) Permissions(ctx context.Context, name string) ([]Permission, error) {
+	args := m.Called(ctx, name)
+
+	res, ok := args.Get(0).([]Permission)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
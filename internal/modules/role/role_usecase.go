@@ -0,0 +1,126 @@
+package role
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+)
+
+const (
+	queryTimeout = time.Second * 5
+
+	// permissionCacheTTL bounds how long another instance keeps serving
+	// the permissions of a role after it was edited.
+	permissionCacheTTL = time.Second * 30
+)
+
+var roleNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,31}$`)
+
+type RoleUsecase interface {
+	ListRoles(ctx context.Context) ([]*Role, error)
+	GetRole(ctx context.Context, name string) (*Role, error)
+	SaveRole(ctx context.Context, name string, req *RoleRequest) (*Role, error)
+	Permissions(ctx context.Context, role string) ([]Permission, error)
+}
+
+type cachedPermissions struct {
+	perms    []Permission
+	loadedAt time.Time
+}
+
+type roleUsecase struct {
+	repo RoleRepository
+
+	mu    sync.Mutex
+	cache map[string]cachedPermissions
+}
+
+func NewRoleUsecase(repo RoleRepository) RoleUsecase {
+	return &roleUsecase{
+		repo:  repo,
+		cache: make(map[string]cachedPermissions),
+	}
+}
+
+func (uc *roleUsecase) ListRoles(ctx context.Context) ([]*Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx)
+}
+
+func (uc *roleUsecase) GetRole(ctx context.Context, name string) (*Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.FindByName(ctx, name)
+}
+
+// SaveRole creates the role or replaces its description and permissions.
+func (uc *roleUsecase) SaveRole(ctx context.Context, name string, req *RoleRequest) (*Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if !roleNamePattern.MatchString(name) {
+		return nil, errs.ErrRoleNameInvalid
+	}
+
+	perms := slices.Clone(req.Permissions)
+	slices.Sort(perms)
+	perms = slices.Compact(perms)
+
+	for _, p := range perms {
+		if !p.Valid() {
+			return nil, errs.ErrPermissionUnknown
+		}
+	}
+
+	if name == RoleAdmin && !slices.Contains(perms, PermRolesManage) {
+		return nil, errs.ErrRoleAdminLockout
+	}
+
+	saved, err := uc.repo.Save(ctx, &Role{
+		Name:        name,
+		Description: req.Description,
+		Permissions: perms,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.mu.Lock()
+	delete(uc.cache, name)
+	uc.mu.Unlock()
+
+	return saved, nil
+}
+
+// Permissions returns the permissions granted to role. Unknown roles have
+// none.
+func (uc *roleUsecase) Permissions(ctx context.Context, role string) ([]Permission, error) {
+	uc.mu.Lock()
+	cached, ok := uc.cache[role]
+	uc.mu.Unlock()
+
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL {
+		return cached.perms, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	perms, err := uc.repo.Permissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.mu.Lock()
+	uc.cache[role] = cachedPermissions{perms: perms, loadedAt: time.Now()}
+	uc.mu.Unlock()
+
+	return perms, nil
+}
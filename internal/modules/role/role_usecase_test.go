@@ -0,0 +1,72 @@
+package role
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSaveRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		role        string
+		perms       []Permission
+		expectedErr error
+	}{
+		{name: "new role", role: "AUDITOR", perms: []Permission{PermLedgerVerify, PermTransactionsReadAll}},
+		{name: "no permissions", role: "GUEST", perms: []Permission{}},
+		{name: "lowercase name", role: "auditor", perms: []Permission{PermLedgerVerify}, expectedErr: errs.ErrRoleNameInvalid},
+		{name: "unknown permission", role: "AUDITOR", perms: []Permission{"ledger:delete"}, expectedErr: errs.ErrPermissionUnknown},
+		{name: "admin keeps roles:manage", role: RoleAdmin, perms: []Permission{PermLedgerVerify}, expectedErr: errs.ErrRoleAdminLockout},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(roleRepositoryMock)
+			if tc.expectedErr == nil {
+				repo.On("Save", mock.Anything, mock.Anything).Return(&Role{Name: tc.role, Permissions: tc.perms}, nil)
+			}
+
+			_, err := NewRoleUsecase(repo).SaveRole(context.Background(), tc.role, &RoleRequest{Permissions: tc.perms})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPermissionsCache(t *testing.T) {
+	repo := new(roleRepositoryMock)
+	repo.On("Permissions", mock.Anything, "STAFF").Return([]Permission{PermAccountsApprove, PermLedgerVerify}, nil).Once()
+	repo.On("Save", mock.Anything, mock.Anything).Return(&Role{Name: "STAFF"}, nil)
+	repo.On("Permissions", mock.Anything, "STAFF").Return([]Permission{PermAccountsApprove}, nil).Once()
+
+	uc := NewRoleUsecase(repo)
+	ctx := context.Background()
+
+	perms, err := uc.Permissions(ctx, "STAFF")
+	assert.NoError(t, err)
+	assert.True(t, HasAll(perms, PermAccountsApprove, PermLedgerVerify))
+
+	// Served from the cache
+	perms, err = uc.Permissions(ctx, "STAFF")
+	assert.NoError(t, err)
+	assert.True(t, HasAll(perms, PermLedgerVerify))
+
+	// Editing the role drops it from the cache
+	_, err = uc.SaveRole(ctx, "STAFF", &RoleRequest{Permissions: []Permission{PermAccountsApprove}})
+	assert.NoError(t, err)
+
+	perms, err = uc.Permissions(ctx, "STAFF")
+	assert.NoError(t, err)
+	assert.False(t, HasAll(perms, PermLedgerVerify))
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,113 @@
+package standingorder
+
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+type StandingOrder struct {
+	ID           int64       `json:"id"`
+	UserID       int64       `json:"user_id"`
+	FromAccount  int64       `json:"from_account"`
+	ToAccount    int64       `json:"to_account"`
+	Amount       money.Money `json:"amount"`
+	Currency     string      `json:"currency"`
+	Frequency    frequency   `json:"frequency"`
+	StartAt      time.Time   `json:"start_at"`
+	EndAt        *time.Time  `json:"end_at"`
+	MaxRuns      *int        `json:"max_runs"`
+	ExecutedRuns int         `json:"executed_runs"`
+	FailureCount int         `json:"failure_count"`
+	NextRunAt    *time.Time  `json:"next_run_at"`
+	Status       orderStatus `json:"status"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    *time.Time  `json:"updated_at"`
+}
+
+type Execution struct {
+	ID              int64           `json:"id"`
+	StandingOrderID int64           `json:"standing_order_id"`
+	TransactionID   *int64          `json:"transaction_id"`
+	Status          executionStatus `json:"status"`
+	Error           *string         `json:"error"`
+	ScheduledFor    time.Time       `json:"scheduled_for"`
+	ExecutedAt      time.Time       `json:"executed_at"`
+}
+
+// occurrence returns the n-th run time counted from StartAt (n = 0 is the
+// first run). Monthly orders keep the day of StartAt and fall back to the
+// last day of shorter months.
+func (o *StandingOrder) occurrence(n int) time.Time {
+	switch o.Frequency {
+	case FrequencyDaily:
+		return o.StartAt.AddDate(0, 0, n)
+	case FrequencyWeekly:
+		return o.StartAt.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		y, m, d := o.StartAt.Date()
+		first := time.Date(y, m+time.Month(n), 1, o.StartAt.Hour(), o.StartAt.Minute(), o.StartAt.Second(), 0, o.StartAt.Location())
+		last := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(d, last)-1)
+	}
+	return o.StartAt
+}
+
+// advance schedules the first run after the given time, or completes the
+// order when it has no runs left. Runs before that time are skipped.
+func (o *StandingOrder) advance(after time.Time) {
+	if o.Frequency == FrequencyOnce || (o.MaxRuns != nil && o.ExecutedRuns >= *o.MaxRuns) {
+		o.complete()
+		return
+	}
+
+	// Runs are never moved earlier, so the next one is at least the
+	// ExecutedRuns-th.
+	n := o.ExecutedRuns
+	for !o.occurrence(n).After(after) {
+		n++
+	}
+
+	next := o.occurrence(n)
+	if o.EndAt != nil && next.After(*o.EndAt) {
+		o.complete()
+		return
+	}
+
+	o.NextRunAt = &next
+}
+
+// resume reactivates a paused order at its first run after now, so runs
+// missed while it was paused are not made up all at once. A one-off order
+// that has not run yet runs now.
+func (o *StandingOrder) resume(now time.Time) {
+	o.Status = StatusActive
+	o.FailureCount = 0
+
+	if o.Frequency == FrequencyOnce && o.ExecutedRuns == 0 {
+		o.NextRunAt = &now
+		return
+	}
+
+	o.advance(now)
+}
+
+func (o *StandingOrder) complete() {
+	o.Status = StatusCompleted
+	o.NextRunAt = nil
+}
+
+// fail schedules a retry of the same run after a failed transfer, or pauses
+// the order when the error is not retryable or it keeps failing.
+func (o *StandingOrder) fail(err error, now time.Time) {
+	o.FailureCount++
+
+	if !retryable(err) || o.FailureCount >= maxFailures {
+		o.Status = StatusPaused
+		o.NextRunAt = nil
+		return
+	}
+
+	next := now.Add(retryDelay)
+	o.NextRunAt = &next
+}
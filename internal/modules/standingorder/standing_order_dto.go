@@ -0,0 +1,48 @@
+package standingorder
+
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+type frequency string
+
+const (
+	FrequencyOnce    frequency = "ONCE"
+	FrequencyDaily   frequency = "DAILY"
+	FrequencyWeekly  frequency = "WEEKLY"
+	FrequencyMonthly frequency = "MONTHLY"
+)
+
+type orderStatus string
+
+const (
+	StatusActive    orderStatus = "ACTIVE"
+	StatusPaused    orderStatus = "PAUSED"
+	StatusCancelled orderStatus = "CANCELLED"
+	StatusCompleted orderStatus = "COMPLETED"
+)
+
+type executionStatus string
+
+const (
+	ExecutionSucceeded executionStatus = "SUCCEEDED"
+	ExecutionFailed    executionStatus = "FAILED"
+)
+
+// StandingOrderRequest schedules a transfer. Recurring orders run until
+// EndAt or MaxRuns, whichever comes first; without either they run until
+// cancelled.
+type StandingOrderRequest struct {
+	FromAccount int64         `json:"from_account" validate:"required"`
+	ToAccount   int64         `json:"to_account" validate:"required,nefield=FromAccount"`
+	Amount      money.Decimal `json:"amount" validate:"required"`
+	Frequency   frequency     `json:"frequency" validate:"required,oneof=ONCE DAILY WEEKLY MONTHLY"`
+	StartAt     time.Time     `json:"start_at" validate:"required"`
+	EndAt       *time.Time    `json:"end_at"`
+	MaxRuns     *int          `json:"max_runs" validate:"omitempty,min=1"`
+
+	// StepUp is set when the request carried a verified one-time code.
+	StepUp bool `json:"-"`
+}
@@ -0,0 +1,160 @@
+package standingorder
+
+import (
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type standingOrderHandler struct {
+	uc       StandingOrderUsecase
+	validate *validator.Validate
+}
+
+func NewStandingOrderHandler(uc StandingOrderUsecase) *standingOrderHandler {
+	return &standingOrderHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
+}
+
+func (h *standingOrderHandler) CreateStandingOrder(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	req := new(StandingOrderRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req.StepUp = ctx.GetBool(utils.ContextKeyStepUp)
+
+	// Standing Order Usecase
+	order, err := h.uc.Create(ctx.Request.Context(), u.ID, req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Created(ctx, order)
+}
+
+func (h *standingOrderHandler) ListStandingOrders(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	orders, err := h.uc.List(ctx.Request.Context(), u.ID)
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, orders)
+}
+
+func (h *standingOrderHandler) ListExecutions(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	executions, err := h.uc.Executions(ctx.Request.Context(), id, u.ID)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, executions)
+}
+
+func (h *standingOrderHandler) CancelStandingOrder(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	order, err := h.uc.Cancel(ctx.Request.Context(), id, u.ID)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, order)
+}
+
+func (h *standingOrderHandler) ResumeStandingOrder(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	order, err := h.uc.Resume(ctx.Request.Context(), id, u.ID, ctx.GetBool(utils.ContextKeyStepUp))
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, order)
+}
+
+func (h *standingOrderHandler) handleError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrStepUpRequired):
+		response.Forbidden(ctx, err)
+	case errors.Is(err, errs.ErrStandingOrderNotFound),
+		errors.Is(err, errs.ErrAccountNotFound):
+		response.ErrNotFound(ctx, err)
+	case errors.Is(err, errs.ErrStandingOrderStatus),
+		errors.Is(err, errs.ErrAccountCannotSend),
+		errors.Is(err, errs.ErrAccountCannotReceive):
+		response.ErrConflict(ctx, err)
+	case errors.Is(err, errs.ErrStandingOrderSchedule),
+		errors.Is(err, errs.ErrStandingOrderNotOwner),
+		errors.Is(err, errs.ErrCurrencyMismatch),
+		errors.Is(err, errs.ErrCurrencyNotSupported),
+		errors.Is(err, errs.ErrAmountGreaterThanZero):
+		response.ErrBadRequest(ctx, err)
+	default:
+		response.ErrInternalServer(ctx, err)
+	}
+}
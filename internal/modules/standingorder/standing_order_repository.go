@@ -0,0 +1,283 @@
+package standingorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+type StandingOrderRepository interface {
+	Create(ctx context.Context, o *StandingOrder) (*StandingOrder, error)
+	FindByID(ctx context.Context, id int64) (*StandingOrder, error)
+	ListByUser(ctx context.Context, userID int64) ([]*StandingOrder, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*StandingOrder, error)
+	Claim(ctx context.Context, id int64, nextRunAt, leaseUntil time.Time) (bool, error)
+	Update(ctx context.Context, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error
+	UpdateWithTx(ctx context.Context, tx *sql.Tx, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error
+	CreateExecutionWithTx(ctx context.Context, tx *sql.Tx, e *Execution) error
+	ListExecutions(ctx context.Context, orderID int64) ([]*Execution, error)
+}
+
+type standingOrderRepository struct {
+	db *sql.DB
+}
+
+// execer is a *sql.DB or a *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func NewStandingOrderRepository(db *sql.DB) StandingOrderRepository {
+	return &standingOrderRepository{db: db}
+}
+
+const standingOrderColumns = `
+	id, user_id, from_account, to_account, amount, currency, frequency,
+	start_at, end_at, max_runs, executed_runs, failure_count, next_run_at,
+	status, created_at, updated_at
+`
+
+func scanStandingOrder(row interface{ Scan(dest ...any) error }) (*StandingOrder, error) {
+	o := new(StandingOrder)
+	var amount int64
+
+	err := row.Scan(
+		&o.ID,
+		&o.UserID,
+		&o.FromAccount,
+		&o.ToAccount,
+		&amount,
+		&o.Currency,
+		&o.Frequency,
+		&o.StartAt,
+		&o.EndAt,
+		&o.MaxRuns,
+		&o.ExecutedRuns,
+		&o.FailureCount,
+		&o.NextRunAt,
+		&o.Status,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	o.Amount = money.FromMinor(amount, o.Currency)
+
+	return o, nil
+}
+
+func (r *standingOrderRepository) Create(ctx context.Context, o *StandingOrder) (*StandingOrder, error) {
+	query := `
+		INSERT INTO standing_orders (
+			user_id, from_account, to_account, amount, currency, frequency,
+			start_at, end_at, max_runs, next_run_at, status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		o.UserID,
+		o.FromAccount,
+		o.ToAccount,
+		o.Amount.Minor(),
+		o.Currency,
+		o.Frequency,
+		o.StartAt,
+		o.EndAt,
+		o.MaxRuns,
+		o.NextRunAt,
+		o.Status,
+	).Scan(
+		&o.ID,
+		&o.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (r *standingOrderRepository) FindByID(ctx context.Context, id int64) (*StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE id = $1 LIMIT 1`
+
+	o, err := scanStandingOrder(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrStandingOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (r *standingOrderRepository) ListByUser(ctx context.Context, userID int64) ([]*StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE user_id = $1 ORDER BY id`
+	return r.list(ctx, query, userID)
+}
+
+func (r *standingOrderRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + ` FROM standing_orders
+		WHERE status = 'ACTIVE' AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`
+	return r.list(ctx, query, now, limit)
+}
+
+func (r *standingOrderRepository) list(ctx context.Context, query string, args ...any) ([]*StandingOrder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*StandingOrder
+
+	for rows.Next() {
+		o, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Claim moves a due order's next run to leaseUntil so no other worker picks
+// it up while it runs. It reports false when someone else got it first.
+func (r *standingOrderRepository) Claim(ctx context.Context, id int64, nextRunAt, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE standing_orders SET next_run_at = $1
+		WHERE id = $2 AND next_run_at = $3 AND status = 'ACTIVE'
+	`
+	res, err := r.db.ExecContext(ctx, query, leaseUntil, id, nextRunAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Update saves the schedule and status of o, unless another request or
+// worker changed them since they were read as status and nextRunAt. That
+// is reported as ErrStandingOrderStatus, so a run finishing after a Cancel
+// cannot bring the order back.
+func (r *standingOrderRepository) Update(ctx context.Context, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	return update(ctx, r.db, o, status, nextRunAt)
+}
+
+func (r *standingOrderRepository) UpdateWithTx(ctx context.Context, tx *sql.Tx, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	return update(ctx, tx, o, status, nextRunAt)
+}
+
+func update(ctx context.Context, db execer, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	query := `
+		UPDATE standing_orders
+		SET executed_runs = $1, failure_count = $2, next_run_at = $3, status = $4, updated_at = $5
+		WHERE id = $6 AND status = $7 AND next_run_at IS NOT DISTINCT FROM $8
+	`
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		o.ExecutedRuns,
+		o.FailureCount,
+		o.NextRunAt,
+		o.Status,
+		o.UpdatedAt,
+		o.ID,
+		status,
+		nextRunAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrStandingOrderStatus
+	}
+
+	return nil
+}
+
+func (r *standingOrderRepository) CreateExecutionWithTx(ctx context.Context, tx *sql.Tx, e *Execution) error {
+	query := `
+		INSERT INTO standing_order_executions (standing_order_id, transaction_id, status, error, scheduled_for)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, executed_at
+	`
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		e.StandingOrderID,
+		e.TransactionID,
+		e.Status,
+		e.Error,
+		e.ScheduledFor,
+	).Scan(
+		&e.ID,
+		&e.ExecutedAt,
+	)
+}
+
+func (r *standingOrderRepository) ListExecutions(ctx context.Context, orderID int64) ([]*Execution, error) {
+	query := `
+		SELECT id, standing_order_id, transaction_id, status, error, scheduled_for, executed_at
+		FROM standing_order_executions WHERE standing_order_id = $1
+		ORDER BY executed_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []*Execution
+
+	for rows.Next() {
+		e := new(Execution)
+		err = rows.Scan(
+			&e.ID,
+			&e.StandingOrderID,
+			&e.TransactionID,
+			&e.Status,
+			&e.Error,
+			&e.ScheduledFor,
+			&e.ExecutedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
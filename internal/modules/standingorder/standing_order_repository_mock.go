@@ -0,0 +1,76 @@
+package standingorder
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type standingOrderRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *standingOrderRepositoryMock) Create(ctx context.Context, o *StandingOrder) (*StandingOrder, error) {
+	args := m.Called(ctx, o)
+
+	res, ok := args.Get(0).(*StandingOrder)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *standingOrderRepositoryMock) FindByID(ctx context.Context, id int64) (*StandingOrder, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*StandingOrder)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *standingOrderRepositoryMock) ListByUser(ctx context.Context, userID int64) ([]*StandingOrder, error) {
+	args := m.Called(ctx, userID)
+
+	res, _ := args.Get(0).([]*StandingOrder)
+	return res, args.Error(1)
+}
+
+func (m *standingOrderRepositoryMock) ListDue(ctx context.Context, now time.Time, limit int) ([]*StandingOrder, error) {
+	args := m.Called(ctx, now, limit)
+
+	res, _ := args.Get(0).([]*StandingOrder)
+	return res, args.Error(1)
+}
+
+func (m *standingOrderRepositoryMock) Claim(ctx context.Context, id int64, nextRunAt, leaseUntil time.Time) (bool, error) {
+	args := m.Called(ctx, id, nextRunAt, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *standingOrderRepositoryMock) Update(ctx context.Context, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	args := m.Called(ctx, o, status, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *standingOrderRepositoryMock) UpdateWithTx(ctx context.Context, tx *sql.Tx, o *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	args := m.Called(ctx, tx, o, status, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *standingOrderRepositoryMock) CreateExecutionWithTx(ctx context.Context, tx *sql.Tx, e *Execution) error {
+	args := m.Called(ctx, tx, e)
+	return args.Error(0)
+}
+
+func (m *standingOrderRepositoryMock) ListExecutions(ctx context.Context, orderID int64) ([]*Execution, error) {
+	args := m.Called(ctx, orderID)
+
+	res, _ := args.Get(0).([]*Execution)
+	return res, args.Error(1)
+}
@@ -0,0 +1,166 @@
+package standingorder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrence(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		frequency frequency
+		n         int
+		expected  time.Time
+	}{
+		{"daily", FrequencyDaily, 3, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)},
+		{"weekly", FrequencyWeekly, 2, time.Date(2025, 2, 14, 9, 0, 0, 0, time.UTC)},
+		{"monthly clamps to short month", FrequencyMonthly, 1, time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC)},
+		{"monthly keeps start day", FrequencyMonthly, 2, time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{"monthly across year", FrequencyMonthly, 13, time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &StandingOrder{Frequency: tc.frequency, StartAt: start}
+			assert.Equal(t, tc.expected, o.occurrence(tc.n))
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	endAt := start.AddDate(0, 0, 2)
+	maxRuns := 2
+
+	testCases := []struct {
+		name     string
+		order    *StandingOrder
+		status   orderStatus
+		expected *time.Time
+	}{
+		{
+			name:   "once completes",
+			order:  &StandingOrder{Frequency: FrequencyOnce, ExecutedRuns: 1},
+			status: StatusCompleted,
+		},
+		{
+			name:   "max runs completes",
+			order:  &StandingOrder{Frequency: FrequencyDaily, MaxRuns: &maxRuns, ExecutedRuns: 2},
+			status: StatusCompleted,
+		},
+		{
+			name:   "past end date completes",
+			order:  &StandingOrder{Frequency: FrequencyDaily, EndAt: &endAt, ExecutedRuns: 3},
+			status: StatusCompleted,
+		},
+		{
+			name:     "schedules next run",
+			order:    &StandingOrder{Frequency: FrequencyDaily, EndAt: &endAt, ExecutedRuns: 2},
+			status:   StatusActive,
+			expected: &endAt,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.order.StartAt = start
+			tc.order.Status = StatusActive
+
+			tc.order.advance(tc.order.occurrence(tc.order.ExecutedRuns - 1))
+
+			assert.Equal(t, tc.status, tc.order.Status)
+			assert.Equal(t, tc.expected, tc.order.NextRunAt)
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	endAt := time.Date(2025, 5, 31, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		order    *StandingOrder
+		status   orderStatus
+		expected *time.Time
+	}{
+		{
+			name:     "daily skips missed runs",
+			order:    &StandingOrder{Frequency: FrequencyDaily, ExecutedRuns: 3},
+			status:   StatusActive,
+			expected: ptr(time.Date(2025, 6, 11, 9, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "monthly skips missed runs",
+			order:    &StandingOrder{Frequency: FrequencyMonthly, ExecutedRuns: 1},
+			status:   StatusActive,
+			expected: ptr(time.Date(2025, 6, 30, 9, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:   "ended while paused completes",
+			order:  &StandingOrder{Frequency: FrequencyMonthly, EndAt: &endAt, ExecutedRuns: 1},
+			status: StatusCompleted,
+		},
+		{
+			name:     "one-off runs now",
+			order:    &StandingOrder{Frequency: FrequencyOnce},
+			status:   StatusActive,
+			expected: &now,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.order.StartAt = start
+			tc.order.Status = StatusPaused
+			tc.order.FailureCount = maxFailures
+
+			tc.order.resume(now)
+
+			assert.Equal(t, tc.status, tc.order.Status)
+			assert.Equal(t, tc.expected, tc.order.NextRunAt)
+			assert.Zero(t, tc.order.FailureCount)
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestFail(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	retryAt := now.Add(retryDelay)
+
+	testCases := []struct {
+		name     string
+		failures int
+		err      error
+		status   orderStatus
+		expected *time.Time
+	}{
+		{"insufficient funds retries", 0, errs.ErrAmountGreaterAccBalance, StatusActive, &retryAt},
+		{"transient error retries", 0, errors.New("connection reset"), StatusActive, &retryAt},
+		{"pauses after max failures", maxFailures - 1, errs.ErrAmountGreaterAccBalance, StatusPaused, nil},
+		{"domain error pauses", 0, errs.ErrAccountNotFound, StatusPaused, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &StandingOrder{Status: StatusActive, FailureCount: tc.failures}
+
+			o.fail(tc.err, now)
+
+			assert.Equal(t, tc.status, o.Status)
+			assert.Equal(t, tc.expected, o.NextRunAt)
+			assert.Equal(t, tc.failures+1, o.FailureCount)
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package standingorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/db"
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/transaction"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+const (
+	queryTimeout = time.Second * 5
+
+	// dueBatchSize is how many due orders one worker tick picks up.
+	dueBatchSize = 100
+	// leaseDuration keeps a claimed order away from other workers while its
+	// transfer runs.
+	leaseDuration = time.Minute * 5
+	// retryDelay and maxFailures control retries after a failed run; the
+	// order is paused once it has failed maxFailures times in a row.
+	retryDelay  = time.Hour
+	maxFailures = 3
+)
+
+type StandingOrderUsecase interface {
+	Create(ctx context.Context, userID int64, req *StandingOrderRequest) (*StandingOrder, error)
+	List(ctx context.Context, userID int64) ([]*StandingOrder, error)
+	Executions(ctx context.Context, id, userID int64) ([]*Execution, error)
+	Cancel(ctx context.Context, id, userID int64) (*StandingOrder, error)
+	Resume(ctx context.Context, id, userID int64, stepUp bool) (*StandingOrder, error)
+	ExecuteDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type standingOrderUsecase struct {
+	repo        StandingOrderRepository
+	accUsecase  account.AccountUsecase
+	tranUsecase transaction.TransactionUsecase
+	txManager   db.TxManager
+}
+
+func NewStandingOrderUsecase(repo StandingOrderRepository, accUsecase account.AccountUsecase, tranUsecase transaction.TransactionUsecase, txManager db.TxManager) StandingOrderUsecase {
+	return &standingOrderUsecase{
+		repo:        repo,
+		accUsecase:  accUsecase,
+		tranUsecase: tranUsecase,
+		txManager:   txManager,
+	}
+}
+
+func (uc *standingOrderUsecase) Create(ctx context.Context, userID int64, req *StandingOrderRequest) (*StandingOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if !req.StartAt.After(time.Now()) || (req.EndAt != nil && req.EndAt.Before(req.StartAt)) {
+		return nil, errs.ErrStandingOrderSchedule
+	}
+
+	from, err := uc.accUsecase.GetAccountByID(ctx, req.FromAccount)
+	if err != nil {
+		return nil, errs.ErrAccountNotFound
+	}
+
+	if from.UserID != userID {
+		return nil, errs.ErrStandingOrderNotOwner
+	}
+
+	if err = from.CanSend(); err != nil {
+		return nil, err
+	}
+
+	to, err := uc.accUsecase.GetAccountByID(ctx, req.ToAccount)
+	if err != nil {
+		return nil, errs.ErrAccountNotFound
+	}
+
+	if err = to.CanReceive(); err != nil {
+		return nil, err
+	}
+
+	if from.Currency != to.Currency {
+		return nil, errs.ErrCurrencyMismatch
+	}
+
+	amount, err := req.Amount.Parse(from.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if !amount.IsPositive() {
+		return nil, errs.ErrAmountGreaterThanZero
+	}
+
+	// Runs are not asked for a one-time code, so large orders need one now.
+	if err = uc.tranUsecase.CheckStepUp(amount, req.StepUp); err != nil {
+		return nil, err
+	}
+
+	startAt := req.StartAt
+	order := &StandingOrder{
+		UserID:      userID,
+		FromAccount: from.ID,
+		ToAccount:   to.ID,
+		Amount:      amount,
+		Currency:    from.Currency,
+		Frequency:   req.Frequency,
+		StartAt:     startAt,
+		EndAt:       req.EndAt,
+		MaxRuns:     req.MaxRuns,
+		NextRunAt:   &startAt,
+		Status:      StatusActive,
+	}
+
+	return uc.repo.Create(ctx, order)
+}
+
+func (uc *standingOrderUsecase) List(ctx context.Context, userID int64) ([]*StandingOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	orders, err := uc.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if orders == nil {
+		orders = []*StandingOrder{}
+	}
+
+	return orders, nil
+}
+
+func (uc *standingOrderUsecase) Executions(ctx context.Context, id, userID int64) ([]*Execution, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if _, err := uc.findOwned(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	executions, err := uc.repo.ListExecutions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if executions == nil {
+		executions = []*Execution{}
+	}
+
+	return executions, nil
+}
+
+func (uc *standingOrderUsecase) Cancel(ctx context.Context, id, userID int64) (*StandingOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	order, err := uc.findOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != StatusActive && order.Status != StatusPaused {
+		return nil, errs.ErrStandingOrderStatus
+	}
+
+	status, nextRunAt := order.Status, order.NextRunAt
+	order.Status = StatusCancelled
+	order.NextRunAt = nil
+
+	if err = uc.update(ctx, order, status, nextRunAt); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Resume reactivates a paused order from its first run after now; runs
+// missed while paused are skipped. Like Create, it needs a verified
+// one-time code for an amount above the step-up threshold.
+func (uc *standingOrderUsecase) Resume(ctx context.Context, id, userID int64, stepUp bool) (*StandingOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	order, err := uc.findOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != StatusPaused {
+		return nil, errs.ErrStandingOrderStatus
+	}
+
+	if err = uc.tranUsecase.CheckStepUp(order.Amount, stepUp); err != nil {
+		return nil, err
+	}
+
+	status, nextRunAt := order.Status, order.NextRunAt
+	order.resume(time.Now())
+
+	if err = uc.update(ctx, order, status, nextRunAt); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ExecuteDue runs every active order whose next run is at or before now and
+// returns how many transfers succeeded. Each order is claimed first, so
+// several server processes can run the worker side by side.
+func (uc *standingOrderUsecase) ExecuteDue(ctx context.Context, now time.Time) (int, error) {
+	listCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+	orders, err := uc.repo.ListDue(listCtx, now, dueBatchSize)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("list due standing orders failed: %w", err)
+	}
+
+	succeeded := 0
+
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+
+		ok, err := uc.execute(ctx, order, now)
+		if err != nil {
+			log.Printf("standing order %d: %v", order.ID, err)
+			continue
+		}
+		if ok {
+			succeeded++
+		}
+	}
+
+	return succeeded, nil
+}
+
+// execute claims a due order, runs its transfer and records the outcome. It
+// reports whether the transfer went through. A successful run is recorded
+// and the order moved on in the transaction of the transfer, so a crash
+// cannot leave the money sent and the run still due.
+func (uc *standingOrderUsecase) execute(ctx context.Context, order *StandingOrder, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout*2)
+	defer cancel()
+
+	scheduledFor := *order.NextRunAt
+	leaseUntil := now.Add(leaseDuration)
+
+	claimed, err := uc.repo.Claim(ctx, order.ID, scheduledFor, leaseUntil)
+	if err != nil {
+		return false, fmt.Errorf("claim failed: %w", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	// The transaction may be run again after a serialization failure.
+	due := *order
+	var execution *Execution
+
+	// Create and Resume asked the owner for a one-time code when the amount
+	// is above the step-up threshold, so the runs are not asked again.
+	tranErr := uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		*order = due
+		execution = &Execution{
+			StandingOrderID: order.ID,
+			ScheduledFor:    scheduledFor,
+			Status:          ExecutionSucceeded,
+		}
+
+		tran, err := uc.tranUsecase.TransferWithTx(ctx, tx, order.UserID, &transaction.TransferReq{
+			FromAccount: order.FromAccount,
+			ToAccount:   order.ToAccount,
+			Amount:      money.Decimal(order.Amount.String()),
+			StepUp:      true,
+		})
+		if err != nil {
+			return err
+		}
+
+		execution.TransactionID = &tran.ID
+		order.ExecutedRuns++
+		order.FailureCount = 0
+		order.advance(scheduledFor)
+
+		return uc.record(ctx, tx, execution, order, &leaseUntil)
+	})
+	if tranErr == nil {
+		return true, nil
+	}
+
+	// Cancelled while the transfer ran, which was rolled back.
+	if errors.Is(tranErr, errs.ErrStandingOrderStatus) {
+		return false, nil
+	}
+
+	*order = due
+	msg := tranErr.Error()
+	execution = &Execution{
+		StandingOrderID: order.ID,
+		ScheduledFor:    scheduledFor,
+		Status:          ExecutionFailed,
+		Error:           &msg,
+	}
+	order.fail(tranErr, now)
+
+	err = uc.txManager.WithTx(ctx, func(tx *sql.Tx) error {
+		return uc.record(ctx, tx, execution, order, &leaseUntil)
+	})
+	if err != nil && !errors.Is(err, errs.ErrStandingOrderStatus) {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// record saves a run and the order after it, as claimed until leaseUntil.
+func (uc *standingOrderUsecase) record(ctx context.Context, tx *sql.Tx, execution *Execution, order *StandingOrder, leaseUntil *time.Time) error {
+	if err := uc.repo.CreateExecutionWithTx(ctx, tx, execution); err != nil {
+		return fmt.Errorf("record execution failed: %w", err)
+	}
+
+	now := time.Now()
+	order.UpdatedAt = &now
+
+	return uc.repo.UpdateWithTx(ctx, tx, order, StatusActive, leaseUntil)
+}
+
+func (uc *standingOrderUsecase) findOwned(ctx context.Context, id, userID int64) (*StandingOrder, error) {
+	order, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != userID {
+		return nil, errs.ErrStandingOrderNotFound
+	}
+
+	return order, nil
+}
+
+func (uc *standingOrderUsecase) update(ctx context.Context, order *StandingOrder, status orderStatus, nextRunAt *time.Time) error {
+	now := time.Now()
+	order.UpdatedAt = &now
+
+	return uc.repo.Update(ctx, order, status, nextRunAt)
+}
+
+// retryable reports whether a failed run may succeed later without the
+// customer changing the order: insufficient funds, or an infrastructure
+// error. Any other domain error pauses the order straight away.
+func retryable(err error) bool {
+	if errors.Is(err, errs.ErrAmountGreaterAccBalance) {
+		return true
+	}
+
+	for _, domain := range []error{
+		errs.ErrAccountNotFound,
+		errs.ErrAccountNotOwner,
+		errs.ErrAccountCannotSend,
+		errs.ErrAccountCannotReceive,
+		errs.ErrTranSameAccount,
+		errs.ErrCurrencyMismatch,
+		errs.ErrCurrencyNotSupported,
+		errs.ErrAmountGreaterThanZero,
+	} {
+		if errors.Is(err, domain) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+package standingorder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/db"
+	"github.com/codepnw/simple-bank/internal/modules/transaction"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExecute(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	start := now.AddDate(0, 0, -1)
+	leaseUntil := now.Add(leaseDuration)
+
+	executed := func(status executionStatus) any {
+		return mock.MatchedBy(func(e *Execution) bool {
+			return e.StandingOrderID == 1 && e.Status == status && e.ScheduledFor.Equal(start)
+		})
+	}
+	saved := func(runs, failures int, next time.Time) any {
+		return mock.MatchedBy(func(o *StandingOrder) bool {
+			return o.ExecutedRuns == runs && o.FailureCount == failures && o.NextRunAt.Equal(next)
+		})
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(repo *standingOrderRepositoryMock, tran *transaction.TransactionUsecaseMock)
+		ok        bool
+	}{
+		{
+			name: "transfer is recorded with the run",
+			mockSetup: func(repo *standingOrderRepositoryMock, tran *transaction.TransactionUsecaseMock) {
+				repo.On("Claim", mock.Anything, int64(1), start, leaseUntil).Return(true, nil)
+				tran.On("TransferWithTx", mock.Anything, mock.Anything, int64(7), mock.Anything).Return(&transaction.Transaction{ID: 30}, nil)
+				repo.On("CreateExecutionWithTx", mock.Anything, mock.Anything, mock.MatchedBy(func(e *Execution) bool {
+					return e.Status == ExecutionSucceeded && *e.TransactionID == 30
+				})).Return(nil)
+				repo.On("UpdateWithTx", mock.Anything, mock.Anything, saved(1, 0, start.AddDate(0, 0, 1)), StatusActive, &leaseUntil).Return(nil)
+			},
+			ok: true,
+		},
+		{
+			name: "claimed by another worker",
+			mockSetup: func(repo *standingOrderRepositoryMock, tran *transaction.TransactionUsecaseMock) {
+				repo.On("Claim", mock.Anything, int64(1), start, leaseUntil).Return(false, nil)
+			},
+		},
+		{
+			name: "cancelled while running",
+			mockSetup: func(repo *standingOrderRepositoryMock, tran *transaction.TransactionUsecaseMock) {
+				repo.On("Claim", mock.Anything, int64(1), start, leaseUntil).Return(true, nil)
+				tran.On("TransferWithTx", mock.Anything, mock.Anything, int64(7), mock.Anything).Return(&transaction.Transaction{ID: 30}, nil)
+				repo.On("CreateExecutionWithTx", mock.Anything, mock.Anything, executed(ExecutionSucceeded)).Return(nil).Once()
+				repo.On("UpdateWithTx", mock.Anything, mock.Anything, mock.Anything, StatusActive, &leaseUntil).Return(errs.ErrStandingOrderStatus).Once()
+			},
+		},
+		{
+			name: "failed transfer is retried later",
+			mockSetup: func(repo *standingOrderRepositoryMock, tran *transaction.TransactionUsecaseMock) {
+				repo.On("Claim", mock.Anything, int64(1), start, leaseUntil).Return(true, nil)
+				tran.On("TransferWithTx", mock.Anything, mock.Anything, int64(7), mock.Anything).Return(nil, errs.ErrAmountGreaterAccBalance)
+				repo.On("CreateExecutionWithTx", mock.Anything, mock.Anything, executed(ExecutionFailed)).Return(nil)
+				repo.On("UpdateWithTx", mock.Anything, mock.Anything, saved(0, 1, now.Add(retryDelay)), StatusActive, &leaseUntil).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(standingOrderRepositoryMock)
+			tran := transaction.NewTransactionUsecaseMock()
+			tc.mockSetup(repo, tran)
+
+			next := start
+			order := &StandingOrder{
+				ID:          1,
+				UserID:      7,
+				FromAccount: 2,
+				ToAccount:   3,
+				Amount:      money.FromMinor(10000, "THB"),
+				Currency:    "THB",
+				Frequency:   FrequencyDaily,
+				StartAt:     start,
+				NextRunAt:   &next,
+				Status:      StatusActive,
+			}
+
+			uc := NewStandingOrderUsecase(repo, nil, tran, &db.TxMock{}).(*standingOrderUsecase)
+			ok, err := uc.execute(context.Background(), order, now)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ok, ok)
+			repo.AssertExpectations(t)
+			tran.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package statement
+
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+type Statement struct {
+	AccountID      int64       `json:"account_id"`
+	AccountName    string      `json:"account_name"`
+	Currency       string      `json:"currency"`
+	From           time.Time   `json:"from"`
+	To             time.Time   `json:"to"`
+	OpeningBalance money.Money `json:"opening_balance"`
+	TotalIn        money.Money `json:"total_in"`
+	TotalOut       money.Money `json:"total_out"`
+	ClosingBalance money.Money `json:"closing_balance"`
+	Lines          []*Line     `json:"lines"`
+	GeneratedAt    time.Time   `json:"generated_at"`
+}
+
+// Line is one movement with the balance right after it.
+type Line struct {
+	Date          time.Time   `json:"date"`
+	TransactionID int64       `json:"transaction_id"`
+	Type          string      `json:"type"`
+	Counterparty  *int64      `json:"counterparty"`
+	Description   string      `json:"description"`
+	Amount        money.Money `json:"amount"`
+	Balance       money.Money `json:"balance"`
+}
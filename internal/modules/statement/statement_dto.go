@@ -0,0 +1,14 @@
+package statement
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatPDF  = "pdf"
+)
+
+// StatementQuery dates are calendar days; To is inclusive.
+type StatementQuery struct {
+	From   string `form:"from" validate:"omitempty,datetime=2006-01-02"`
+	To     string `form:"to" validate:"omitempty,datetime=2006-01-02"`
+	Format string `form:"format" validate:"omitempty,oneof=json csv pdf"`
+}
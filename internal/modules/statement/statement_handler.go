@@ -0,0 +1,100 @@
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/codepnw/simple-bank/internal/modules/role"
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type statementHandler struct {
+	uc       StatementUsecase
+	validate *validator.Validate
+}
+
+func NewStatementHandler(uc StatementUsecase) *statementHandler {
+	return &statementHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
+}
+
+func (h *statementHandler) GetStatement(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	// Staff may read the statement of any account
+	var ownerID *int64
+	if !role.Granted(ctx, role.PermAccountsReadAll) {
+		ownerID = &u.ID
+	}
+
+	query := new(StatementQuery)
+
+	if err := ctx.ShouldBindQuery(query); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(query); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	// Statement Usecase
+	st, err := h.uc.Generate(ctx.Request.Context(), id, ownerID, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrStatementPeriod):
+			response.ErrBadRequest(ctx, err)
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrAccountNotOwner):
+			response.Forbidden(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+
+	switch query.Format {
+	case FormatCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = renderCSV(&buf, st)
+	case FormatPDF:
+		contentType = "application/pdf"
+		err = renderPDF(&buf, st)
+	default:
+		response.Success(ctx, st)
+		return
+	}
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", st.AccountID, st.From.Format(dateLayout), st.To.Format(dateLayout), query.Format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
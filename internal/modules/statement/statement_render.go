@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	pdfLinesPerPage = 60
+	pdfDescWidth    = 38
+)
+
+func renderCSV(w io.Writer, st *Statement) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		{"Account", strconv.FormatInt(st.AccountID, 10), st.AccountName},
+		{"Currency", st.Currency},
+		{"Period", st.From.Format(dateLayout), st.To.Format(dateLayout)},
+		{"Opening Balance", st.OpeningBalance.String()},
+		{},
+		{"Date", "Transaction ID", "Type", "Description", "Amount", "Balance"},
+	}
+
+	for _, l := range st.Lines {
+		records = append(records, []string{
+			l.Date.Format(time.RFC3339),
+			strconv.FormatInt(l.TransactionID, 10),
+			l.Type,
+			l.Description,
+			l.Amount.String(),
+			l.Balance.String(),
+		})
+	}
+
+	records = append(records,
+		[]string{},
+		[]string{"Total In", st.TotalIn.String()},
+		[]string{"Total Out", st.TotalOut.String()},
+		[]string{"Closing Balance", st.ClosingBalance.String()},
+	)
+
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("write csv failed: %w", err)
+	}
+
+	return nil
+}
+
+func renderPDF(w io.Writer, st *Statement) error {
+	row := func(date, id, desc, amount, balance string) string {
+		if len(desc) > pdfDescWidth {
+			desc = desc[:pdfDescWidth-3] + "..."
+		}
+		return fmt.Sprintf("%-10s %8s %-*s %14s %14s", date, id, pdfDescWidth, desc, amount, balance)
+	}
+
+	lines := []string{
+		"ACCOUNT STATEMENT",
+		"",
+		fmt.Sprintf("Account:  %d %s", st.AccountID, st.AccountName),
+		fmt.Sprintf("Currency: %s", st.Currency),
+		fmt.Sprintf("Period:   %s to %s", st.From.Format(dateLayout), st.To.Format(dateLayout)),
+		"",
+		row("Date", "Txn", "Description", "Amount", "Balance"),
+		row("", "", "Opening balance", "", st.OpeningBalance.String()),
+	}
+
+	for _, l := range st.Lines {
+		lines = append(lines, row(
+			l.Date.Format(dateLayout),
+			strconv.FormatInt(l.TransactionID, 10),
+			l.Description,
+			l.Amount.String(),
+			l.Balance.String(),
+		))
+	}
+
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Total in:        %s", st.TotalIn.String()),
+		fmt.Sprintf("Total out:       %s", st.TotalOut.String()),
+		fmt.Sprintf("Closing balance: %s", st.ClosingBalance.String()),
+		"",
+		fmt.Sprintf("Generated %s", st.GeneratedAt.Format(time.RFC1123)),
+	)
+
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	return writePDF(w, pages)
+}
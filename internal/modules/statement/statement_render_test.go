@@ -0,0 +1,76 @@
+package statement
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStatement() *Statement {
+	thb := func(minor int64) money.Money { return money.FromMinor(minor, "THB") }
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	return &Statement{
+		AccountID:      7,
+		AccountName:    "Savings (main)",
+		Currency:       "THB",
+		From:           day,
+		To:             day.AddDate(0, 1, 0).Add(-time.Nanosecond),
+		OpeningBalance: thb(10000),
+		TotalIn:        thb(5050),
+		TotalOut:       thb(2000),
+		ClosingBalance: thb(13050),
+		Lines: []*Line{
+			{Date: day.Add(time.Hour), TransactionID: 1, Type: "DEPOSIT", Description: "DEPOSIT", Amount: thb(5050), Balance: thb(15050)},
+			{Date: day.Add(2 * time.Hour), TransactionID: 2, Type: "TRANSFER", Description: "TRANSFER to account 9", Amount: thb(-2000), Balance: thb(13050)},
+		},
+		GeneratedAt: day,
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := renderCSV(&buf, testStatement())
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "Opening Balance,100.00\n")
+	assert.Contains(t, out, "2,TRANSFER,TRANSFER to account 9,-20.00,130.50\n")
+	assert.Contains(t, out, "Closing Balance,130.50\n")
+}
+
+func TestRenderPDF(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := renderPDF(&buf, testStatement())
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, `Savings \(main\)`)
+
+	// startxref must point at the xref table
+	idx := strings.LastIndex(out, "startxref\n")
+	offset, err := strconv.Atoi(strings.Fields(out[idx+len("startxref\n"):])[0])
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out[offset:], "xref\n"))
+}
+
+func TestStatementPeriod(t *testing.T) {
+	now := time.Date(2025, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	from, to, err := statementPeriod(&StatementQuery{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = statementPeriod(&StatementQuery{From: "2025-03-10", To: "2025-03-01"}, now)
+	assert.Error(t, err)
+}
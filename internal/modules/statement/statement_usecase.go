@@ -0,0 +1,161 @@
+package statement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/transaction"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
+const (
+	queryTimeout = time.Second * 10
+	dateLayout   = "2006-01-02"
+	maxRange     = time.Hour * 24 * 366
+)
+
+type StatementUsecase interface {
+	Generate(ctx context.Context, accountID int64, ownerID *int64, query *StatementQuery) (*Statement, error)
+}
+
+type statementUsecase struct {
+	accRepo  account.AccountRepository
+	tranRepo transaction.TransasctionRepository
+}
+
+func NewStatementUsecase(accRepo account.AccountRepository, tranRepo transaction.TransasctionRepository) StatementUsecase {
+	return &statementUsecase{
+		accRepo:  accRepo,
+		tranRepo: tranRepo,
+	}
+}
+
+// Generate builds the statement of an account for the requested days. The
+// opening balance and every movement come from the ledger, so the closing
+// balance can be checked against the account balance. With an ownerID the
+// account must belong to that user.
+func (uc *statementUsecase) Generate(ctx context.Context, accountID int64, ownerID *int64, query *StatementQuery) (*Statement, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	from, to, err := statementPeriod(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := uc.accRepo.FindByID(ctx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ownerID != nil {
+		if err = acc.OwnedBy(*ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	opening, err := uc.tranRepo.BalanceAt(ctx, acc.ID, from)
+	if err != nil {
+		return nil, fmt.Errorf("opening balance failed: %w", err)
+	}
+
+	movements, err := uc.tranRepo.Movements(ctx, acc.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("find movements failed: %w", err)
+	}
+
+	st := &Statement{
+		AccountID:      acc.ID,
+		AccountName:    acc.Name,
+		Currency:       acc.Currency,
+		From:           from,
+		To:             to.Add(-time.Nanosecond),
+		OpeningBalance: money.FromMinor(opening, acc.Currency),
+		TotalIn:        money.Zero(acc.Currency),
+		TotalOut:       money.Zero(acc.Currency),
+		Lines:          make([]*Line, 0, len(movements)),
+		GeneratedAt:    time.Now(),
+	}
+
+	balance := st.OpeningBalance
+	for _, m := range movements {
+		if balance, err = balance.Add(m.Amount); err != nil {
+			return nil, err
+		}
+
+		if m.Amount.IsPositive() {
+			st.TotalIn, err = st.TotalIn.Add(m.Amount)
+		} else {
+			st.TotalOut, err = st.TotalOut.Sub(m.Amount)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		st.Lines = append(st.Lines, &Line{
+			Date:          m.CreatedAt,
+			TransactionID: m.TransactionID,
+			Type:          string(m.Type),
+			Counterparty:  m.Counterparty,
+			Description:   describe(m),
+			Amount:        m.Amount,
+			Balance:       balance,
+		})
+	}
+	st.ClosingBalance = balance
+
+	return st, nil
+}
+
+// statementPeriod turns the inclusive day range of the query into [from, to).
+// It defaults to the current month up to today.
+func statementPeriod(query *StatementQuery, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := today
+
+	var err error
+	if query.From != "" {
+		if from, err = time.Parse(dateLayout, query.From); err != nil {
+			return time.Time{}, time.Time{}, errs.ErrStatementPeriod
+		}
+	}
+	if query.To != "" {
+		if to, err = time.Parse(dateLayout, query.To); err != nil {
+			return time.Time{}, time.Time{}, errs.ErrStatementPeriod
+		}
+	}
+
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) || to.Sub(from) > maxRange {
+		return time.Time{}, time.Time{}, errs.ErrStatementPeriod
+	}
+
+	return from, to, nil
+}
+
+func describe(m *transaction.Movement) string {
+	desc := string(m.Type)
+
+	if m.Counterparty != nil {
+		if m.Amount.IsPositive() {
+			desc = fmt.Sprintf("%s from account %d", desc, *m.Counterparty)
+		} else {
+			desc = fmt.Sprintf("%s to account %d", desc, *m.Counterparty)
+		}
+	}
+
+	if m.Reason != nil && *m.Reason != "" {
+		desc += ": " + *m.Reason
+	}
+
+	return desc
+}
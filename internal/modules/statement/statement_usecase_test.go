@@ -0,0 +1,111 @@
+package statement
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/transaction"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGenerate(t *testing.T) {
+	thb := func(minor int64) money.Money { return money.FromMinor(minor, "THB") }
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	query := &StatementQuery{From: "2025-03-01", To: "2025-03-31"}
+
+	acc := &account.Account{ID: 1, UserID: 7, Name: "Savings", Currency: "THB", Balance: thb(12050)}
+	counterparty := int64(9)
+	reason := "rent"
+	movements := []*transaction.Movement{
+		{TransactionID: 11, Type: transaction.TypeDeposit, Amount: thb(5050), CreatedAt: from.Add(time.Hour)},
+		{TransactionID: 12, Type: transaction.TypeTransfer, Counterparty: &counterparty, Amount: thb(-2000), Reason: &reason, CreatedAt: from.Add(2 * time.Hour)},
+		{TransactionID: 13, Type: transaction.TypeWithdraw, Amount: thb(-1000), CreatedAt: from.Add(3 * time.Hour)},
+	}
+
+	owner := int64(7)
+	stranger := int64(8)
+
+	tests := []struct {
+		name        string
+		ownerID     *int64
+		mockSetup   func(accRepo *account.AccountRepositoryMock, tranRepo *mock.Mock)
+		expectedErr error
+	}{
+		{
+			name:    "statement of own account",
+			ownerID: &owner,
+			mockSetup: func(accRepo *account.AccountRepositoryMock, tranRepo *mock.Mock) {
+				accRepo.On("FindByID", mock.Anything, int64(1)).Return(acc, nil)
+				tranRepo.On("BalanceAt", mock.Anything, int64(1), from).Return(int64(10000), nil)
+				tranRepo.On("Movements", mock.Anything, int64(1), from, to).Return(movements, nil)
+			},
+		},
+		{
+			name: "statement of any account without an owner",
+			mockSetup: func(accRepo *account.AccountRepositoryMock, tranRepo *mock.Mock) {
+				accRepo.On("FindByID", mock.Anything, int64(1)).Return(acc, nil)
+				tranRepo.On("BalanceAt", mock.Anything, int64(1), from).Return(int64(10000), nil)
+				tranRepo.On("Movements", mock.Anything, int64(1), from, to).Return(movements, nil)
+			},
+		},
+		{
+			name:    "account of another user",
+			ownerID: &stranger,
+			mockSetup: func(accRepo *account.AccountRepositoryMock, tranRepo *mock.Mock) {
+				accRepo.On("FindByID", mock.Anything, int64(1)).Return(acc, nil)
+			},
+			expectedErr: errs.ErrAccountNotOwner,
+		},
+		{
+			name:    "account not found",
+			ownerID: &owner,
+			mockSetup: func(accRepo *account.AccountRepositoryMock, tranRepo *mock.Mock) {
+				accRepo.On("FindByID", mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+			},
+			expectedErr: errs.ErrAccountNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			accRepo := account.NewAccountRepositoryMock()
+			tranRepo := transaction.NewtransactionRepositoryMockMock()
+			tc.mockSetup(accRepo, &tranRepo.Mock)
+
+			uc := NewStatementUsecase(accRepo, tranRepo)
+			st, err := uc.Generate(context.Background(), 1, tc.ownerID, query)
+
+			accRepo.AssertExpectations(t)
+			tranRepo.AssertExpectations(t)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, st)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, from, st.From)
+			assert.Equal(t, to.Add(-time.Nanosecond), st.To)
+			assert.Equal(t, thb(10000), st.OpeningBalance)
+			assert.Equal(t, thb(5050), st.TotalIn)
+			assert.Equal(t, thb(3000), st.TotalOut)
+			assert.Equal(t, thb(12050), st.ClosingBalance)
+			assert.Equal(t, acc.Balance, st.ClosingBalance)
+
+			if assert.Len(t, st.Lines, 3) {
+				assert.Equal(t, thb(15050), st.Lines[0].Balance)
+				assert.Equal(t, thb(13050), st.Lines[1].Balance)
+				assert.Equal(t, "TRANSFER to account 9: rent", st.Lines[1].Description)
+				assert.Equal(t, thb(12050), st.Lines[2].Balance)
+			}
+		})
+	}
+}
@@ -1,13 +1,36 @@
 package transaction
 
-import "time"
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
 
 type Transaction struct {
-	ID          int64           `json:"id"`
-	FromAccount *int64          `json:"from_account"`
-	ToAccount   *int64          `json:"to_account"`
-	Amount      float64         `json:"amount"`
-	Type        transactionType `json:"type"`
-	Role        *string         `json:"role"`
-	CreatedAt   time.Time       `json:"created_at"`
+	ID             int64           `json:"id"`
+	FromAccount    *int64          `json:"from_account"`
+	ToAccount      *int64          `json:"to_account"`
+	Amount         money.Money     `json:"amount"`
+	Currency       string          `json:"currency"`
+	Type           transactionType `json:"type"`
+	Role           *string         `json:"role"`
+	ReversalOf     *int64          `json:"reversal_of,omitempty"`
+	ReversedAmount money.Money     `json:"reversed_amount"`
+	CreatedBy      *int64          `json:"created_by,omitempty"`
+	Reason         *string         `json:"reason,omitempty"`
+	Channel        *string         `json:"channel,omitempty"`
+	ChannelID      *int64          `json:"channel_id,omitempty"`
+	Reference      *string         `json:"reference,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Movement is one ledger entry of an account with its transaction details.
+// Amount is signed: positive money in, negative money out.
+type Movement struct {
+	TransactionID int64           `json:"transaction_id"`
+	Type          transactionType `json:"type"`
+	Counterparty  *int64          `json:"counterparty"`
+	Amount        money.Money     `json:"amount"`
+	Reason        *string         `json:"reason,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
 }
@@ -1,25 +1,103 @@
 package transaction
 
+import (
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+)
+
 type transactionType string
 
 const (
 	TypeDeposit  transactionType = "DEPOSIT"
 	TypeTransfer transactionType = "TRANSFER"
 	TypeWithdraw transactionType = "WITHDRAW"
+	TypeReversal transactionType = "REVERSAL"
+)
+
+// Deposit channels. Teller deposits are made by staff at a branch, the
+// others by trusted systems authenticated as a deposit channel.
+const (
+	ChannelTeller = "TELLER"
+	ChannelAPIKey = "API_KEY"
+	ChannelHMAC   = "HMAC"
 )
 
+// DepositSource records who made a deposit: a teller, or a deposit channel.
+type DepositSource struct {
+	Channel   string
+	ChannelID *int64
+	TellerID  *int64
+}
+
+// Amounts are decimals in the currency of the account, e.g. "10.50".
+
 type DepositReq struct {
-	ToAccount int64   `json:"to_account" validate:"required"`
-	Amount    float64 `json:"amount" validate:"required,gt=0"`
+	ToAccount int64         `json:"to_account" validate:"required"`
+	Amount    money.Decimal `json:"amount" validate:"required"`
+	Reference string        `json:"reference" validate:"max=255"`
 }
 
 type WithdrawReq struct {
-	FromAccount int64   `json:"from_account" validate:"required"`
-	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	FromAccount int64         `json:"from_account" validate:"required"`
+	Amount      money.Decimal `json:"amount" validate:"required"`
 }
 
 type TransferReq struct {
-	FromAccount int64   `json:"from_account" validate:"required"`
-	ToAccount   int64   `json:"to_account" validate:"required"`
-	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	FromAccount int64         `json:"from_account" validate:"required"`
+	ToAccount   int64         `json:"to_account" validate:"required"`
+	Amount      money.Decimal `json:"amount" validate:"required"`
+
+	// StepUp is set when the request carried a verified one-time code.
+	StepUp bool `json:"-"`
+}
+
+// ReverseReq reverses a transaction. Without an amount the whole remaining
+// amount is reversed; a smaller amount is a partial refund.
+type ReverseReq struct {
+	Amount money.Decimal `json:"amount"`
+	Reason string        `json:"reason" validate:"required,max=255"`
+}
+
+// CaptureReq settles a hold. Without an amount the whole hold is captured.
+// With a ToAccount the money is transferred there, otherwise it is withdrawn.
+type CaptureReq struct {
+	Amount    money.Decimal `json:"amount"`
+	ToAccount *int64        `json:"to_account" validate:"omitempty,gt=0"`
+}
+
+// TransactionQuery is the query string of the history endpoints. Amounts are
+// decimals in Currency, which defaults to THB.
+type TransactionQuery struct {
+	AccountID *int64        `form:"account_id"`
+	Type      string        `form:"type" validate:"omitempty,oneof=DEPOSIT TRANSFER WITHDRAW REVERSAL"`
+	From      time.Time     `form:"from"`
+	To        time.Time     `form:"to"`
+	MinAmount money.Decimal `form:"min_amount"`
+	MaxAmount money.Decimal `form:"max_amount"`
+	Currency  string        `form:"currency" validate:"omitempty,currency"`
+	Cursor    string        `form:"cursor"`
+	Limit     int           `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type TransactionFilter struct {
+	AccountID *int64
+	Type      transactionType
+	From      time.Time
+	To        time.Time
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	Cursor    *Cursor
+	Limit     int
+}
+
+// Cursor points at the last transaction of a page.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+type TransactionPage struct {
+	Items      []*Transaction `json:"items"`
+	NextCursor *string        `json:"next_cursor"`
 }
@@ -1,9 +1,15 @@
 package transaction
 
 import (
+	"errors"
+
+	"github.com/codepnw/simple-bank/internal/modules/channel"
 	"github.com/codepnw/simple-bank/internal/modules/user"
 	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -16,11 +22,34 @@ type transactionHandler struct {
 func NewTransactionHandler(uc TransactionUsecase) *transactionHandler {
 	return &transactionHandler{
 		uc:       uc,
-		validate: validator.New(),
+		validate: validation.New(),
+	}
+}
+
+// TellerDeposit is a cash deposit made by staff at a branch.
+func (h *transactionHandler) TellerDeposit(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	h.deposit(ctx, &DepositSource{Channel: ChannelTeller, TellerID: &u.ID})
+}
+
+// ChannelDeposit is a deposit sent by a trusted system authenticated by the
+// DepositChannel middleware.
+func (h *transactionHandler) ChannelDeposit(ctx *gin.Context) {
+	ch, ok := channel.Current(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "deposit channel not found in context")
+		return
 	}
+
+	h.deposit(ctx, &DepositSource{Channel: string(ch.Type), ChannelID: &ch.ID})
 }
 
-func (h *transactionHandler) Deposit(ctx *gin.Context) {
+func (h *transactionHandler) deposit(ctx *gin.Context, source *DepositSource) {
 	req := new(DepositReq)
 
 	if err := ctx.ShouldBindJSON(req); err != nil {
@@ -34,9 +63,22 @@ func (h *transactionHandler) Deposit(ctx *gin.Context) {
 	}
 
 	// Deposit Usecase
-	result, err := h.uc.Deposit(ctx.Request.Context(), req)
+	result, err := h.uc.Deposit(ctx.Request.Context(), source, req)
 	if err != nil {
-		response.ErrInternalServer(ctx, err)
+		switch {
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrDepositReferenceNeeded),
+			errors.Is(err, errs.ErrCurrencyNotSupported),
+			errors.Is(err, errs.ErrAmountGreaterThanZero),
+			errors.Is(err, money.ErrInvalidAmount):
+			response.ErrBadRequest(ctx, err)
+		case errors.Is(err, errs.ErrAccountCannotSend),
+			errors.Is(err, errs.ErrAccountCannotReceive):
+			response.ErrConflict(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
 		return
 	}
 
@@ -44,6 +86,12 @@ func (h *transactionHandler) Deposit(ctx *gin.Context) {
 }
 
 func (h *transactionHandler) Withdraw(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
 	req := new(WithdrawReq)
 
 	if err := ctx.ShouldBindJSON(req); err != nil {
@@ -57,9 +105,24 @@ func (h *transactionHandler) Withdraw(ctx *gin.Context) {
 	}
 
 	// Withdraw Usecase
-	result, err := h.uc.Withdraw(ctx.Request.Context(), req)
+	result, err := h.uc.Withdraw(ctx.Request.Context(), u.ID, req)
 	if err != nil {
-		response.ErrInternalServer(ctx, err)
+		switch {
+		case errors.Is(err, errs.ErrAccountNotOwner):
+			response.Forbidden(ctx, err)
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrAmountGreaterAccBalance),
+			errors.Is(err, errs.ErrAccountCannotSend),
+			errors.Is(err, errs.ErrAccountCannotReceive):
+			response.ErrConflict(ctx, err)
+		case errors.Is(err, errs.ErrCurrencyNotSupported),
+			errors.Is(err, errs.ErrAmountGreaterThanZero),
+			errors.Is(err, money.ErrInvalidAmount):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
 		return
 	}
 
@@ -67,6 +130,12 @@ func (h *transactionHandler) Withdraw(ctx *gin.Context) {
 }
 
 func (h *transactionHandler) Transfer(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
 	req := new(TransferReq)
 
 	if err := ctx.ShouldBindJSON(req); err != nil {
@@ -79,31 +148,134 @@ func (h *transactionHandler) Transfer(ctx *gin.Context) {
 		return
 	}
 
+	req.StepUp = ctx.GetBool(utils.ContextKeyStepUp)
+
 	// Transfer Usecase
-	result, err := h.uc.Transfer(ctx.Request.Context(), req)
+	result, err := h.uc.Transfer(ctx.Request.Context(), u.ID, req)
 	if err != nil {
-		response.ErrInternalServer(ctx, err)
+		switch {
+		case errors.Is(err, errs.ErrAccountNotOwner),
+			errors.Is(err, errs.ErrStepUpRequired):
+			response.Forbidden(ctx, err)
+		case errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrAmountGreaterAccBalance),
+			errors.Is(err, errs.ErrAccountCannotSend),
+			errors.Is(err, errs.ErrAccountCannotReceive):
+			response.ErrConflict(ctx, err)
+		case errors.Is(err, errs.ErrTranSameAccount),
+			errors.Is(err, errs.ErrCurrencyMismatch),
+			errors.Is(err, errs.ErrAmountGreaterThanZero),
+			errors.Is(err, money.ErrInvalidAmount):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
 		return
 	}
 
 	response.Success(ctx, result)
 }
 
-func (h *transactionHandler) TransactionsByCurrentUser(ctx *gin.Context) {
+func (h *transactionHandler) Reverse(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
 	u, err := user.CurrentUser(ctx)
 	if err != nil {
 		response.Unauthorized(ctx, err.Error())
 		return
 	}
 
-	// Transactions Usecase
-	result, err := h.uc.Transactions(ctx.Request.Context(), u.ID)
+	req := new(ReverseReq)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	// Reverse Usecase
+	result, err := h.uc.Reverse(ctx.Request.Context(), id, u.ID, req)
 	if err != nil {
-		response.ErrInternalServer(ctx, err)
+		switch {
+		case errors.Is(err, errs.ErrTranNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrTranAlreadyReversed),
+			errors.Is(err, errs.ErrTranReversalExceeds),
+			errors.Is(err, errs.ErrTranCannotReverseReverse),
+			errors.Is(err, errs.ErrAmountGreaterAccBalance),
+			errors.Is(err, errs.ErrAccountCannotSend),
+			errors.Is(err, errs.ErrAccountCannotReceive):
+			response.ErrConflict(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
 		return
 	}
 
-	response.Success(ctx, result)
+	response.Created(ctx, result)
+}
+
+func (h *transactionHandler) CaptureHold(ctx *gin.Context) {
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req := new(CaptureReq)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	// Capture Usecase
+	result, err := h.uc.CaptureHold(ctx.Request.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrHoldNotFound),
+			errors.Is(err, errs.ErrAccountNotFound):
+			response.ErrNotFound(ctx, err)
+		case errors.Is(err, errs.ErrHoldNotActive),
+			errors.Is(err, errs.ErrHoldCaptureExceeds),
+			errors.Is(err, errs.ErrAccountCannotSend),
+			errors.Is(err, errs.ErrAccountCannotReceive):
+			response.ErrConflict(ctx, err)
+		case errors.Is(err, errs.ErrTranSameAccount),
+			errors.Is(err, errs.ErrCurrencyMismatch),
+			errors.Is(err, errs.ErrAmountGreaterThanZero):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
+func (h *transactionHandler) TransactionsByCurrentUser(ctx *gin.Context) {
+	u, err := user.CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	h.transactions(ctx, u.ID)
 }
 
 func (h *transactionHandler) TransactionsByUserID(ctx *gin.Context) {
@@ -113,10 +285,33 @@ func (h *transactionHandler) TransactionsByUserID(ctx *gin.Context) {
 		return
 	}
 
+	h.transactions(ctx, userID)
+}
+
+func (h *transactionHandler) transactions(ctx *gin.Context, userID int64) {
+	query := new(TransactionQuery)
+
+	if err := ctx.ShouldBindQuery(query); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(query); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
 	// Transactions Usecase
-	result, err := h.uc.Transactions(ctx.Request.Context(), userID)
+	result, err := h.uc.Transactions(ctx.Request.Context(), userID, query)
 	if err != nil {
-		response.ErrInternalServer(ctx, err)
+		switch {
+		case errors.Is(err, errs.ErrInvalidCursor),
+			errors.Is(err, money.ErrInvalidAmount),
+			errors.Is(err, money.ErrUnknownCurrency):
+			response.ErrBadRequest(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
 		return
 	}
 
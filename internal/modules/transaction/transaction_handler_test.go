@@ -0,0 +1,80 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// transactionRouter serves the handler for uc as the user with ID 1.
+func transactionRouter(uc *TransactionUsecaseMock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	h := NewTransactionHandler(uc)
+	signedIn := func(ctx *gin.Context) {
+		ctx.Set(utils.ContextKeyUser, &user.User{ID: 1})
+	}
+	r.POST("/withdraw", signedIn, h.Withdraw)
+	r.POST("/transfer", signedIn, h.Transfer)
+	return r
+}
+
+func TestTransactionHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		err            error
+		expectedStatus int
+	}{
+		{"withdraw more than the balance", "Withdraw", errs.ErrAmountGreaterAccBalance, http.StatusConflict},
+		{"withdraw from an unknown account", "Withdraw", errs.ErrAccountNotFound, http.StatusNotFound},
+		{"withdraw an invalid amount", "Withdraw", fmt.Errorf("parse 1.234: %w", money.ErrInvalidAmount), http.StatusBadRequest},
+		{"withdraw a zero amount", "Withdraw", errs.ErrAmountGreaterThanZero, http.StatusBadRequest},
+		{"withdraw in an unsupported currency", "Withdraw", errs.ErrCurrencyNotSupported, http.StatusBadRequest},
+		{"withdraw from another user's account", "Withdraw", errs.ErrAccountNotOwner, http.StatusForbidden},
+		{"withdraw fails", "Withdraw", errors.New("connection reset"), http.StatusInternalServerError},
+		{"transfer more than the balance", "Transfer", errs.ErrAmountGreaterAccBalance, http.StatusConflict},
+		{"transfer to an unknown account", "Transfer", errs.ErrAccountNotFound, http.StatusNotFound},
+		{"transfer to the same account", "Transfer", errs.ErrTranSameAccount, http.StatusBadRequest},
+		{"transfer between currencies", "Transfer", errs.ErrCurrencyMismatch, http.StatusBadRequest},
+		{"transfer an invalid amount", "Transfer", fmt.Errorf("parse 1.234: %w", money.ErrInvalidAmount), http.StatusBadRequest},
+		{"transfer a zero amount", "Transfer", errs.ErrAmountGreaterThanZero, http.StatusBadRequest},
+		{"transfer from a frozen account", "Transfer", errs.ErrAccountCannotSend, http.StatusConflict},
+		{"transfer without step-up", "Transfer", errs.ErrStepUpRequired, http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			uc := NewTransactionUsecaseMock()
+			uc.On(tc.method, mock.Anything, int64(1), mock.Anything).Return(nil, tc.err)
+
+			var path, body string
+			switch tc.method {
+			case "Withdraw":
+				path, body = "/withdraw", `{"from_account":2,"amount":"10"}`
+			case "Transfer":
+				path, body = "/transfer", `{"from_account":2,"to_account":3,"amount":"10"}`
+			}
+
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			transactionRouter(uc).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			uc.AssertExpectations(t)
+		})
+	}
+}
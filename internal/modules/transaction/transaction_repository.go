@@ -3,13 +3,25 @@ package transaction
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 )
 
 type TransasctionRepository interface {
 	DepositWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error)
 	WithdrawWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error)
 	TransferWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error)
-	Transactions(ctx context.Context, userID int64) ([]*Transaction, error)
+	ReversalWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error)
+	FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Transaction, error)
+	AddReversedAmountWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error
+	Transactions(ctx context.Context, userID int64, filter *TransactionFilter) ([]*Transaction, error)
+	Movements(ctx context.Context, accountID int64, from, to time.Time) ([]*Movement, error)
+	BalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error)
 }
 
 type transactionRepository struct {
@@ -22,16 +34,21 @@ func NewTransactionRepository(db *sql.DB) TransasctionRepository {
 
 func (r *transactionRepository) DepositWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error) {
 	query := `
-		INSERT INTO transactions (to_account, amount, type)
-		VALUES ($1, $2, $3)
+		INSERT INTO transactions (to_account, amount, currency, type, channel, channel_id, created_by, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, type, created_at
 	`
 	err := tx.QueryRowContext(
 		ctx,
 		query,
 		input.ToAccount,
-		input.Amount,
+		input.Amount.Minor(),
+		input.Amount.Currency(),
 		TypeDeposit,
+		input.Channel,
+		input.ChannelID,
+		input.CreatedBy,
+		input.Reference,
 	).Scan(
 		&input.ID,
 		&input.Type,
@@ -40,21 +57,23 @@ func (r *transactionRepository) DepositWithTx(ctx context.Context, tx *sql.Tx, i
 	if err != nil {
 		return nil, err
 	}
+	input.Currency = input.Amount.Currency()
 
 	return input, nil
 }
 
 func (r *transactionRepository) WithdrawWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error) {
 	query := `
-		INSERT INTO transactions (from_account, amount, type)
-		VALUES ($1, $2, $3)
+		INSERT INTO transactions (from_account, amount, currency, type)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, type, created_at
 	`
 	err := tx.QueryRowContext(
 		ctx,
 		query,
 		input.FromAccount,
-		input.Amount,
+		input.Amount.Minor(),
+		input.Amount.Currency(),
 		TypeWithdraw,
 	).Scan(
 		&input.ID,
@@ -64,14 +83,15 @@ func (r *transactionRepository) WithdrawWithTx(ctx context.Context, tx *sql.Tx,
 	if err != nil {
 		return nil, err
 	}
+	input.Currency = input.Amount.Currency()
 
 	return input, nil
 }
 
 func (r *transactionRepository) TransferWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error) {
 	query := `
-		INSERT INTO transactions (from_account, to_account, amount, type)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO transactions (from_account, to_account, amount, currency, type)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, type, created_at
 	`
 	err := tx.QueryRowContext(
@@ -79,7 +99,8 @@ func (r *transactionRepository) TransferWithTx(ctx context.Context, tx *sql.Tx,
 		query,
 		input.FromAccount,
 		input.ToAccount,
-		input.Amount,
+		input.Amount.Minor(),
+		input.Amount.Currency(),
 		TypeTransfer,
 	).Scan(
 		&input.ID,
@@ -89,23 +110,156 @@ func (r *transactionRepository) TransferWithTx(ctx context.Context, tx *sql.Tx,
 	if err != nil {
 		return nil, err
 	}
+	input.Currency = input.Amount.Currency()
+
+	return input, nil
+}
+
+func (r *transactionRepository) ReversalWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error) {
+	query := `
+		INSERT INTO transactions (from_account, to_account, amount, currency, type, reversal_of, created_by, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, type, created_at
+	`
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		input.FromAccount,
+		input.ToAccount,
+		input.Amount.Minor(),
+		input.Amount.Currency(),
+		TypeReversal,
+		input.ReversalOf,
+		input.CreatedBy,
+		input.Reason,
+	).Scan(
+		&input.ID,
+		&input.Type,
+		&input.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	input.Currency = input.Amount.Currency()
 
 	return input, nil
 }
 
-func (r *transactionRepository) Transactions(ctx context.Context, userID int64) ([]*Transaction, error) {
+// FindByIDForUpdateWithTx locks the transaction row until tx ends so
+// concurrent reversals of it are serialized.
+func (r *transactionRepository) FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Transaction, error) {
 	query := `
-		SELECT t.id, t.from_account, t.to_account, t.amount, t.type, t.created_at,
-			CASE 
-				WHEN t.from_account = a.id THEN 'SENDER'
-				WHEN t.to_account = a.id THEN 'RECEIVER'
-			END AS role
-		FROM transactions t 
-		JOIN accounts a 
-			ON (a.id = t.from_account OR a.id = t.to_account)
-		WHERE a.user_id = $1
+		SELECT id, from_account, to_account, amount, currency, type,
+			reversal_of, reversed_amount, created_by, reason,
+			channel, channel_id, reference, created_at
+		FROM transactions WHERE id = $1
+		FOR UPDATE
 	`
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	t := new(Transaction)
+	var amount, reversed int64
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&t.ID,
+		&t.FromAccount,
+		&t.ToAccount,
+		&amount,
+		&t.Currency,
+		&t.Type,
+		&t.ReversalOf,
+		&reversed,
+		&t.CreatedBy,
+		&t.Reason,
+		&t.Channel,
+		&t.ChannelID,
+		&t.Reference,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrTranNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Amount = money.FromMinor(amount, t.Currency)
+	t.ReversedAmount = money.FromMinor(reversed, t.Currency)
+
+	return t, nil
+}
+
+func (r *transactionRepository) AddReversedAmountWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
+	query := `
+		UPDATE transactions SET reversed_amount = reversed_amount + $1
+		WHERE id = $2 AND reversed_amount + $1 <= amount
+	`
+	res, err := tx.ExecContext(ctx, query, amount.Minor(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrTranReversalExceeds
+	}
+
+	return nil
+}
+
+// Transactions returns one page of a user's history, newest first. Rows are
+// ordered by (created_at, id) so the keyset cursor is stable.
+func (r *transactionRepository) Transactions(ctx context.Context, userID int64, filter *TransactionFilter) ([]*Transaction, error) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	role := "CASE WHEN fa.user_id = $1 THEN 'SENDER' ELSE 'RECEIVER' END"
+	where := []string{"(fa.user_id = $1 OR ta.user_id = $1)"}
+
+	if filter.AccountID != nil {
+		p := arg(*filter.AccountID)
+		role = fmt.Sprintf("CASE WHEN t.from_account = %s THEN 'SENDER' ELSE 'RECEIVER' END", p)
+		where = append(where, fmt.Sprintf("(t.from_account = %s OR t.to_account = %s)", p, p))
+	}
+	if filter.Type != "" {
+		where = append(where, "t.type = "+arg(filter.Type))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "t.created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "t.created_at < "+arg(filter.To))
+	}
+	if filter.MinAmount != nil {
+		where = append(where, "t.currency = "+arg(filter.MinAmount.Currency()))
+		where = append(where, "t.amount >= "+arg(filter.MinAmount.Minor()))
+	}
+	if filter.MaxAmount != nil {
+		where = append(where, "t.currency = "+arg(filter.MaxAmount.Currency()))
+		where = append(where, "t.amount <= "+arg(filter.MaxAmount.Minor()))
+	}
+	if filter.Cursor != nil {
+		where = append(where, fmt.Sprintf("(t.created_at, t.id) < (%s, %s)", arg(filter.Cursor.CreatedAt), arg(filter.Cursor.ID)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.from_account, t.to_account, t.amount, t.currency, t.type,
+			t.reversal_of, t.reversed_amount, t.created_by, t.reason,
+			t.channel, t.channel_id, t.reference, t.created_at,
+			%s AS role
+		FROM transactions t
+		LEFT JOIN accounts fa ON fa.id = t.from_account
+		LEFT JOIN accounts ta ON ta.id = t.to_account
+		WHERE %s
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT %s
+	`, role, strings.Join(where, " AND "), arg(filter.Limit))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -115,19 +269,30 @@ func (r *transactionRepository) Transactions(ctx context.Context, userID int64)
 
 	for rows.Next() {
 		t := new(Transaction)
+		var amount, reversed int64
 
 		err = rows.Scan(
 			&t.ID,
 			&t.FromAccount,
 			&t.ToAccount,
-			&t.Amount,
+			&amount,
+			&t.Currency,
 			&t.Type,
+			&t.ReversalOf,
+			&reversed,
+			&t.CreatedBy,
+			&t.Reason,
+			&t.Channel,
+			&t.ChannelID,
+			&t.Reference,
 			&t.CreatedAt,
 			&t.Role,
 		)
 		if err != nil {
 			return nil, err
 		}
+		t.Amount = money.FromMinor(amount, t.Currency)
+		t.ReversedAmount = money.FromMinor(reversed, t.Currency)
 
 		transactions = append(transactions, t)
 	}
@@ -138,3 +303,68 @@ func (r *transactionRepository) Transactions(ctx context.Context, userID int64)
 
 	return transactions, nil
 }
+
+// Movements returns the ledger entries of an account in [from, to), oldest
+// first.
+func (r *transactionRepository) Movements(ctx context.Context, accountID int64, from, to time.Time) ([]*Movement, error) {
+	query := `
+		SELECT e.transaction_id, t.type,
+			CASE WHEN e.amount > 0 THEN t.from_account ELSE t.to_account END,
+			e.amount, e.currency, t.reason, e.created_at
+		FROM ledger_entries e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE e.account_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		ORDER BY e.created_at, e.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []*Movement
+
+	for rows.Next() {
+		m := new(Movement)
+		var amount int64
+		var currency string
+
+		err = rows.Scan(
+			&m.TransactionID,
+			&m.Type,
+			&m.Counterparty,
+			&amount,
+			&currency,
+			&m.Reason,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		m.Amount = money.FromMinor(amount, currency)
+
+		movements = append(movements, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// BalanceAt returns the account balance in minor units just before at,
+// derived from the ledger.
+func (r *transactionRepository) BalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error) {
+	var balance int64
+	query := `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_entries
+		WHERE account_id = $1 AND created_at < $2
+	`
+	err := r.db.QueryRowContext(ctx, query, accountID, at).Scan(&balance)
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
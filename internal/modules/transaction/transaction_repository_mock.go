@@ -3,7 +3,9 @@ package transaction
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	"github.com/codepnw/simple-bank/internal/utils/money"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -26,8 +28,8 @@ func (m *transactionRepositoryMock) DepositWithTx(ctx context.Context, tx *sql.T
 	return res, args.Error(1)
 }
 
-func (m *transactionRepositoryMock) Transactions(ctx context.Context, userID int64) ([]*Transaction, error) {
-	args := m.Called(ctx, userID)
+func (m *transactionRepositoryMock) Transactions(ctx context.Context, userID int64, filter *TransactionFilter) ([]*Transaction, error) {
+	args := m.Called(ctx, userID, filter)
 
 	res, ok := args.Get(0).([]*Transaction)
 	if !ok {
@@ -58,3 +60,46 @@ func (m *transactionRepositoryMock) WithdrawWithTx(ctx context.Context, tx *sql.
 
 	return res, args.Error(1)
 }
+
+func (m *transactionRepositoryMock) ReversalWithTx(ctx context.Context, tx *sql.Tx, input *Transaction) (*Transaction, error) {
+	args := m.Called(ctx, tx, input)
+
+	res, ok := args.Get(0).(*Transaction)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *transactionRepositoryMock) FindByIDForUpdateWithTx(ctx context.Context, tx *sql.Tx, id int64) (*Transaction, error) {
+	args := m.Called(ctx, tx, id)
+
+	res, ok := args.Get(0).(*Transaction)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *transactionRepositoryMock) AddReversedAmountWithTx(ctx context.Context, tx *sql.Tx, id int64, amount money.Money) error {
+	args := m.Called(ctx, tx, id, amount)
+	return args.Error(0)
+}
+
+func (m *transactionRepositoryMock) Movements(ctx context.Context, accountID int64, from, to time.Time) ([]*Movement, error) {
+	args := m.Called(ctx, accountID, from, to)
+
+	res, ok := args.Get(0).([]*Movement)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *transactionRepositoryMock) BalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error) {
+	args := m.Called(ctx, accountID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -3,66 +3,119 @@ package transaction
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/codepnw/simple-bank/internal/db"
 	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/ledger"
 	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 )
 
-const queryTimeout = time.Second * 5
+const (
+	queryTimeout = time.Second * 5
+
+	defaultPageLimit = 20
+)
 
 type TransactionUsecase interface {
-	Deposit(ctx context.Context, req *DepositReq) (*Transaction, error)
-	Withdraw(ctx context.Context, req *WithdrawReq) (*Transaction, error)
-	Transfer(ctx context.Context, req *TransferReq) (*Transaction, error)
-	Transactions(ctx context.Context, userID int64) ([]*Transaction, error)
+	Deposit(ctx context.Context, source *DepositSource, req *DepositReq) (*Transaction, error)
+	Withdraw(ctx context.Context, userID int64, req *WithdrawReq) (*Transaction, error)
+	Transfer(ctx context.Context, userID int64, req *TransferReq) (*Transaction, error)
+	TransferWithTx(ctx context.Context, tx *sql.Tx, userID int64, req *TransferReq) (*Transaction, error)
+	Reverse(ctx context.Context, id, actorID int64, req *ReverseReq) (*Transaction, error)
+	CaptureHold(ctx context.Context, holdID int64, req *CaptureReq) (*Transaction, error)
+	Transactions(ctx context.Context, userID int64, query *TransactionQuery) (*TransactionPage, error)
+	CheckStepUp(amount money.Money, verified bool) error
 }
 
 type transactionUsecase struct {
-	tranRepo   TransasctionRepository
-	accUsecase account.AccountUsecase
-	txManager  db.TxManager
+	tranRepo        TransasctionRepository
+	accUsecase      account.AccountUsecase
+	ledgerUsecase   ledger.LedgerUsecase
+	txManager       db.TxManager
+	stepUpThreshold money.Decimal
 }
 
-func NewTransactionUsecse(tranRepo TransasctionRepository, accUsecase account.AccountUsecase, txManager db.TxManager) TransactionUsecase {
+// NewTransactionUsecse creates the usecase. Transfers above
+// stepUpThreshold, in units of the sending account currency, need a verified
+// one-time code; an empty threshold allows any amount.
+func NewTransactionUsecse(tranRepo TransasctionRepository, accUsecase account.AccountUsecase, ledgerUsecase ledger.LedgerUsecase, txManager db.TxManager, stepUpThreshold money.Decimal) TransactionUsecase {
 	return &transactionUsecase{
-		tranRepo:   tranRepo,
-		accUsecase: accUsecase,
-		txManager:  txManager,
+		tranRepo:        tranRepo,
+		accUsecase:      accUsecase,
+		ledgerUsecase:   ledgerUsecase,
+		txManager:       txManager,
+		stepUpThreshold: stepUpThreshold,
 	}
 }
 
-func (uc *transactionUsecase) Deposit(ctx context.Context, req *DepositReq) (*Transaction, error) {
+// Deposit credits an account with cash from the vault. The source is
+// recorded on the transaction; teller deposits need a reference, e.g. the
+// slip number.
+func (uc *transactionUsecase) Deposit(ctx context.Context, source *DepositSource, req *DepositReq) (*Transaction, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
+	if source.Channel == ChannelTeller && req.Reference == "" {
+		return nil, errs.ErrDepositReferenceNeeded
+	}
+
 	result := new(Transaction)
 
-	// Find Account
-	account, err := uc.accUsecase.GetAccountByID(ctx, req.ToAccount)
+	vaultID, err := uc.cashVaultFor(ctx, req.ToAccount)
 	if err != nil {
-		return nil, errs.ErrAccountNotFound
+		return nil, err
 	}
 
 	// Tx Transaction
-	err = uc.txManager.WithTx(ctx, func(tx *sql.Tx) error {
-		// Update Account
-		err = uc.accUsecase.UpdateBalanceWithTx(ctx, tx, account.ID, req.Amount)
+	err = uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		// Lock Account: the vault is not locked, see cashVaultFor
+		accounts, err := uc.accUsecase.LockAccountsWithTx(ctx, tx, req.ToAccount)
 		if err != nil {
-			return fmt.Errorf("update balance failed: %w", err)
+			return err
+		}
+		account := accounts[req.ToAccount]
+
+		if err = account.CanReceive(); err != nil {
+			return err
+		}
+
+		amount, err := parseAmount(req.Amount, account.Currency)
+		if err != nil {
+			return err
 		}
 
 		// Insert Transaction
-		result, err = uc.tranRepo.DepositWithTx(ctx, tx, &Transaction{
+		deposit := &Transaction{
 			ToAccount: &account.ID,
-			Amount:    req.Amount,
-		})
+			Amount:    amount,
+			Channel:   &source.Channel,
+			ChannelID: source.ChannelID,
+			CreatedBy: source.TellerID,
+		}
+		if req.Reference != "" {
+			deposit.Reference = &req.Reference
+		}
+
+		result, err = uc.tranRepo.DepositWithTx(ctx, tx, deposit)
 		if err != nil {
 			return fmt.Errorf("insert transaction failed: %w", err)
 		}
 
+		// Post Ledger: Cash Vault -> Account
+		err = uc.ledgerUsecase.PostWithTx(ctx, tx, result.ID, []*ledger.Entry{
+			{AccountID: vaultID, Amount: amount.Neg()},
+			{AccountID: account.ID, Amount: amount},
+		})
+		if err != nil {
+			return fmt.Errorf("post ledger failed: %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -72,39 +125,63 @@ func (uc *transactionUsecase) Deposit(ctx context.Context, req *DepositReq) (*Tr
 	return result, nil
 }
 
-func (uc *transactionUsecase) Withdraw(ctx context.Context, req *WithdrawReq) (*Transaction, error) {
+// Withdraw takes money out of an account of userID.
+func (uc *transactionUsecase) Withdraw(ctx context.Context, userID int64, req *WithdrawReq) (*Transaction, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	result := new(Transaction)
 
-	// Find Account
-	account, err := uc.accUsecase.GetAccountByID(ctx, req.FromAccount)
+	vaultID, err := uc.cashVaultFor(ctx, req.FromAccount)
 	if err != nil {
-		return nil, errs.ErrAccountNotFound
-	}
-
-	if req.Amount > float64(account.Balance) {
-		return nil, errs.ErrAmountGreaterAccBalance
+		return nil, err
 	}
 
 	// Tx Transaction
-	err = uc.txManager.WithTx(ctx, func(tx *sql.Tx) error {
-		// Update Account
-		err = uc.accUsecase.UpdateBalanceWithTx(ctx, tx, account.ID, -req.Amount)
+	err = uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		// Lock Account: the vault is not locked, see cashVaultFor
+		accounts, err := uc.accUsecase.LockAccountsWithTx(ctx, tx, req.FromAccount)
+		if err != nil {
+			return err
+		}
+		account := accounts[req.FromAccount]
+
+		if err = account.OwnedBy(userID); err != nil {
+			return err
+		}
+
+		if err = account.CanSend(); err != nil {
+			return err
+		}
+
+		amount, err := parseAmount(req.Amount, account.Currency)
 		if err != nil {
-			return fmt.Errorf("update balance failed: %w", err)
+			return err
+		}
+
+		// Check Account Balance
+		if err = checkBalance(amount, account.AvailableBalance); err != nil {
+			return err
 		}
 
 		// Insert Transaction
 		result, err = uc.tranRepo.WithdrawWithTx(ctx, tx, &Transaction{
 			FromAccount: &account.ID,
-			Amount:      req.Amount,
+			Amount:      amount,
 		})
 		if err != nil {
 			return fmt.Errorf("insert transaction failed: %w", err)
 		}
 
+		// Post Ledger: Account -> Cash Vault
+		err = uc.ledgerUsecase.PostWithTx(ctx, tx, result.ID, []*ledger.Entry{
+			{AccountID: account.ID, Amount: amount.Neg()},
+			{AccountID: vaultID, Amount: amount},
+		})
+		if err != nil {
+			return fmt.Errorf("post ledger failed: %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -114,62 +191,218 @@ func (uc *transactionUsecase) Withdraw(ctx context.Context, req *WithdrawReq) (*
 	return result, nil
 }
 
-func (uc *transactionUsecase) Transfer(ctx context.Context, req *TransferReq) (*Transaction, error) {
+// Transfer moves money from an account of userID to any account. Both rows
+// are locked before the balance is checked, so concurrent transfers cannot
+// overdraw the sender or lose an update.
+func (uc *transactionUsecase) Transfer(ctx context.Context, userID int64, req *TransferReq) (*Transaction, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	result := new(Transaction)
+	var result *Transaction
 
-	// Find From Account
-	fromAcc, err := uc.accUsecase.GetAccountByID(ctx, req.FromAccount)
+	// Tx Transaction
+	err := uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		var err error
+		result, err = uc.TransferWithTx(ctx, tx, userID, req)
+		return err
+	})
 	if err != nil {
-		return nil, errs.ErrAccountNotFound
+		return nil, err
 	}
 
-	// Find To Account
-	toAcc, err := uc.accUsecase.GetAccountByID(ctx, req.ToAccount)
-	if err != nil {
-		return nil, errs.ErrAccountNotFound
-	}
+	return result, nil
+}
 
+// TransferWithTx moves money inside the caller's transaction, which must be
+// serializable, so the caller can record what the transfer was for in the
+// same commit.
+func (uc *transactionUsecase) TransferWithTx(ctx context.Context, tx *sql.Tx, userID int64, req *TransferReq) (*Transaction, error) {
 	if req.FromAccount == req.ToAccount {
 		return nil, errs.ErrTranSameAccount
 	}
 
-	if req.Amount <= 0 {
-		return nil, errs.ErrAmountGreaterThanZero
+	// Lock Accounts
+	accounts, err := uc.accUsecase.LockAccountsWithTx(ctx, tx, req.FromAccount, req.ToAccount)
+	if err != nil {
+		return nil, err
+	}
+	fromAcc, toAcc := accounts[req.FromAccount], accounts[req.ToAccount]
+
+	if err = fromAcc.OwnedBy(userID); err != nil {
+		return nil, err
+	}
+
+	if err = fromAcc.CanSend(); err != nil {
+		return nil, err
+	}
+
+	if err = toAcc.CanReceive(); err != nil {
+		return nil, err
+	}
+
+	if fromAcc.Currency != toAcc.Currency {
+		return nil, errs.ErrCurrencyMismatch
+	}
+
+	amount, err := parseAmount(req.Amount, fromAcc.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = uc.CheckStepUp(amount, req.StepUp); err != nil {
+		return nil, err
 	}
 
 	// Check Account Balance
-	if req.Amount > float64(fromAcc.Balance) {
-		return nil, errs.ErrAmountGreaterAccBalance
+	if err = checkBalance(amount, fromAcc.AvailableBalance); err != nil {
+		return nil, err
 	}
 
+	// Insert Transaction
+	result, err := uc.tranRepo.TransferWithTx(ctx, tx, &Transaction{
+		FromAccount: &fromAcc.ID,
+		ToAccount:   &toAcc.ID,
+		Amount:      amount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("insert transaction failed: %w", err)
+	}
+
+	// Post Ledger: From Account -> To Account
+	err = uc.ledgerUsecase.PostWithTx(ctx, tx, result.ID, []*ledger.Entry{
+		{AccountID: fromAcc.ID, Amount: amount.Neg()},
+		{AccountID: toAcc.ID, Amount: amount},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("post ledger failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// cashVaultFor returns the cash vault in the currency of an account. The
+// currency never changes, so it is read before the account is locked. The
+// vault itself is never locked: it takes part in every cash movement in its
+// currency, has no balance to guard and its balance is read from the ledger.
+func (uc *transactionUsecase) cashVaultFor(ctx context.Context, accountID int64) (int64, error) {
+	acc, err := uc.accUsecase.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return 0, errs.ErrAccountNotFound
+	}
+
+	return uc.ledgerUsecase.SystemAccountID(ctx, ledger.SystemCashVault, acc.Currency)
+}
+
+// Reverse posts a compensating transaction that moves the money of the
+// original back. Repeated calls may refund it in parts until nothing is left.
+// The account the money comes back from must be able to send and cover it,
+// and the account it goes to must be able to receive.
+func (uc *transactionUsecase) Reverse(ctx context.Context, id, actorID int64, req *ReverseReq) (*Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result := new(Transaction)
+
 	// Tx Transaction
-	err = uc.txManager.WithTx(ctx, func(tx *sql.Tx) error {
-		// Update From Account
-		err = uc.accUsecase.UpdateBalanceWithTx(ctx, tx, fromAcc.ID, -req.Amount)
+	err := uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		// Lock Original
+		original, err := uc.tranRepo.FindByIDForUpdateWithTx(ctx, tx, id)
 		if err != nil {
-			return fmt.Errorf("update from account failed: %w", err)
+			return err
 		}
 
-		// Update To Account
-		err = uc.accUsecase.UpdateBalanceWithTx(ctx, tx, toAcc.ID, req.Amount)
+		if original.Type == TypeReversal {
+			return errs.ErrTranCannotReverseReverse
+		}
+
+		remaining, err := original.Amount.Sub(original.ReversedAmount)
 		if err != nil {
-			return fmt.Errorf("update to account failed: %w", err)
+			return err
 		}
 
-		// Insert Transaction
-		result, err = uc.tranRepo.TransferWithTx(ctx, tx, &Transaction{
-			FromAccount: &req.FromAccount,
-			ToAccount:   &req.ToAccount,
-			Amount:      req.Amount,
+		if !remaining.IsPositive() {
+			return errs.ErrTranAlreadyReversed
+		}
+
+		amount := remaining
+		if req.Amount != "" {
+			amount, err = parseAmount(req.Amount, original.Currency)
+			if err != nil {
+				return err
+			}
+
+			if err = checkBalance(amount, remaining); err != nil {
+				return errs.ErrTranReversalExceeds
+			}
+		}
+
+		// Compensating Entries
+		entries, err := uc.ledgerUsecase.EntriesWithTx(ctx, tx, original.ID)
+		if err != nil {
+			return fmt.Errorf("find ledger entries failed: %w", err)
+		}
+
+		reversed := make([]*ledger.Entry, 0, len(entries))
+		for _, e := range entries {
+			part, err := e.Amount.MulFrac(amount.Minor(), original.Amount.Minor())
+			if err != nil {
+				return err
+			}
+			reversed = append(reversed, &ledger.Entry{AccountID: e.AccountID, Amount: part.Neg()})
+		}
+
+		// Lock Accounts: only the customer accounts of the original, the
+		// cash vault is not locked
+		ids := make([]int64, 0, 2)
+		for _, id := range []*int64{original.FromAccount, original.ToAccount} {
+			if id != nil {
+				ids = append(ids, *id)
+			}
+		}
+		accounts, err := uc.accUsecase.LockAccountsWithTx(ctx, tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		// Check Accounts: the money goes back from the account credited by
+		// the original, which must still hold it
+		for _, e := range reversed {
+			acc, ok := accounts[e.AccountID]
+			if !ok {
+				continue
+			}
+
+			if e.Amount.IsPositive() {
+				err = acc.CanReceive()
+			} else if err = acc.CanSend(); err == nil {
+				err = checkBalance(e.Amount.Neg(), acc.AvailableBalance)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// Insert Reversal
+		reason := req.Reason
+		result, err = uc.tranRepo.ReversalWithTx(ctx, tx, &Transaction{
+			FromAccount: original.ToAccount,
+			ToAccount:   original.FromAccount,
+			Amount:      amount,
+			ReversalOf:  &original.ID,
+			CreatedBy:   &actorID,
+			Reason:      &reason,
 		})
 		if err != nil {
 			return fmt.Errorf("insert transaction failed: %w", err)
 		}
 
-		return nil
+		// Post Ledger
+		err = uc.ledgerUsecase.PostWithTx(ctx, tx, result.ID, reversed)
+		if err != nil {
+			return fmt.Errorf("post ledger failed: %w", err)
+		}
+
+		return uc.tranRepo.AddReversedAmountWithTx(ctx, tx, original.ID, amount)
 	})
 	if err != nil {
 		return nil, err
@@ -178,9 +411,269 @@ func (uc *transactionUsecase) Transfer(ctx context.Context, req *TransferReq) (*
 	return result, nil
 }
 
-func (uc *transactionUsecase) Transactions(ctx context.Context, userID int64) ([]*Transaction, error) {
+func (uc *transactionUsecase) Transactions(ctx context.Context, userID int64, query *TransactionQuery) (*TransactionPage, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	return uc.tranRepo.Transactions(ctx, userID)
+	filter, err := newTransactionFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether another page exists
+	limit := filter.Limit
+	filter.Limit++
+
+	items, err := uc.tranRepo.Transactions(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next := encodeCursor(&Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		page.NextCursor = &next
+	}
+
+	if page.Items == nil {
+		page.Items = []*Transaction{}
+	}
+
+	return page, nil
+}
+
+// CaptureHold turns a hold into a real withdrawal, or a transfer when the
+// request names a destination account. Capturing less than the hold settles
+// it for that amount and frees the rest.
+func (uc *transactionUsecase) CaptureHold(ctx context.Context, holdID int64, req *CaptureReq) (*Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var creditID int64
+	if req.ToAccount != nil {
+		creditID = *req.ToAccount
+	} else {
+		hold, err := uc.accUsecase.GetHold(ctx, holdID)
+		if err != nil {
+			return nil, err
+		}
+
+		creditID, err = uc.cashVaultFor(ctx, hold.AccountID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := new(Transaction)
+
+	// Tx Transaction
+	err := uc.txManager.WithTxOptions(ctx, db.Serializable, func(tx *sql.Tx) error {
+		// Lock Hold
+		hold, err := uc.accUsecase.LockHoldWithTx(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if creditID == hold.AccountID {
+			return errs.ErrTranSameAccount
+		}
+
+		// Lock Accounts: a capture into the cash vault locks only the
+		// held account
+		ids := []int64{hold.AccountID}
+		if req.ToAccount != nil {
+			ids = append(ids, creditID)
+		}
+		accounts, err := uc.accUsecase.LockAccountsWithTx(ctx, tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		if err = accounts[hold.AccountID].CanSend(); err != nil {
+			return err
+		}
+
+		amount := hold.Amount
+		if req.Amount != "" {
+			amount, err = parseAmount(req.Amount, hold.Currency)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Insert Transaction
+		input := &Transaction{
+			FromAccount: &hold.AccountID,
+			Amount:      amount,
+		}
+
+		if req.ToAccount != nil {
+			toAcc := accounts[creditID]
+			if err = toAcc.CanReceive(); err != nil {
+				return err
+			}
+			if toAcc.Currency != hold.Currency {
+				return errs.ErrCurrencyMismatch
+			}
+
+			input.ToAccount = &toAcc.ID
+			result, err = uc.tranRepo.TransferWithTx(ctx, tx, input)
+		} else {
+			result, err = uc.tranRepo.WithdrawWithTx(ctx, tx, input)
+		}
+		if err != nil {
+			return fmt.Errorf("insert transaction failed: %w", err)
+		}
+
+		// Lift Hold
+		if err = uc.accUsecase.CaptureHoldWithTx(ctx, tx, hold, amount, result.ID); err != nil {
+			return err
+		}
+
+		// Post Ledger: Account -> Cash Vault or To Account
+		err = uc.ledgerUsecase.PostWithTx(ctx, tx, result.ID, []*ledger.Entry{
+			{AccountID: hold.AccountID, Amount: amount.Neg()},
+			{AccountID: creditID, Amount: amount},
+		})
+		if err != nil {
+			return fmt.Errorf("post ledger failed: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// parseAmount reads a requested amount in the account currency and requires
+// it to be positive.
+func parseAmount(d money.Decimal, currency string) (money.Money, error) {
+	amount, err := d.Parse(currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if !amount.IsPositive() {
+		return money.Money{}, errs.ErrAmountGreaterThanZero
+	}
+
+	return amount, nil
+}
+
+// CheckStepUp returns ErrStepUpRequired for an amount above the step-up
+// threshold unless the user has just verified a one-time code.
+func (uc *transactionUsecase) CheckStepUp(amount money.Money, verified bool) error {
+	if uc.stepUpThreshold == "" || verified {
+		return nil
+	}
+
+	limit, err := uc.stepUpThreshold.Parse(amount.Currency())
+	if err != nil {
+		return fmt.Errorf("parse step-up threshold failed: %w", err)
+	}
+
+	cmp, err := amount.Cmp(limit)
+	if err != nil {
+		return err
+	}
+	if cmp > 0 {
+		return errs.ErrStepUpRequired
+	}
+
+	return nil
+}
+
+func checkBalance(amount, balance money.Money) error {
+	cmp, err := amount.Cmp(balance)
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return errs.ErrAmountGreaterAccBalance
+	}
+
+	return nil
+}
+
+func newTransactionFilter(query *TransactionQuery) (*TransactionFilter, error) {
+	filter := &TransactionFilter{
+		AccountID: query.AccountID,
+		Type:      transactionType(query.Type),
+		From:      query.From,
+		To:        query.To,
+		Limit:     query.Limit,
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageLimit
+	}
+
+	currency := query.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	if query.MinAmount != "" {
+		min, err := query.MinAmount.Parse(currency)
+		if err != nil {
+			return nil, err
+		}
+		filter.MinAmount = &min
+	}
+
+	if query.MaxAmount != "" {
+		max, err := query.MaxAmount.Parse(currency)
+		if err != nil {
+			return nil, err
+		}
+		filter.MaxAmount = &max
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = cursor
+	}
+
+	return filter, nil
+}
+
+// Cursors are opaque to clients: base64 of "<created_at>|<id>".
+func encodeCursor(c *Cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	c := new(Cursor)
+
+	c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	c.ID, err = strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	return c, nil
 }
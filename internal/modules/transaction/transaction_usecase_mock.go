@@ -0,0 +1,72 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/stretchr/testify/mock"
+)
+
+type TransactionUsecaseMock struct {
+	mock.Mock
+}
+
+func NewTransactionUsecaseMock() *TransactionUsecaseMock {
+	return &TransactionUsecaseMock{}
+}
+
+func (m *TransactionUsecaseMock) Deposit(ctx context.Context, source *DepositSource, req *DepositReq) (*Transaction, error) {
+	args := m.Called(ctx, source, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) Withdraw(ctx context.Context, userID int64, req *WithdrawReq) (*Transaction, error) {
+	args := m.Called(ctx, userID, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) Transfer(ctx context.Context, userID int64, req *TransferReq) (*Transaction, error) {
+	args := m.Called(ctx, userID, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) TransferWithTx(ctx context.Context, tx *sql.Tx, userID int64, req *TransferReq) (*Transaction, error) {
+	args := m.Called(ctx, tx, userID, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) Reverse(ctx context.Context, id, actorID int64, req *ReverseReq) (*Transaction, error) {
+	args := m.Called(ctx, id, actorID, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) CaptureHold(ctx context.Context, holdID int64, req *CaptureReq) (*Transaction, error) {
+	args := m.Called(ctx, holdID, req)
+	return transactionOf(args)
+}
+
+func (m *TransactionUsecaseMock) Transactions(ctx context.Context, userID int64, query *TransactionQuery) (*TransactionPage, error) {
+	args := m.Called(ctx, userID, query)
+
+	res, ok := args.Get(0).(*TransactionPage)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *TransactionUsecaseMock) CheckStepUp(amount money.Money, verified bool) error {
+	args := m.Called(amount, verified)
+	return args.Error(0)
+}
+
+func transactionOf(args mock.Arguments) (*Transaction, error) {
+	res, ok := args.Get(0).(*Transaction)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
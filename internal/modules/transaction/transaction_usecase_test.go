@@ -3,115 +3,399 @@ package transaction
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/codepnw/simple-bank/internal/db"
 	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/ledger"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/money"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 type testCase struct {
-	name        string
-	method      string
-	req         any
-	mockSetup   func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock)
-	expected    any
-	expectedErr bool
+	name          string
+	method        string
+	req           any
+	mockSetup     func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock)
+	expected      any
+	expectedErr   bool
+	expectedErrIs error
 }
 
 func TestTransactionUsecaseAll(t *testing.T) {
 	var tests []*testCase
 
 	// Mock Account
-	account1 := &account.Account{ID: int64(1), Balance: 0}
-	account2 := &account.Account{ID: int64(2), Balance: 200}
+	thb := func(minor int64) money.Money { return money.FromMinor(minor, "THB") }
+	ownerID := int64(1)
+	account1 := &account.Account{ID: int64(1), UserID: ownerID, Balance: thb(0), AvailableBalance: thb(0), Currency: "THB", Status: account.StatusActive}
+	account2 := &account.Account{ID: int64(2), UserID: ownerID, Balance: thb(20000), AvailableBalance: thb(20000), Currency: "THB", Status: account.StatusActive}
+	account3 := &account.Account{ID: int64(3), UserID: ownerID, Balance: thb(20000), AvailableBalance: thb(1000), Currency: "THB", Status: account.StatusActive}
+	frozen := &account.Account{ID: int64(4), UserID: ownerID, Balance: thb(20000), AvailableBalance: thb(20000), Currency: "THB", Status: account.StatusFrozen}
+	foreign := &account.Account{ID: int64(5), UserID: int64(2), Balance: thb(20000), AvailableBalance: thb(20000), Currency: "THB", Status: account.StatusActive}
+	vaultID := int64(99)
+	locked := func(accs ...*account.Account) map[int64]*account.Account {
+		m := make(map[int64]*account.Account, len(accs))
+		for _, a := range accs {
+			m[a.ID] = a
+		}
+		return m
+	}
 	// Mock Request
-	depositReq := &DepositReq{ToAccount: account1.ID, Amount: float64(100)}
-	withdrawReq := &WithdrawReq{FromAccount: account2.ID, Amount: float64(50)}
-	transferReq := &TransferReq{FromAccount: account2.ID, ToAccount: account1.ID, Amount: float64(50)}
+	depositReq := &DepositReq{ToAccount: account1.ID, Amount: "100"}
+	withdrawReq := &WithdrawReq{FromAccount: account2.ID, Amount: "50.25"}
+	transferReq := &TransferReq{FromAccount: account2.ID, ToAccount: account1.ID, Amount: "50"}
 	// Mock Expected
-	depositExpected := &Transaction{ToAccount: &account1.ID, Amount: float64(100)}
-	withdrawExpected := &Transaction{FromAccount: &account2.ID, Amount: float64(150)}
-	transferExpected := &Transaction{FromAccount: &account2.ID, ToAccount: &account1.ID, Amount: float64(150)}
+	depositExpected := &Transaction{ToAccount: &account1.ID, Amount: thb(10000)}
+	withdrawExpected := &Transaction{FromAccount: &account2.ID, Amount: thb(15000)}
+	transferExpected := &Transaction{FromAccount: &account2.ID, ToAccount: &account1.ID, Amount: thb(15000)}
 
 	// Deposit Method
 	deposit := &testCase{
 		name:   "Deposit success",
 		method: "Deposit",
 		req:    depositReq,
-		mockSetup: func(accUsecase *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock) {
+		mockSetup: func(accUsecase *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
 			accUsecase.On("GetAccountByID", mock.Anything, account1.ID).Return(account1, nil)
-			accUsecase.On("UpdateBalanceWithTx", mock.Anything, mock.Anything, account1.ID, depositReq.Amount).Return(nil)
+			accUsecase.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account1.ID}).Return(locked(account1), nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			led.On("PostWithTx", mock.Anything, mock.Anything, mock.Anything, []*ledger.Entry{
+				{AccountID: vaultID, Amount: thb(-10000)},
+				{AccountID: account1.ID, Amount: thb(10000)},
+			}).Return(nil)
 
-			tranResponse := &Transaction{ToAccount: &account1.ID, Amount: float64(100)}
+			tranResponse := &Transaction{ToAccount: &account1.ID, Amount: thb(10000)}
 			tranRepo.On("DepositWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
 		},
 		expected: depositExpected,
 	}
 	tests = append(tests, deposit)
 
+	// Deposit In Another Currency
+	usd := &account.Account{ID: int64(6), UserID: ownerID, Balance: money.FromMinor(0, "USD"), AvailableBalance: money.FromMinor(0, "USD"), Currency: "USD", Status: account.StatusActive}
+	usdVault := &account.Account{ID: int64(98), Currency: "USD", Status: account.StatusActive}
+	depositUSD := &testCase{
+		name:   "Deposit uses the vault of the account currency",
+		method: "Deposit",
+		req:    &DepositReq{ToAccount: usd.ID, Amount: "25"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("GetAccountByID", mock.Anything, usd.ID).Return(usd, nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "USD").Return(usdVault.ID, nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{usd.ID}).Return(locked(usd), nil)
+			led.On("PostWithTx", mock.Anything, mock.Anything, mock.Anything, []*ledger.Entry{
+				{AccountID: usdVault.ID, Amount: money.FromMinor(-2500, "USD")},
+				{AccountID: usd.ID, Amount: money.FromMinor(2500, "USD")},
+			}).Return(nil)
+
+			tranResponse := &Transaction{ToAccount: &usd.ID, Amount: money.FromMinor(2500, "USD")}
+			tranRepo.On("DepositWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
+		},
+		expected: &Transaction{ToAccount: &usd.ID, Amount: money.FromMinor(2500, "USD")},
+	}
+	tests = append(tests, depositUSD)
+
 	// Withdraw Method
 	withdraw := &testCase{
 		name:   "Withdraw success",
 		method: "Withdraw",
 		req:    withdrawReq,
-		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock) {
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
 			acc.On("GetAccountByID", mock.Anything, account2.ID).Return(account2, nil)
-			acc.On("UpdateBalanceWithTx", mock.Anything, mock.Anything, account2.ID, -withdrawReq.Amount).Return(nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account2.ID}).Return(locked(account2), nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			led.On("PostWithTx", mock.Anything, mock.Anything, mock.Anything, []*ledger.Entry{
+				{AccountID: account2.ID, Amount: thb(-5025)},
+				{AccountID: vaultID, Amount: thb(5025)},
+			}).Return(nil)
 
-			tranResponse := &Transaction{FromAccount: &account2.ID, Amount: float64(150)}
+			tranResponse := &Transaction{FromAccount: &account2.ID, Amount: thb(15000)}
 			tranRepo.On("WithdrawWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
 		},
 		expected: withdrawExpected,
 	}
 	tests = append(tests, withdraw)
 
+	// Withdraw Held Funds
+	withdrawHeld := &testCase{
+		name:   "Withdraw more than available balance",
+		method: "Withdraw",
+		req:    &WithdrawReq{FromAccount: account3.ID, Amount: "50"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("GetAccountByID", mock.Anything, account3.ID).Return(account3, nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account3.ID}).Return(locked(account3), nil)
+		},
+		expectedErr: true,
+	}
+	tests = append(tests, withdrawHeld)
+
 	// Transfer Method
 	transfer := &testCase{
 		name:   "Transfer success",
 		method: "Transfer",
 		req:    transferReq,
-		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock) {
-			acc.On("GetAccountByID", mock.Anything, account1.ID).Return(account1, nil)
-			acc.On("GetAccountByID", mock.Anything, account2.ID).Return(account2, nil)
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account2.ID, account1.ID}).Return(locked(account1, account2), nil)
 
-			acc.On("UpdateBalanceWithTx", mock.Anything, mock.Anything, account1.ID, transferReq.Amount).Return(nil)
-			acc.On("UpdateBalanceWithTx", mock.Anything, mock.Anything, account2.ID, -transferReq.Amount).Return(nil)
+			led.On("PostWithTx", mock.Anything, mock.Anything, mock.Anything, []*ledger.Entry{
+				{AccountID: account2.ID, Amount: thb(-5000)},
+				{AccountID: account1.ID, Amount: thb(5000)},
+			}).Return(nil)
 
-			tranResponse := &Transaction{FromAccount: &account2.ID, ToAccount: &account1.ID, Amount: float64(150)}
+			tranResponse := &Transaction{FromAccount: &account2.ID, ToAccount: &account1.ID, Amount: thb(15000)}
 			tranRepo.On("TransferWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
 		},
 		expected: transferExpected,
 	}
 	tests = append(tests, transfer)
 
+	// Transfer From Frozen Account
+	transferFrozen := &testCase{
+		name:   "Transfer from frozen account",
+		method: "Transfer",
+		req:    &TransferReq{FromAccount: frozen.ID, ToAccount: account1.ID, Amount: "50"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{frozen.ID, account1.ID}).Return(locked(account1, frozen), nil)
+		},
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAccountCannotSend,
+	}
+	tests = append(tests, transferFrozen)
+
+	// Transfer Above Step-Up Threshold
+	transferStepUp := &testCase{
+		name:   "Transfer above step-up threshold without one-time code",
+		method: "Transfer",
+		req:    &TransferReq{FromAccount: account2.ID, ToAccount: account1.ID, Amount: "150"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account2.ID, account1.ID}).Return(locked(account1, account2), nil)
+		},
+		expectedErr:   true,
+		expectedErrIs: errs.ErrStepUpRequired,
+	}
+	tests = append(tests, transferStepUp)
+
+	// Teller Deposit Without Reference
+	tellerNoRef := &testCase{
+		name:          "Teller deposit without reference",
+		method:        "TellerDeposit",
+		req:           depositReq,
+		expectedErr:   true,
+		expectedErrIs: errs.ErrDepositReferenceNeeded,
+	}
+	tests = append(tests, tellerNoRef)
+
+	// Transfer From Account Of Another User
+	transferForeign := &testCase{
+		name:   "Transfer from account of another user",
+		method: "Transfer",
+		req:    &TransferReq{FromAccount: foreign.ID, ToAccount: account1.ID, Amount: "50"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{foreign.ID, account1.ID}).Return(locked(account1, foreign), nil)
+		},
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAccountNotOwner,
+	}
+	tests = append(tests, transferForeign)
+
+	// Deposit To Frozen Account
+	depositFrozen := &testCase{
+		name:   "Deposit to frozen account",
+		method: "Deposit",
+		req:    &DepositReq{ToAccount: frozen.ID, Amount: "50"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("GetAccountByID", mock.Anything, frozen.ID).Return(frozen, nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{frozen.ID}).Return(locked(frozen), nil)
+		},
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAccountCannotReceive,
+	}
+	tests = append(tests, depositFrozen)
+
+	// Reverse Method
+	reverseReq := &ReverseReq{Amount: "40", Reason: "duplicate deposit"}
+	reverseDeposit := func(acc *account.Account) func(*account.AccountUsecaseMock, *transactionRepositoryMock, *ledger.LedgerUsecaseMock) {
+		return func(accUsecase *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			original := &Transaction{ID: 10, ToAccount: &acc.ID, Amount: thb(10000), Currency: "THB", Type: TypeDeposit, ReversedAmount: thb(0)}
+			tranRepo.On("FindByIDForUpdateWithTx", mock.Anything, mock.Anything, int64(10)).Return(original, nil)
+
+			led.On("EntriesWithTx", mock.Anything, mock.Anything, int64(10)).Return([]*ledger.Entry{
+				{AccountID: vaultID, Amount: thb(-10000)},
+				{AccountID: acc.ID, Amount: thb(10000)},
+			}, nil)
+			accUsecase.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{acc.ID}).Return(locked(acc), nil)
+		}
+	}
+	reverse := &testCase{
+		name:   "Reverse partial success",
+		method: "Reverse",
+		req:    reverseReq,
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			reverseDeposit(account2)(acc, tranRepo, led)
+			led.On("PostWithTx", mock.Anything, mock.Anything, int64(11), []*ledger.Entry{
+				{AccountID: vaultID, Amount: thb(4000)},
+				{AccountID: account2.ID, Amount: thb(-4000)},
+			}).Return(nil)
+
+			tranResponse := &Transaction{ID: 11, FromAccount: &account2.ID, Amount: thb(4000), Type: TypeReversal}
+			tranRepo.On("ReversalWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
+			tranRepo.On("AddReversedAmountWithTx", mock.Anything, mock.Anything, int64(10), thb(4000)).Return(nil)
+		},
+		expected: &Transaction{ID: 11, FromAccount: &account2.ID, Amount: thb(4000), Type: TypeReversal},
+	}
+	tests = append(tests, reverse)
+
+	// Reverse Short Balance: account3 has 10.00 available
+	reverseShort := &testCase{
+		name:          "Reverse more than the available balance",
+		method:        "Reverse",
+		req:           reverseReq,
+		mockSetup:     reverseDeposit(account3),
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAmountGreaterAccBalance,
+	}
+	tests = append(tests, reverseShort)
+
+	// Reverse From Frozen Account
+	reverseFrozen := &testCase{
+		name:          "Reverse from a frozen account",
+		method:        "Reverse",
+		req:           reverseReq,
+		mockSetup:     reverseDeposit(frozen),
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAccountCannotSend,
+	}
+	tests = append(tests, reverseFrozen)
+
+	// Reverse To Frozen Account
+	reverseToFrozen := &testCase{
+		name:   "Reverse a transfer into a frozen account",
+		method: "Reverse",
+		req:    reverseReq,
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			original := &Transaction{ID: 10, FromAccount: &frozen.ID, ToAccount: &account2.ID, Amount: thb(10000), Currency: "THB", Type: TypeTransfer, ReversedAmount: thb(0)}
+			tranRepo.On("FindByIDForUpdateWithTx", mock.Anything, mock.Anything, int64(10)).Return(original, nil)
+
+			led.On("EntriesWithTx", mock.Anything, mock.Anything, int64(10)).Return([]*ledger.Entry{
+				{AccountID: frozen.ID, Amount: thb(-10000)},
+				{AccountID: account2.ID, Amount: thb(10000)},
+			}, nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{frozen.ID, account2.ID}).Return(locked(frozen, account2), nil)
+		},
+		expectedErr:   true,
+		expectedErrIs: errs.ErrAccountCannotReceive,
+	}
+	tests = append(tests, reverseToFrozen)
+
+	// Reverse Already Reversed
+	reverseDone := &testCase{
+		name:   "Reverse already reversed",
+		method: "Reverse",
+		req:    &ReverseReq{Reason: "again"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			original := &Transaction{ID: 10, ToAccount: &account1.ID, Amount: thb(10000), Currency: "THB", Type: TypeDeposit, ReversedAmount: thb(10000)}
+			tranRepo.On("FindByIDForUpdateWithTx", mock.Anything, mock.Anything, int64(10)).Return(original, nil)
+		},
+		expectedErr: true,
+	}
+	tests = append(tests, reverseDone)
+
+	// CaptureHold Method
+	hold := &account.Hold{ID: 5, AccountID: account2.ID, Amount: thb(10000), Currency: "THB", Status: account.HoldActive}
+	capture := &testCase{
+		name:   "CaptureHold partial success",
+		method: "CaptureHold",
+		req:    &CaptureReq{Amount: "60"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("GetHold", mock.Anything, hold.ID).Return(hold, nil)
+			acc.On("GetAccountByID", mock.Anything, account2.ID).Return(account2, nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			acc.On("LockHoldWithTx", mock.Anything, mock.Anything, hold.ID).Return(hold, nil)
+			acc.On("LockAccountsWithTx", mock.Anything, mock.Anything, []int64{account2.ID}).Return(locked(account2), nil)
+			acc.On("CaptureHoldWithTx", mock.Anything, mock.Anything, hold, thb(6000), int64(12)).Return(nil)
+			led.On("PostWithTx", mock.Anything, mock.Anything, int64(12), []*ledger.Entry{
+				{AccountID: account2.ID, Amount: thb(-6000)},
+				{AccountID: vaultID, Amount: thb(6000)},
+			}).Return(nil)
+
+			tranResponse := &Transaction{ID: 12, FromAccount: &account2.ID, Amount: thb(6000)}
+			tranRepo.On("WithdrawWithTx", mock.Anything, mock.Anything, mock.Anything).Return(tranResponse, nil)
+		},
+		expected: &Transaction{ID: 12, FromAccount: &account2.ID, Amount: thb(6000)},
+	}
+	tests = append(tests, capture)
+
+	// CaptureHold Not Active
+	captureReleased := &testCase{
+		name:   "CaptureHold not active",
+		method: "CaptureHold",
+		req:    &CaptureReq{},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			acc.On("GetHold", mock.Anything, hold.ID).Return(hold, nil)
+			acc.On("GetAccountByID", mock.Anything, account2.ID).Return(account2, nil)
+			led.On("SystemAccountID", mock.Anything, ledger.SystemCashVault, "THB").Return(vaultID, nil)
+			acc.On("LockHoldWithTx", mock.Anything, mock.Anything, hold.ID).Return(nil, errs.ErrHoldNotActive)
+		},
+		expectedErr: true,
+	}
+	tests = append(tests, captureReleased)
+
 	// Transactions Method
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
 	trans := &testCase{
 		name:   "Transactions success",
 		method: "Transactions",
-		req:    int64(1), // UserID
-		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock) {
-			tranRepo.On("Transactions", mock.Anything, int64(1)).Return([]*Transaction{
-				{ID: 1, Amount: 100},
-				{ID: 2, Amount: 200},
+		req:    &TransactionQuery{},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			tranRepo.On("Transactions", mock.Anything, int64(1), mock.Anything).Return([]*Transaction{
+				{ID: 1, Amount: thb(100)},
+				{ID: 2, Amount: thb(200)},
 			}, nil)
 		},
-		expected: []*Transaction{
-			{ID: 1, Amount: 100},
-			{ID: 2, Amount: 200},
-		},
+		expected: &TransactionPage{Items: []*Transaction{
+			{ID: 1, Amount: thb(100)},
+			{ID: 2, Amount: thb(200)},
+		}},
 	}
 	tests = append(tests, trans)
 
+	// Transactions Next Page
+	nextCursor := encodeCursor(&Cursor{CreatedAt: createdAt, ID: 2})
+	transPage := &testCase{
+		name:   "Transactions next page",
+		method: "Transactions",
+		req:    &TransactionQuery{Limit: 2, Type: "DEPOSIT"},
+		mockSetup: func(acc *account.AccountUsecaseMock, tranRepo *transactionRepositoryMock, led *ledger.LedgerUsecaseMock) {
+			filter := &TransactionFilter{Type: TypeDeposit, Limit: 3}
+			tranRepo.On("Transactions", mock.Anything, int64(1), filter).Return([]*Transaction{
+				{ID: 3, Amount: thb(300), CreatedAt: createdAt},
+				{ID: 2, Amount: thb(200), CreatedAt: createdAt},
+				{ID: 1, Amount: thb(100), CreatedAt: createdAt},
+			}, nil)
+		},
+		expected: &TransactionPage{
+			Items: []*Transaction{
+				{ID: 3, Amount: thb(300), CreatedAt: createdAt},
+				{ID: 2, Amount: thb(200), CreatedAt: createdAt},
+			},
+			NextCursor: &nextCursor,
+		},
+	}
+	tests = append(tests, transPage)
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tranRepo := NewtransactionRepositoryMockMock()
 			accUsecase := account.NewAccountUsecaseMock()
+			ledgerUsecase := ledger.NewLedgerUsecaseMock()
 			tx := db.TxMock{}
-			uc := NewTransactionUsecse(tranRepo, accUsecase, &tx)
+			uc := NewTransactionUsecse(tranRepo, accUsecase, ledgerUsecase, &tx, "100")
 
 			if tt.mockSetup != nil {
-				tt.mockSetup(accUsecase, tranRepo)
+				tt.mockSetup(accUsecase, tranRepo, ledgerUsecase)
 			}
 
 			var result any
@@ -119,23 +403,32 @@ func TestTransactionUsecaseAll(t *testing.T) {
 
 			switch tt.method {
 			case "Deposit":
-				result, err = uc.Deposit(context.Background(), tt.req.(*DepositReq))
+				result, err = uc.Deposit(context.Background(), &DepositSource{Channel: ChannelAPIKey}, tt.req.(*DepositReq))
+			case "TellerDeposit":
+				result, err = uc.Deposit(context.Background(), &DepositSource{Channel: ChannelTeller}, tt.req.(*DepositReq))
 			case "Withdraw":
-				result, err = uc.Withdraw(context.Background(), tt.req.(*WithdrawReq))
+				result, err = uc.Withdraw(context.Background(), ownerID, tt.req.(*WithdrawReq))
 			case "Transfer":
-				result, err = uc.Transfer(context.Background(), tt.req.(*TransferReq))
+				result, err = uc.Transfer(context.Background(), ownerID, tt.req.(*TransferReq))
+			case "Reverse":
+				result, err = uc.Reverse(context.Background(), int64(10), int64(7), tt.req.(*ReverseReq))
+			case "CaptureHold":
+				result, err = uc.CaptureHold(context.Background(), int64(5), tt.req.(*CaptureReq))
 			case "Transactions":
-				result, err = uc.Transactions(context.Background(), tt.req.(int64))
+				result, err = uc.Transactions(context.Background(), int64(1), tt.req.(*TransactionQuery))
 			}
 
 			if tt.expectedErr {
 				assert.Error(t, err)
+				if tt.expectedErrIs != nil {
+					assert.ErrorIs(t, err, tt.expectedErrIs)
+				}
 			} else {
 				assert.NoError(t, err)
 
 				switch tt.method {
 				case "Transactions":
-					assert.EqualValues(t, tt.expected.([]*Transaction), result)
+					assert.EqualValues(t, tt.expected.(*TransactionPage), result)
 				default:
 					assert.EqualValues(t, tt.expected.(*Transaction), result)
 				}
@@ -143,3 +436,14 @@ func TestTransactionUsecaseAll(t *testing.T) {
 		})
 	}
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := &Cursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), ID: 42}
+
+	decoded, err := decodeCursor(encodeCursor(c))
+	assert.NoError(t, err)
+	assert.Equal(t, c, decoded)
+
+	_, err = decodeCursor("not-a-cursor")
+	assert.Error(t, err)
+}
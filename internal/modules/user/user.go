@@ -2,6 +2,8 @@ package user
 
 import "time"
 
+// UserRole is the name of a row in the roles table. Beside the built-in
+// roles below, admins may create their own.
 type UserRole string
 
 var (
@@ -11,13 +13,18 @@ var (
 )
 
 type User struct {
-	ID        int64      `json:"id"`
-	Email     string     `json:"email"`
-	Password  string     `json:"-"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Phone     *string    `json:"phone"`
-	Role      UserRole   `json:"role"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID              int64      `json:"id"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Phone           *string    `json:"phone"`
+	Role            UserRole   `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
@@ -1,16 +1,28 @@
 package user
 
+// UserRequest creates a user. The password policy is checked by the
+// usecase; phone numbers are in E.164 format, e.g. +66812345678.
 type UserRequest struct {
-	Email     string  `json:"email"`
-	Password  string  `json:"-"`
-	FirstName string  `json:"first_name"`
-	LastName  string  `json:"last_name"`
-	Phone     *string `json:"phone"`
+	Email     string  `json:"email" validate:"required,email,max=50"`
+	Password  string  `json:"password" validate:"required"`
+	FirstName string  `json:"first_name" validate:"required,max=50"`
+	LastName  string  `json:"last_name" validate:"required,max=50"`
+	Phone     *string `json:"phone" validate:"omitempty,phone"`
 }
 
+// UserUpdateRequest changes the fields that are set. An empty phone
+// removes the phone number.
 type UserUpdateRequest struct {
-	FirstName *string `json:"first_name"`
-	LastName  *string `json:"last_name"`
-	Phone     *string `json:"phone"`
+	FirstName *string `json:"first_name" validate:"omitnil,required,max=50"`
+	LastName  *string `json:"last_name" validate:"omitnil,required,max=50"`
+	Phone     *string `json:"phone" validate:"omitempty,phone"`
+}
+
+type RoleRequest struct {
+	Role UserRole `json:"role" validate:"required,max=32"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
-	
\ No newline at end of file
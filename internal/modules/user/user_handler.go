@@ -4,16 +4,23 @@ import (
 	"errors"
 
 	"github.com/codepnw/simple-bank/internal/utils"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"github.com/codepnw/simple-bank/internal/utils/response"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 type userHandler struct {
-	uc UserUsecase
+	uc       UserUsecase
+	validate *validator.Validate
 }
 
 func NewUserHandler(uc UserUsecase) *userHandler {
-	return &userHandler{uc: uc}
+	return &userHandler{
+		uc:       uc,
+		validate: validation.New(),
+	}
 }
 
 func (h *userHandler) CreateUser(ctx *gin.Context) {
@@ -24,8 +31,17 @@ func (h *userHandler) CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
 	result, err := h.uc.Create(ctx, req)
 	if err != nil {
+		if errors.Is(err, errs.ErrPasswordWeak) {
+			response.ErrBadRequest(ctx, err)
+			return
+		}
 		response.ErrInternalServer(ctx, err)
 		return
 	}
@@ -37,6 +53,7 @@ func (h *userHandler) GetUser(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
+		return
 	}
 
 	result, err := h.uc.GetUserByID(ctx, id)
@@ -55,7 +72,13 @@ func (h *userHandler) GetProfile(ctx *gin.Context) {
 		return
 	}
 
-	response.Success(ctx, u)
+	result, err := h.uc.GetUserByID(ctx, u.ID)
+	if err != nil {
+		response.ErrInternalServer(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
 }
 
 func (h *userHandler) GetUsers(ctx *gin.Context) {
@@ -72,6 +95,7 @@ func (h *userHandler) UpdateUser(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
+		return
 	}
 
 	req := new(UserUpdateRequest)
@@ -81,6 +105,11 @@ func (h *userHandler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
 	result, err := h.uc.Update(ctx, id, req)
 	if err != nil {
 		response.ErrInternalServer(ctx, err)
@@ -104,6 +133,11 @@ func (h *userHandler) UpdateProfile(ctx *gin.Context) {
 		return
 	}
 
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
 	result, err := h.uc.Update(ctx, u.ID, req)
 	if err != nil {
 		response.ErrInternalServer(ctx, err)
@@ -113,10 +147,54 @@ func (h *userHandler) UpdateProfile(ctx *gin.Context) {
 	response.Success(ctx, result)
 }
 
+func (h *userHandler) UpdateRole(ctx *gin.Context) {
+	u, err := CurrentUser(ctx)
+	if err != nil {
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+
+	id, err := utils.GetParamID(ctx, "id")
+	if err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	req := new(RoleRequest)
+
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.ErrBadRequest(ctx, err)
+		return
+	}
+
+	result, err := h.uc.UpdateRole(ctx, u.ID, id, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrUserRoleInvalid):
+			response.ErrBadRequest(ctx, err)
+		case errors.Is(err, errs.ErrUserRoleSelf):
+			response.ErrConflict(ctx, err)
+		case errors.Is(err, errs.ErrUserNotFound):
+			response.ErrNotFound(ctx, err)
+		default:
+			response.ErrInternalServer(ctx, err)
+		}
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
 func (h *userHandler) DeleteUser(ctx *gin.Context) {
 	id, err := utils.GetParamID(ctx, "id")
 	if err != nil {
 		response.ErrBadRequest(ctx, err)
+		return
 	}
 
 	if err := h.uc.Delete(ctx, id); err != nil {
@@ -127,8 +205,10 @@ func (h *userHandler) DeleteUser(ctx *gin.Context) {
 	response.Success(ctx, "user deleted")
 }
 
+// CurrentUser returns the user of the request as set by the Authorized
+// middleware from the access token claims: ID, Email and Role only.
 func CurrentUser(ctx *gin.Context) (*User, error) {
-	val, ok := ctx.Get("user")
+	val, ok := ctx.Get(utils.ContextKeyUser)
 	if !ok {
 		return nil, errors.New("user not found in context")
 	}
@@ -3,8 +3,10 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/lib/pq"
 )
 
 type UserRepository interface {
@@ -13,6 +15,11 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	List(ctx context.Context) ([]*User, error)
 	Update(ctx context.Context, u *User) error
+	UpdateRole(ctx context.Context, id int64, role UserRole) (*User, error)
+	UpdatePassword(ctx context.Context, id int64, hash string, keep int) error
+	RehashPassword(ctx context.Context, id int64, oldHash, newHash string) error
+	PasswordHistory(ctx context.Context, id int64, limit int) ([]string, error)
+	MarkEmailVerified(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
 }
 
@@ -27,7 +34,7 @@ func NewUserRepository(db *sql.DB) UserRepository {
 func (r *userRepository) Create(ctx context.Context, u *User) (*User, error) {
 	query := `
 		INSERT INTO users (email, password, first_name, last_name, phone)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at;
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, role, created_at;
 	`
 	err := r.db.QueryRowContext(
 		ctx,
@@ -37,7 +44,7 @@ func (r *userRepository) Create(ctx context.Context, u *User) (*User, error) {
 		u.FirstName,
 		u.LastName,
 		u.Phone,
-	).Scan(&u.ID, &u.CreatedAt)
+	).Scan(&u.ID, &u.Role, &u.CreatedAt)
 
 	if err != nil {
 		return nil, err
@@ -48,7 +55,7 @@ func (r *userRepository) Create(ctx context.Context, u *User) (*User, error) {
 
 func (r *userRepository) FindByID(ctx context.Context, id int64) (*User, error) {
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, created_at, updated_at
+		SELECT id, email, password, first_name, last_name, phone, role, email_verified_at, created_at, updated_at
 		FROM users WHERE id = $1 LIMIT 1;
 	`
 	var u User
@@ -60,9 +67,14 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*User, error)
 		&u.FirstName,
 		&u.LastName,
 		&u.Phone,
+		&u.Role,
+		&u.EmailVerifiedAt,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +84,7 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*User, error)
 
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, created_at, updated_at
+		SELECT id, email, password, first_name, last_name, phone, role, email_verified_at, created_at, updated_at
 		FROM users WHERE email = $1 LIMIT 1;
 	`
 	var u User
@@ -84,9 +96,14 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*User,
 		&u.FirstName,
 		&u.LastName,
 		&u.Phone,
+		&u.Role,
+		&u.EmailVerifiedAt,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +113,7 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*User,
 
 func (r *userRepository) List(ctx context.Context) ([]*User, error) {
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, created_at, updated_at
+		SELECT id, email, password, first_name, last_name, phone, role, email_verified_at, created_at, updated_at
 		FROM users;
 	`
 	var users []*User
@@ -116,6 +133,8 @@ func (r *userRepository) List(ctx context.Context) ([]*User, error) {
 			&u.FirstName,
 			&u.LastName,
 			&u.Phone,
+			&u.Role,
+			&u.EmailVerifiedAt,
 			&u.CreatedAt,
 			&u.UpdatedAt,
 		)
@@ -154,6 +173,152 @@ func (r *userRepository) Update(ctx context.Context, u *User) error {
 	return nil
 }
 
+// UpdateRole also ends the sessions of the user: their access tokens carry
+// the old role, so tokens issued before the change are rejected and a
+// refresh picks up the new one.
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role UserRole) (*User, error) {
+	query := `
+		UPDATE users
+		SET role = $1, tokens_valid_after = date_trunc('second', NOW()), updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, email, password, first_name, last_name, phone, role, email_verified_at, created_at, updated_at
+	`
+	var u User
+
+	err := r.db.QueryRowContext(ctx, query, string(role), id).Scan(
+		&u.ID,
+		&u.Email,
+		&u.Password,
+		&u.FirstName,
+		&u.LastName,
+		&u.Phone,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrUserNotFound
+	}
+	// users.role references roles(name)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return nil, errs.ErrUserRoleInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// UpdatePassword sets a new password hash and moves the old one to the
+// password history, keeping the keep most recent hashes there.
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, hash string, keep int) error {
+	query := `
+		WITH old AS (
+			SELECT password FROM users WHERE id = $2
+		), saved AS (
+			INSERT INTO password_history (user_id, hash)
+			SELECT $2, password FROM old
+		)
+		UPDATE users SET password = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	res, err := r.db.ExecContext(ctx, query, hash, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+
+	query = `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)
+	`
+	_, err = r.db.ExecContext(ctx, query, id, keep)
+	return err
+}
+
+// RehashPassword replaces a hash of the same password, unless the password
+// was changed in the meantime. It is not added to the history.
+func (r *userRepository) RehashPassword(ctx context.Context, id int64, oldHash, newHash string) error {
+	query := `
+		UPDATE users SET password = $1
+		WHERE id = $2 AND password = $3
+	`
+	_, err := r.db.ExecContext(ctx, query, newHash, id, oldHash)
+	return err
+}
+
+// PasswordHistory returns the current password hash of a user and up to
+// limit earlier ones.
+func (r *userRepository) PasswordHistory(ctx context.Context, id int64, limit int) ([]string, error) {
+	query := `
+		SELECT password FROM users WHERE id = $1
+		UNION ALL
+		(SELECT hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2)
+	`
+	rows, err := r.db.QueryContext(ctx, query, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// MarkEmailVerified keeps the time of the first verification.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (r *userRepository) Delete(ctx context.Context, id int64) error {
 	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
 	if err != nil {
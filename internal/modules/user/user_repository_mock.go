@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type userRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *userRepositoryMock) Create(ctx context.Context, u *User) (*User, error) {
+	args := m.Called(ctx, u)
+
+	res, ok := args.Get(0).(*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) FindByID(ctx context.Context, id int64) (*User, error) {
+	args := m.Called(ctx, id)
+
+	res, ok := args.Get(0).(*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) FindByEmail(ctx context.Context, email string) (*User, error) {
+	args := m.Called(ctx, email)
+
+	res, ok := args.Get(0).(*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) List(ctx context.Context) ([]*User, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).([]*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) Update(ctx context.Context, u *User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *userRepositoryMock) UpdateRole(ctx context.Context, id int64, role UserRole) (*User, error) {
+	args := m.Called(ctx, id, role)
+
+	res, ok := args.Get(0).(*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) UpdatePassword(ctx context.Context, id int64, hash string, keep int) error {
+	args := m.Called(ctx, id, hash, keep)
+	return args.Error(0)
+}
+
+func (m *userRepositoryMock) RehashPassword(ctx context.Context, id int64, oldHash, newHash string) error {
+	args := m.Called(ctx, id, oldHash, newHash)
+	return args.Error(0)
+}
+
+func (m *userRepositoryMock) PasswordHistory(ctx context.Context, id int64, limit int) ([]string, error) {
+	args := m.Called(ctx, id, limit)
+
+	res, ok := args.Get(0).([]string)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *userRepositoryMock) MarkEmailVerified(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *userRepositoryMock) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
@@ -2,8 +2,12 @@ package user
 
 import (
 	"context"
+	"errors"
+	"log"
 	"time"
 
+	"github.com/codepnw/simple-bank/config"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"github.com/codepnw/simple-bank/internal/utils/security"
 )
 
@@ -15,22 +19,37 @@ type UserUsecase interface {
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUsers(ctx context.Context) ([]*User, error)
 	Update(ctx context.Context, id int64, req *UserUpdateRequest) (*User, error)
+	UpdateRole(ctx context.Context, actorID, id int64, role UserRole) (*User, error)
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	ChangePassword(ctx context.Context, id int64, req *PasswordChangeRequest) error
+	Authenticate(ctx context.Context, email, password string) (*User, error)
+	VerifyEmail(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
 }
 
 type userUsecase struct {
-	repo UserRepository
+	repo        UserRepository
+	passwords   *security.PasswordPolicy
+	historySize int
 }
 
-func NewUserUsecase(repo UserRepository) UserUsecase {
-	return &userUsecase{repo: repo}
+func NewUserUsecase(cfg *config.EnvConfig, repo UserRepository, passwords *security.PasswordPolicy) UserUsecase {
+	return &userUsecase{
+		repo:        repo,
+		passwords:   passwords,
+		historySize: cfg.Password.HistorySize,
+	}
 }
 
 func (uc *userUsecase) Create(ctx context.Context, req *UserRequest) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	hashPassword, err := security.HashPassword(req.Password)
+	if err := uc.passwords.Check(req.Password); err != nil {
+		return nil, err
+	}
+
+	hashPassword, err := uc.passwords.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +59,7 @@ func (uc *userUsecase) Create(ctx context.Context, req *UserRequest) (*User, err
 		Password:  hashPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Phone:     req.Phone,
+		Phone:     phoneOrNil(req.Phone),
 	}
 
 	created, err := uc.repo.Create(ctx, user)
@@ -89,8 +108,9 @@ func (uc *userUsecase) Update(ctx context.Context, id int64, req *UserUpdateRequ
 		user.LastName = *req.LastName
 	}
 
+	// An empty phone removes the phone number.
 	if req.Phone != nil {
-		user.Phone = req.Phone
+		user.Phone = phoneOrNil(req.Phone)
 	}
 
 	now := time.Now()
@@ -103,9 +123,124 @@ func (uc *userUsecase) Update(ctx context.Context, id int64, req *UserUpdateRequ
 	return user, nil
 }
 
+// UpdateRole changes the role of user id on behalf of actorID. Admins
+// cannot change their own role, so the last admin cannot lock everyone out.
+func (uc *userUsecase) UpdateRole(ctx context.Context, actorID, id int64, role UserRole) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if role == "" {
+		return nil, errs.ErrUserRoleInvalid
+	}
+
+	if actorID == id {
+		return nil, errs.ErrUserRoleSelf
+	}
+
+	return uc.repo.UpdateRole(ctx, id, role)
+}
+
+// UpdatePassword sets a new password that meets the password policy and is
+// none of the recent passwords of the user.
+func (uc *userUsecase) UpdatePassword(ctx context.Context, id int64, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := uc.passwords.Check(password); err != nil {
+		return err
+	}
+
+	// The current password counts as one of the recent ones.
+	history, err := uc.repo.PasswordHistory(ctx, id, uc.historySize-1)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range history {
+		if security.ComparePassword(hash, password) == nil {
+			return errs.ErrPasswordReused
+		}
+	}
+
+	hashPassword, err := uc.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.UpdatePassword(ctx, id, hashPassword, uc.historySize-1)
+}
+
+// ChangePassword sets a new password after checking the current one.
+func (uc *userUsecase) ChangePassword(ctx context.Context, id int64, req *PasswordChangeRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	user, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = security.ComparePassword(user.Password, req.CurrentPassword); err != nil {
+		return errs.ErrPasswordIncorrect
+	}
+
+	return uc.UpdatePassword(ctx, id, req.NewPassword)
+}
+
+// Authenticate returns the user with an email address and password.
+// Unknown addresses and wrong passwords fail alike with
+// ErrInvalidCredentials and take as long. A hash weaker than the password
+// policy asks for is replaced while the password is at hand.
+func (uc *userUsecase) Authenticate(ctx context.Context, email, password string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	user, err := uc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil, uc.passwords.CompareDummy(password)
+		}
+		return nil, err
+	}
+
+	if err = security.ComparePassword(user.Password, password); err != nil {
+		return nil, errs.ErrInvalidCredentials
+	}
+
+	if uc.passwords.NeedsRehash(user.Password) {
+		// The login goes on with the old hash if this fails.
+		hash, err := uc.passwords.Hash(password)
+		if err == nil {
+			err = uc.repo.RehashPassword(ctx, user.ID, user.Password, hash)
+		}
+		if err != nil {
+			log.Printf("rehash password of user %d failed: %v", user.ID, err)
+		} else {
+			user.Password = hash
+		}
+	}
+
+	return user, nil
+}
+
+func (uc *userUsecase) VerifyEmail(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return uc.repo.MarkEmailVerified(ctx, id)
+}
+
 func (uc *userUsecase) Delete(ctx context.Context, id int64) error {
 	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	return uc.repo.Delete(ctx, id)
 }
+
+// phoneOrNil stores an empty phone number as no phone number.
+func phoneOrNil(phone *string) *string {
+	if phone == nil || *phone == "" {
+		return nil
+	}
+	return phone
+}
@@ -0,0 +1,85 @@
+package user
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type UserUsecaseMock struct {
+	mock.Mock
+}
+
+func NewUserUsecaseMock() *UserUsecaseMock {
+	return &UserUsecaseMock{}
+}
+
+func (m *UserUsecaseMock) Create(ctx context.Context, req *UserRequest) (*User, error) {
+	args := m.Called(ctx, req)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) GetUserByID(ctx context.Context, id int64) (*User, error) {
+	args := m.Called(ctx, id)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	args := m.Called(ctx, email)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) GetUsers(ctx context.Context) ([]*User, error) {
+	args := m.Called(ctx)
+
+	res, ok := args.Get(0).([]*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
+
+func (m *UserUsecaseMock) Update(ctx context.Context, id int64, req *UserUpdateRequest) (*User, error) {
+	args := m.Called(ctx, id, req)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) UpdateRole(ctx context.Context, actorID, id int64, role UserRole) (*User, error) {
+	args := m.Called(ctx, actorID, id, role)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) UpdatePassword(ctx context.Context, id int64, password string) error {
+	args := m.Called(ctx, id, password)
+	return args.Error(0)
+}
+
+func (m *UserUsecaseMock) ChangePassword(ctx context.Context, id int64, req *PasswordChangeRequest) error {
+	args := m.Called(ctx, id, req)
+	return args.Error(0)
+}
+
+func (m *UserUsecaseMock) Authenticate(ctx context.Context, email, password string) (*User, error) {
+	args := m.Called(ctx, email, password)
+	return m.user(args)
+}
+
+func (m *UserUsecaseMock) VerifyEmail(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *UserUsecaseMock) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *UserUsecaseMock) user(args mock.Arguments) (*User, error) {
+	res, ok := args.Get(0).(*User)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return res, args.Error(1)
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codepnw/simple-bank/config"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testUsecase(t *testing.T, repo UserRepository, cost int) *userUsecase {
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	cfg, err := config.LoadEnvConfig(file)
+	assert.NoError(t, err)
+	cfg.Password.CommonListFile = ""
+	cfg.Password.BcryptCost = cost
+
+	passwords, err := security.NewPasswordPolicy(cfg)
+	assert.NoError(t, err)
+
+	return NewUserUsecase(cfg, repo, passwords).(*userUsecase)
+}
+
+func hashOf(t *testing.T, password string, cost int) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	assert.NoError(t, err)
+	return string(hash)
+}
+
+func TestChangePassword(t *testing.T) {
+	const (
+		current  = "Current-password-1"
+		previous = "Previous-password-1"
+		fresh    = "Brand-new-password-1"
+	)
+	cost := bcrypt.MinCost
+	history := []string{hashOf(t, current, cost), hashOf(t, previous, cost)}
+
+	tests := []struct {
+		name        string
+		req         *PasswordChangeRequest
+		expectedErr error
+	}{
+		{name: "success", req: &PasswordChangeRequest{CurrentPassword: current, NewPassword: fresh}},
+		{name: "wrong current", req: &PasswordChangeRequest{CurrentPassword: "guess", NewPassword: fresh}, expectedErr: errs.ErrPasswordIncorrect},
+		{name: "weak", req: &PasswordChangeRequest{CurrentPassword: current, NewPassword: "short"}, expectedErr: errs.ErrPasswordWeak},
+		{name: "same as current", req: &PasswordChangeRequest{CurrentPassword: current, NewPassword: current}, expectedErr: errs.ErrPasswordReused},
+		{name: "recently used", req: &PasswordChangeRequest{CurrentPassword: current, NewPassword: previous}, expectedErr: errs.ErrPasswordReused},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(userRepositoryMock)
+			uc := testUsecase(t, repo, cost)
+
+			repo.On("FindByID", mock.Anything, int64(1)).Return(&User{ID: 1, Password: history[0]}, nil)
+			if tc.expectedErr != errs.ErrPasswordIncorrect && tc.expectedErr != errs.ErrPasswordWeak {
+				repo.On("PasswordHistory", mock.Anything, int64(1), uc.historySize-1).Return(history, nil)
+			}
+			if tc.expectedErr == nil {
+				repo.On("UpdatePassword", mock.Anything, int64(1), mock.MatchedBy(func(hash string) bool {
+					return security.ComparePassword(hash, fresh) == nil
+				}), uc.historySize-1).Return(nil)
+			}
+
+			err := uc.ChangePassword(context.Background(), 1, tc.req)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticateRehashesWeakHash(t *testing.T) {
+	const password = "Current-password-1"
+	old := hashOf(t, password, bcrypt.MinCost)
+
+	repo := new(userRepositoryMock)
+	uc := testUsecase(t, repo, bcrypt.MinCost+1)
+
+	repo.On("FindByEmail", mock.Anything, "a@example.com").Return(&User{ID: 1, Password: old}, nil)
+	repo.On("RehashPassword", mock.Anything, int64(1), old, mock.MatchedBy(func(hash string) bool {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost == bcrypt.MinCost+1 && security.ComparePassword(hash, password) == nil
+	})).Return(nil)
+
+	u, err := uc.Authenticate(context.Background(), "a@example.com", password)
+	assert.NoError(t, err)
+	assert.NotEqual(t, old, u.Password)
+
+	_, err = uc.Authenticate(context.Background(), "a@example.com", "wrong")
+	assert.ErrorIs(t, err, errs.ErrInvalidCredentials)
+
+	repo.AssertExpectations(t)
+}
+
+func TestUpdatePhone(t *testing.T) {
+	old := "+66812345678"
+	changed := "+66898765432"
+	empty := ""
+
+	tests := []struct {
+		name     string
+		phone    *string
+		expected *string
+	}{
+		{name: "phone left as is", phone: nil, expected: &old},
+		{name: "phone changed", phone: &changed, expected: &changed},
+		{name: "empty phone removes it", phone: &empty, expected: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			phone := old
+			repo := new(userRepositoryMock)
+			repo.On("FindByID", mock.Anything, int64(1)).Return(&User{ID: 1, Phone: &phone}, nil)
+			repo.On("Update", mock.Anything, mock.MatchedBy(func(u *User) bool {
+				return assert.ObjectsAreEqual(tc.expected, u.Phone)
+			})).Return(nil)
+
+			uc := testUsecase(t, repo, bcrypt.MinCost)
+			u, err := uc.Update(context.Background(), 1, &UserUpdateRequest{Phone: tc.phone})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, u.Phone)
+			repo.AssertExpectations(t)
+		})
+	}
+}
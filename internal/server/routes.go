@@ -2,122 +2,359 @@ package server
 
 import (
 	"database/sql"
+	"expvar"
 
 	"github.com/codepnw/simple-bank/config"
 	"github.com/codepnw/simple-bank/internal/db"
 	"github.com/codepnw/simple-bank/internal/middleware"
 	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/modules/apikey"
 	"github.com/codepnw/simple-bank/internal/modules/auth"
+	"github.com/codepnw/simple-bank/internal/modules/channel"
+	"github.com/codepnw/simple-bank/internal/modules/idempotency"
+	"github.com/codepnw/simple-bank/internal/modules/ledger"
+	"github.com/codepnw/simple-bank/internal/modules/role"
+	"github.com/codepnw/simple-bank/internal/modules/standingorder"
+	"github.com/codepnw/simple-bank/internal/modules/statement"
 	"github.com/codepnw/simple-bank/internal/modules/transaction"
 	"github.com/codepnw/simple-bank/internal/modules/user"
+	"github.com/codepnw/simple-bank/internal/utils/mailer"
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/codepnw/simple-bank/internal/utils/security"
 	"github.com/gin-gonic/gin"
 )
 
 type routeConfig struct {
-	router *gin.Engine
-	db     *sql.DB
-	tx     db.TxManager
-	cfg    *config.EnvConfig
-	mid    middleware.Auth
+	router    *gin.Engine
+	db        *sql.DB
+	tx        db.TxManager
+	cfg       *config.EnvConfig
+	mid       middleware.Auth
+	mail      mailer.Mailer
+	auth      auth.AuthUsecase
+	role      role.RoleUsecase
+	token     *security.Token
+	passwords *security.PasswordPolicy
+	apiKey    apikey.APIKeyUsecase
 }
 
 func setupRoutes(params *routeConfig) *routeConfig {
+	userRepo := user.NewUserRepository(params.db)
+	userUsecase := user.NewUserUsecase(params.cfg, userRepo, params.passwords)
+
+	authRepo := auth.NewAuthRepository(params.db)
+	authUsecase := auth.NewAuthUsecase(params.cfg, params.token, authRepo, userUsecase, params.mail)
+
+	roleRepo := role.NewRoleRepository(params.db)
+	roleUsecase := role.NewRoleUsecase(roleRepo)
+
+	apiKeyRepo := apikey.NewAPIKeyRepository(params.db)
+	apiKeyUsecase := apikey.NewAPIKeyUsecase(params.cfg, params.token, apiKeyRepo, userUsecase)
+
 	return &routeConfig{
-		router: params.router,
-		db:     params.db,
-		tx:     params.tx,
-		cfg:    params.cfg,
-		mid:    middleware.AuthMiddleware(params.cfg),
+		router:    params.router,
+		db:        params.db,
+		tx:        params.tx,
+		cfg:       params.cfg,
+		mid:       middleware.AuthMiddleware(params.token, authUsecase, roleUsecase, apiKeyUsecase),
+		mail:      params.mail,
+		auth:      authUsecase,
+		role:      roleUsecase,
+		token:     params.token,
+		passwords: params.passwords,
+		apiKey:    apiKeyUsecase,
 	}
 }
 
 // Route: Auth
 func (r *routeConfig) authRoutes() {
-	userRepo := user.NewUserRepository(r.db)
-	userUsecase := user.NewUserUsecase(userRepo)
+	authHandler := auth.NewAuthHandler(r.auth)
 
-	authUsecase := auth.NewAuthUsecase(r.cfg, userUsecase)
-	authHandler := auth.NewAuthHandler(authUsecase)
+	r.router.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	// Public
 	public := r.router.Group("/auth")
 	{
 		public.POST("/register", authHandler.Register)
 		public.POST("/login", authHandler.Login)
+		public.POST("/login/totp", authHandler.LoginTOTP)
+		public.POST("/refresh", authHandler.Refresh)
+		public.POST("/password/forgot", authHandler.ForgotPassword)
+		public.POST("/password/reset", authHandler.ResetPassword)
+		public.POST("/email/verify", authHandler.VerifyEmail)
+	}
+
+	// Authorized: not with API keys or clients
+	authorized := r.router.Group("/auth", r.mid.Authorized(), r.mid.UserSession())
+	{
+		authorized.POST("/logout", authHandler.Logout)
+		authorized.POST("/email/verify/resend", authHandler.ResendVerification)
+		authorized.POST("/totp/enroll", authHandler.EnrollTOTP)
+		authorized.POST("/totp/confirm", authHandler.ConfirmTOTP)
+		authorized.POST("/totp/disable", authHandler.DisableTOTP)
+		authorized.POST("/totp/recovery-codes", authHandler.RegenerateRecoveryCodes)
 	}
 }
 
 // Route: Users
 func (r *routeConfig) userRoutes() {
 	userRepo := user.NewUserRepository(r.db)
-	userUsecase := user.NewUserUsecase(userRepo)
+	userUsecase := user.NewUserUsecase(r.cfg, userRepo, r.passwords)
 	userHandler := user.NewUserHandler(userUsecase)
+	roleHandler := role.NewRoleHandler(r.role)
+	authHandler := auth.NewAuthHandler(r.auth)
 
 	// Group: All Role
 	authorized := r.router.Group("/users/profile", r.mid.Authorized())
 	{
 		authorized.GET("/", userHandler.GetProfile)
-		authorized.PATCH("/", userHandler.UpdateProfile)
+		authorized.PATCH("/", r.mid.UserSession(), userHandler.UpdateProfile)
+		authorized.PATCH("/password", r.mid.UserSession(), authHandler.ChangePassword)
+		authorized.GET("/permissions", roleHandler.MyPermissions)
+		authorized.GET("/sessions", authHandler.ListSessions)
+		authorized.DELETE("/sessions/:id", r.mid.UserSession(), authHandler.EndSession)
+	}
+
+	// Group: Permission users:read_pii
+	read := r.router.Group("/users", r.mid.Authorized(string(role.PermUsersReadPII)), r.mid.Permissions(role.PermUsersReadPII))
+	{
+		read.GET("/", userHandler.GetUsers)
+		read.GET("/:id", userHandler.GetUser)
+	}
+
+	// Group: Permission users:manage
+	manage := r.router.Group("/users", r.mid.Authorized(string(role.PermUsersManage)), r.mid.Permissions(role.PermUsersManage))
+	{
+		manage.POST("/", userHandler.CreateUser)
+		manage.DELETE("/:id", userHandler.DeleteUser)
+		manage.POST("/:id/unlock", authHandler.UnlockUser)
+		manage.GET("/:id/sessions", authHandler.ListUserSessions)
+		manage.DELETE("/:id/sessions/:sessionID", authHandler.EndUserSession)
+	}
+
+	// Group: Permission roles:manage
+	roles := r.router.Group("/users", r.mid.Authorized(string(role.PermRolesManage)), r.mid.Permissions(role.PermRolesManage))
+	{
+		roles.PATCH("/:id/role", userHandler.UpdateRole)
+	}
+}
+
+// Route: API Keys & OAuth2 Clients
+func (r *routeConfig) apiKeyRoutes() {
+	apiKeyHandler := apikey.NewAPIKeyHandler(r.apiKey)
+
+	// Public
+	r.router.POST("/oauth/token", apiKeyHandler.Token)
+
+	// Own keys: not with API keys or clients
+	authorized := r.router.Group("/api-keys", r.mid.Authorized(), r.mid.UserSession())
+	{
+		authorized.POST("/", apiKeyHandler.CreateKey)
+		authorized.GET("/", apiKeyHandler.ListKeys)
+		authorized.DELETE("/:keyID", apiKeyHandler.RevokeKey)
 	}
 
-	// Group: Admin Role
-	permission := r.router.Group("/users", r.mid.Authorized(), r.mid.Permissions(user.RoleAdmin))
+	// Group: Permission clients:manage
+	permission := r.router.Group("", r.mid.Authorized(), r.mid.UserSession(), r.mid.Permissions(role.PermClientsManage))
 	{
-		permission.POST("/", userHandler.CreateUser)
-		permission.GET("/", userHandler.GetUsers)
-		permission.GET("/:id", userHandler.GetUser)
-		permission.DELETE("/:id", userHandler.DeleteUser)
+		permission.GET("/users/:id/api-keys", apiKeyHandler.ListUserKeys)
+		permission.DELETE("/users/:id/api-keys/:keyID", apiKeyHandler.RevokeUserKey)
+		permission.POST("/oauth/clients", apiKeyHandler.CreateClient)
+		permission.GET("/oauth/clients", apiKeyHandler.ListClients)
+		permission.DELETE("/oauth/clients/:id", apiKeyHandler.RevokeClient)
+	}
+}
+
+// Route: Roles
+func (r *routeConfig) roleRoutes() {
+	roleHandler := role.NewRoleHandler(r.role)
+
+	// Group: Permission roles:manage
+	permission := r.router.Group("/roles", r.mid.Authorized(string(role.PermRolesManage)), r.mid.Permissions(role.PermRolesManage))
+	{
+		permission.GET("/", roleHandler.ListRoles)
+		permission.GET("/:name", roleHandler.GetRole)
+		permission.PUT("/:name", roleHandler.SaveRole)
 	}
 }
 
 // Route: Accounts
 func (r *routeConfig) accountRoutes() {
 	accRepo := account.NewAccountRepository(r.db)
-	accUsecase := account.NewAccountUsecse(accRepo)
+	accUsecase := account.NewAccountUsecse(accRepo, r.cfg.Account.HoldExpiry)
 	accHandler := account.NewAccountHandler(accUsecase)
 
+	userRepo := user.NewUserRepository(r.db)
+	userUsecase := user.NewUserUsecase(r.cfg, userRepo, r.passwords)
+
 	authorized := r.router.Group("/accounts", r.mid.Authorized())
 	{
-		authorized.POST("/", accHandler.CreateAccount)
+		authorized.POST("/", middleware.VerifiedEmail(userUsecase), accHandler.CreateAccount)
 		authorized.GET("/user/:userID", accHandler.ListAccounts)
 		authorized.GET("/:id", accHandler.GetAccountByID)
 	}
 
-	// Group: Staff, Admin
-	permission := r.router.Group("/accounts", r.mid.Authorized(), r.mid.Permissions(user.RoleStaff, user.RoleAdmin))
+	// Group: Permissions
+	permission := r.router.Group("/accounts", r.mid.Authorized(string(role.PermAccountsApprove), string(role.PermHoldsManage)))
 	{
-		permission.GET("/:id/pending", accHandler.UpdateStatusPending)
-		permission.GET("/:id/approved", accHandler.UpdateStatusApproved)
-		permission.GET("/:id/rejected", accHandler.UpdateStatusRejected)
+		permission.PATCH("/:id/status", r.mid.Permissions(role.PermAccountsApprove), accHandler.UpdateStatus)
+		permission.GET("/:id/holds", r.mid.Permissions(role.PermHoldsManage), accHandler.ListHolds)
 	}
 }
 
 // Route: Transactions
 func (r *routeConfig) transactionRoutes() {
 	accRepo := account.NewAccountRepository(r.db)
-	accUsecase := account.NewAccountUsecse(accRepo)
+	accUsecase := account.NewAccountUsecse(accRepo, r.cfg.Account.HoldExpiry)
+
+	ledgerRepo := ledger.NewLedgerRepository(r.db)
+	ledgerUsecase := ledger.NewLedgerUsecase(ledgerRepo, accUsecase)
 
 	tranRepo := transaction.NewTransactionRepository(r.db)
-	tranUsecase := transaction.NewTransactionUsecse(tranRepo, accUsecase, r.tx)
+	tranUsecase := transaction.NewTransactionUsecse(tranRepo, accUsecase, ledgerUsecase, r.tx, money.Decimal(r.cfg.MFA.StepUpThreshold))
 	tranHandler := transaction.NewTransactionHandler(tranUsecase)
 
-	// Public
-	public := r.router.Group("/transactions")
+	idemRepo := idempotency.NewIdempotencyRepository(r.db)
+	idemUsecase := idempotency.NewIdempotencyUsecase(idemRepo)
+	idempotent := middleware.Idempotency(idemUsecase)
+
+	chRepo := channel.NewChannelRepository(r.db)
+	chUsecase := channel.NewChannelUsecase(chRepo)
+
+	// Deposit Channels: API key or HMAC signature
+	machine := r.router.Group("/transactions", middleware.DepositChannel(chUsecase))
+	{
+		machine.POST("/deposit/channel", idempotent, tranHandler.ChannelDeposit)
+	}
+
+	// Authorized: API keys and clients need write or the route scope
+	authorized := r.router.Group("/transactions")
+	{
+		authorized.POST("/withdraw", r.mid.Authorized(security.ScopeWithdraw), idempotent, tranHandler.Withdraw)
+		authorized.POST("/transfer", r.mid.Authorized(security.ScopeTransfer), idempotent, middleware.StepUp(r.auth), tranHandler.Transfer)
+		authorized.GET("/", r.mid.Authorized(), tranHandler.TransactionsByCurrentUser)
+	}
+
+	// Group: Permissions
+	permission := r.router.Group("/transactions", r.mid.Authorized(
+		string(role.PermTransactionsDeposit),
+		string(role.PermTransactionsReadAll),
+		string(role.PermTransactionsReverse),
+	))
+	{
+		permission.POST("/deposit", r.mid.Permissions(role.PermTransactionsDeposit), idempotent, tranHandler.TellerDeposit)
+		permission.GET("/user/:id", r.mid.Permissions(role.PermTransactionsReadAll), tranHandler.TransactionsByUserID)
+		permission.POST("/:id/reverse", r.mid.Permissions(role.PermTransactionsReverse), idempotent, tranHandler.Reverse)
+	}
+}
+
+// Route: Deposit Channels
+func (r *routeConfig) channelRoutes() {
+	chRepo := channel.NewChannelRepository(r.db)
+	chUsecase := channel.NewChannelUsecase(chRepo)
+	chHandler := channel.NewChannelHandler(chUsecase)
+
+	// Group: Permission channels:manage
+	permission := r.router.Group("/channels", r.mid.Authorized(string(role.PermChannelsManage)), r.mid.Permissions(role.PermChannelsManage))
+	{
+		permission.POST("/", chHandler.CreateChannel)
+		permission.GET("/", chHandler.ListChannels)
+		permission.DELETE("/:id", chHandler.RevokeChannel)
+	}
+}
+
+// Route: Holds
+func (r *routeConfig) holdRoutes() {
+	accRepo := account.NewAccountRepository(r.db)
+	accUsecase := account.NewAccountUsecse(accRepo, r.cfg.Account.HoldExpiry)
+	accHandler := account.NewAccountHandler(accUsecase)
+
+	ledgerRepo := ledger.NewLedgerRepository(r.db)
+	ledgerUsecase := ledger.NewLedgerUsecase(ledgerRepo, accUsecase)
+
+	tranRepo := transaction.NewTransactionRepository(r.db)
+	tranUsecase := transaction.NewTransactionUsecse(tranRepo, accUsecase, ledgerUsecase, r.tx, money.Decimal(r.cfg.MFA.StepUpThreshold))
+	tranHandler := transaction.NewTransactionHandler(tranUsecase)
+
+	idemRepo := idempotency.NewIdempotencyRepository(r.db)
+	idemUsecase := idempotency.NewIdempotencyUsecase(idemRepo)
+	idempotent := middleware.Idempotency(idemUsecase)
+
+	// Group: Permission holds:manage
+	permission := r.router.Group("/holds", r.mid.Authorized(string(role.PermHoldsManage)), r.mid.Permissions(role.PermHoldsManage))
+	{
+		permission.POST("/", idempotent, accHandler.PlaceHold)
+		permission.GET("/:id", accHandler.GetHold)
+		permission.POST("/:id/capture", idempotent, tranHandler.CaptureHold)
+		permission.POST("/:id/release", accHandler.ReleaseHold)
+	}
+}
+
+// Route: Ledger
+func (r *routeConfig) ledgerRoutes() {
+	accRepo := account.NewAccountRepository(r.db)
+	accUsecase := account.NewAccountUsecse(accRepo, r.cfg.Account.HoldExpiry)
+
+	ledgerRepo := ledger.NewLedgerRepository(r.db)
+	ledgerUsecase := ledger.NewLedgerUsecase(ledgerRepo, accUsecase)
+	ledgerHandler := ledger.NewLedgerHandler(ledgerUsecase)
+
+	// Group: Permission ledger:verify
+	permission := r.router.Group("/ledger", r.mid.Authorized(string(role.PermLedgerVerify)), r.mid.Permissions(role.PermLedgerVerify))
 	{
-		public.POST("/deposit", tranHandler.Deposit)
+		permission.GET("/verify", ledgerHandler.Verify)
 	}
+}
+
+// Route: Statements
+func (r *routeConfig) statementRoutes() {
+	accRepo := account.NewAccountRepository(r.db)
+	tranRepo := transaction.NewTransactionRepository(r.db)
 
-	// Authorized
-	authorized := r.router.Group("/transactions", r.mid.Authorized())
+	stUsecase := statement.NewStatementUsecase(accRepo, tranRepo)
+	stHandler := statement.NewStatementHandler(stUsecase)
+
+	authorized := r.router.Group("/accounts", r.mid.Authorized())
 	{
-		authorized.POST("/withdraw", tranHandler.Withdraw)
-		authorized.POST("/transfer", tranHandler.Transfer)
-		authorized.GET("/", tranHandler.TransactionsByCurrentUser)
+		authorized.GET("/:id/statement", stHandler.GetStatement)
 	}
+}
 
-	// Group: Staff, Admin
-	permission := r.router.Group("/transactions/user", r.mid.Authorized(), r.mid.Permissions(user.RoleStaff, user.RoleAdmin))
+// Route: Metrics
+func (r *routeConfig) metricsRoutes() {
+	// Group: Permission system:metrics
+	permission := r.router.Group("/debug", r.mid.Authorized(string(role.PermSystemMetrics)), r.mid.Permissions(role.PermSystemMetrics))
 	{
-		permission.GET("/:id", tranHandler.TransactionsByUserID)
+		permission.GET("/vars", gin.WrapH(expvar.Handler()))
 	}
 }
+
+// Route: Standing Orders
+func (r *routeConfig) standingOrderRoutes(soUsecase standingorder.StandingOrderUsecase) {
+	soHandler := standingorder.NewStandingOrderHandler(soUsecase)
+
+	authorized := r.router.Group("/standing-orders", r.mid.Authorized(security.ScopeStandingOrders))
+	{
+		authorized.POST("/", middleware.StepUp(r.auth), soHandler.CreateStandingOrder)
+		authorized.GET("/", soHandler.ListStandingOrders)
+		authorized.GET("/:id/executions", soHandler.ListExecutions)
+		authorized.POST("/:id/resume", middleware.StepUp(r.auth), soHandler.ResumeStandingOrder)
+		authorized.DELETE("/:id", soHandler.CancelStandingOrder)
+	}
+}
+
+// standingOrderUsecase is shared by the standing order routes and the
+// background worker that executes due orders.
+func (r *routeConfig) standingOrderUsecase() standingorder.StandingOrderUsecase {
+	accRepo := account.NewAccountRepository(r.db)
+	accUsecase := account.NewAccountUsecse(accRepo, r.cfg.Account.HoldExpiry)
+
+	ledgerRepo := ledger.NewLedgerRepository(r.db)
+	ledgerUsecase := ledger.NewLedgerUsecase(ledgerRepo, accUsecase)
+
+	tranRepo := transaction.NewTransactionRepository(r.db)
+	tranUsecase := transaction.NewTransactionUsecse(tranRepo, accUsecase, ledgerUsecase, r.tx, money.Decimal(r.cfg.MFA.StepUpThreshold))
+
+	soRepo := standingorder.NewStandingOrderRepository(r.db)
+	return standingorder.NewStandingOrderUsecase(soRepo, accUsecase, tranUsecase, r.tx)
+}
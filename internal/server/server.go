@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/codepnw/simple-bank/config"
 	"github.com/codepnw/simple-bank/internal/db"
+	"github.com/codepnw/simple-bank/internal/middleware"
+	"github.com/codepnw/simple-bank/internal/modules/account"
+	"github.com/codepnw/simple-bank/internal/utils/mailer"
+	"github.com/codepnw/simple-bank/internal/utils/security"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,22 +31,95 @@ func Run(cfg *config.EnvConfig) error {
 	// Init TX
 	tx := db.InitTx(pg)
 
+	// Init Mailer
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		return fmt.Errorf("mailer init failed: %w", err)
+	}
+
+	// Init JWT signing keys
+	keys, err := security.NewKeySet(cfg)
+	if err != nil {
+		return fmt.Errorf("jwt keys init failed: %w", err)
+	}
+
+	// Init password policy
+	passwords, err := security.NewPasswordPolicy(cfg)
+	if err != nil {
+		return fmt.Errorf("password policy init failed: %w", err)
+	}
+
 	// Init gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	if err = r.SetTrustedProxies(cfg.APP.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies init failed: %w", err)
+	}
+	r.Use(middleware.SecurityEvents())
 
 	// Init Routes
 	routes := setupRoutes(&routeConfig{
-		router: r,
-		db:     pg,
-		tx:     tx,
-		cfg:    cfg,
+		router:    r,
+		db:        pg,
+		tx:        tx,
+		cfg:       cfg,
+		mail:      mail,
+		token:     security.InitJWT(cfg, keys),
+		passwords: passwords,
 	})
 
 	routes.authRoutes()
 	routes.userRoutes()
+	routes.roleRoutes()
+	routes.apiKeyRoutes()
 	routes.accountRoutes()
 	routes.transactionRoutes()
+	routes.channelRoutes()
+	routes.ledgerRoutes()
+	routes.holdRoutes()
+	routes.statementRoutes()
+	routes.metricsRoutes()
+
+	soUsecase := routes.standingOrderUsecase()
+	routes.standingOrderRoutes(soUsecase)
+
+	// Background Workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go runPeriodic(ctx, "standing orders", cfg.Worker.StandingOrderInterval, func(ctx context.Context) error {
+		n, err := soUsecase.ExecuteDue(ctx, time.Now())
+		if n > 0 {
+			log.Printf("standing orders: %d executed", n)
+		}
+		return err
+	})
+
+	accUsecase := account.NewAccountUsecse(account.NewAccountRepository(pg), cfg.Account.HoldExpiry)
+
+	go runPeriodic(ctx, "hold expiry", cfg.Worker.HoldExpiryInterval, func(ctx context.Context) error {
+		n, err := accUsecase.ExpireHolds(ctx, time.Now())
+		if n > 0 {
+			log.Printf("hold expiry: %d expired", n)
+		}
+		return err
+	})
+
+	go runPeriodic(ctx, "token cleanup", cfg.Worker.TokenCleanupInterval, func(ctx context.Context) error {
+		n, err := routes.auth.DeleteExpiredTokens(ctx, time.Now())
+		if n > 0 {
+			log.Printf("token cleanup: %d deleted", n)
+		}
+		return err
+	})
+
+	go runPeriodic(ctx, "jwt key rotation", cfg.Worker.KeyRotationInterval, func(ctx context.Context) error {
+		rotated, err := keys.Rotate()
+		if rotated {
+			log.Printf("jwt key rotation: new signing key created")
+		}
+		return err
+	})
 
 	return r.Run(cfg.APP.Port)
 }
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runPeriodic calls fn every interval until ctx is cancelled. Errors are
+// logged and the next tick runs as usual.
+func runPeriodic(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("%s worker started, every %s", name, interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				log.Printf("%s worker: %v", name, err)
+			}
+		}
+	}
+}
@@ -1,17 +1,141 @@
 package errs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
+	// Error Request
+	ErrRequestInvalid = errors.New("request is invalid")
+
 	// Error Account
 	ErrAccountNotFound         = errors.New("account not found")
 	ErrAccountAmountNotZero    = errors.New("amount mest not zero")
 	ErrAmountGreaterThanZero   = errors.New("amount must be greater than zero")
 	ErrAmountGreaterAccBalance = errors.New("amount must be greater than account balance")
+	ErrCurrencyNotSupported    = errors.New("currency is not supported")
+	ErrCurrencyMismatch        = errors.New("amount currency does not match the account currency")
+	ErrAccountCannotSend       = errors.New("account cannot send money in its current status")
+	ErrAccountCannotReceive    = errors.New("account cannot receive money in its current status")
+	ErrAccountStatusTransition = errors.New("account status change is not allowed")
+	ErrAccountBalanceNotZero   = errors.New("account must have a zero balance and no holds to be closed")
+	ErrAccountNotOwner         = errors.New("account does not belong to you")
+
+	// Error Hold
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrHoldCaptureExceeds = errors.New("capture amount exceeds the held amount")
 
 	// Error Transaction
-	ErrTranSameAccount = errors.New("cant transfer to the same account")
+	ErrTranSameAccount          = errors.New("cant transfer to the same account")
+	ErrTranNotFound             = errors.New("transaction not found")
+	ErrTranAlreadyReversed      = errors.New("transaction is already fully reversed")
+	ErrTranReversalExceeds      = errors.New("reversal amount exceeds the amount left to reverse")
+	ErrTranCannotReverseReverse = errors.New("a reversal cannot be reversed")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+
+	// Error Deposit Channel
+	ErrChannelNotFound        = errors.New("deposit channel not found")
+	ErrChannelNameTaken       = errors.New("deposit channel name is already taken")
+	ErrChannelTypeInvalid     = errors.New("deposit channel type must be API_KEY or HMAC")
+	ErrChannelUnauthorized    = errors.New("deposit channel authentication failed")
+	ErrDepositReferenceNeeded = errors.New("teller deposits need a reference")
+
+	// Error Ledger
+	ErrLedgerUnbalanced = errors.New("ledger entries must sum to zero")
+
+	// Error Idempotency
+	ErrIdempotencyKeyInvalid = errors.New("idempotency key must be between 1 and 255 characters")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+
+	// Error Statement
+	ErrStatementPeriod = errors.New("statement period must be valid dates, from before to, at most one year")
+
+	// Error Standing Order
+	ErrStandingOrderNotFound = errors.New("standing order not found")
+	ErrStandingOrderStatus   = errors.New("standing order cannot be changed in its current status")
+	ErrStandingOrderSchedule = errors.New("standing order start must be in the future and before its end")
+	ErrStandingOrderNotOwner = errors.New("standing order must debit one of your own accounts")
+
+	// Error Auth
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; all sessions of this login were revoked")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrLoginThrottled      = errors.New("too many failed attempts; try again later")
+	ErrSessionNotFound     = errors.New("session not found")
+
+	// Error Password
+	ErrPasswordWeak      = errors.New("password does not meet the password policy")
+	ErrPasswordReused    = errors.New("choose a password you have not used recently")
+	ErrPasswordIncorrect = errors.New("current password is incorrect")
+
+	// Error Two-Factor
+	ErrTOTPNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled     = errors.New("start two-factor enrollment first")
+	ErrTOTPInvalid         = errors.New("one-time code is invalid")
+	ErrMFAChallengeInvalid = errors.New("login challenge is invalid or expired")
+	ErrStepUpRequired      = errors.New("transfers above the limit need a one-time code from two-factor authentication")
+
+	// Error Password Reset & Email Verification
+	ErrUserTokenInvalid     = errors.New("link is invalid or expired")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	ErrEmailNotVerified     = errors.New("verify your email address first")
+
+	// Error API Keys & OAuth2 Clients
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrAPIKeyInvalid        = errors.New("api key is invalid, expired or revoked")
+	ErrAPIKeyExpiryInvalid  = errors.New("expiry must be in the future")
+	ErrScopeInvalid         = errors.New("unknown scope")
+	ErrInsufficientScope    = errors.New("the credentials are not allowed to do this")
+	ErrUserSessionRequired  = errors.New("sign in with your password; api keys and clients cannot do this")
+	ErrClientNotFound       = errors.New("client not found")
+	ErrClientInvalid        = errors.New("client authentication failed")
+	ErrUnsupportedGrantType = errors.New("only the client_credentials grant is supported")
+
+	// Error Role
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleNameInvalid   = errors.New("role name must be 2 to 32 uppercase letters, digits or underscores")
+	ErrRoleAdminLockout  = errors.New("the ADMIN role must keep the roles:manage permission")
+	ErrPermissionUnknown = errors.New("unknown permission")
+	ErrPermissionDenied  = errors.New("permission denied")
 
 	// Error Users
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUserRoleInvalid = errors.New("role does not exist")
+	ErrUserRoleSelf    = errors.New("you cannot change your own role")
 )
+
+// AccountStatusError reports an account whose status blocks a money
+// movement. It matches ErrAccountCannotSend or ErrAccountCannotReceive.
+type AccountStatusError struct {
+	AccountID int64
+	Status    string
+	Err       error
+}
+
+func (e *AccountStatusError) Error() string {
+	return fmt.Sprintf("account %d is %s: %v", e.AccountID, e.Status, e.Err)
+}
+
+func (e *AccountStatusError) Unwrap() error {
+	return e.Err
+}
+
+// ThrottledError reports a login refused because of earlier failures. It
+// matches ErrLoginThrottled.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrLoginThrottled.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer writes every message as an .eml file into dir instead of
+// sending it, for local development.
+func NewFileMailer(from, dir string) Mailer {
+	return &fileMailer{from: from, dir: dir}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := format(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(m.dir, 0o700); err != nil {
+		return fmt.Errorf("create mail dir failed: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), rand.Text()[:8])
+
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	from := "Simple Bank <no-reply@simple-bank.local>"
+
+	tests := []struct {
+		name        string
+		msg         *Message
+		expectedErr bool
+	}{
+		{name: "plain message", msg: &Message{To: "a@example.com", Subject: "Hello", Body: "line 1\nline 2"}},
+		{name: "header in recipient", msg: &Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "Hello"}, expectedErr: true},
+		{name: "header in subject", msg: &Message{To: "a@example.com", Subject: "Hello\r\nBcc: b@example.com"}, expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := format(from, tc.msg)
+
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Contains(t, string(data), "To: <a@example.com>\r\n")
+			assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nline 1\r\nline 2"))
+		})
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+
+	assert.NoError(t, m.Send(context.Background(), &Message{To: "a@example.com", Subject: "Hello"}))
+	assert.Equal(t, []Message{{To: "a@example.com", Subject: "Hello"}}, m.Messages())
+}
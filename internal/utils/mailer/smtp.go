@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type smtpMailer struct {
+	from string
+	host string
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer sends through an SMTP server, upgrading to TLS with
+// STARTTLS when the server offers it. Without a user no authentication is
+// attempted.
+func NewSMTPMailer(from, host, port, user, pass string) Mailer {
+	m := &smtpMailer{
+		from: from,
+		host: host,
+		addr: net.JoinHostPort(host, port),
+	}
+	if user != "" {
+		m.auth = smtp.PlainAuth("", user, pass, host)
+	}
+	return m
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := format(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	sender, _ := mail.ParseAddress(m.from)
+	to, _ := mail.ParseAddress(msg.To)
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial failed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("smtp starttls failed: %w", err)
+		}
+	}
+
+	if m.auth != nil {
+		if err = c.Auth(m.auth); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err = c.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err = c.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,47 @@
+package money
+
+import "fmt"
+
+// RoundingMode decides what happens to digits beyond a currency's minor unit.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the nearest even minor unit (banker's rounding).
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+)
+
+const DefaultCurrency = "THB"
+
+type Currency struct {
+	Code     string
+	Exponent int
+	Rounding RoundingMode
+}
+
+var currencies = map[string]Currency{
+	"THB": {Code: "THB", Exponent: 2, Rounding: RoundHalfUp},
+	"USD": {Code: "USD", Exponent: 2, Rounding: RoundHalfEven},
+	"EUR": {Code: "EUR", Exponent: 2, Rounding: RoundHalfEven},
+	"GBP": {Code: "GBP", Exponent: 2, Rounding: RoundHalfEven},
+	"SGD": {Code: "SGD", Exponent: 2, Rounding: RoundHalfUp},
+	"JPY": {Code: "JPY", Exponent: 0, Rounding: RoundHalfUp},
+	"KWD": {Code: "KWD", Exponent: 3, Rounding: RoundHalfUp},
+}
+
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// IsCurrency reports whether code is a supported ISO 4217 currency.
+func IsCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
@@ -0,0 +1,33 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Decimal is an amount as sent by a client. It keeps the text of the JSON
+// string or number as-is so it is never rounded through float64; the
+// currency is applied later with Parse.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+
+	*d = Decimal(b)
+	return nil
+}
+
+func (d Decimal) Parse(currency string) (Money, error) {
+	return Parse(string(d), currency)
+}
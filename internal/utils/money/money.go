@@ -0,0 +1,275 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrOverflow         = errors.New("amount overflow")
+)
+
+// Money is an exact amount held in the minor unit of its currency
+// (satang for THB, cents for USD, yen for JPY).
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New returns an amount of minor units in a supported currency.
+func New(minor int64, currency string) (Money, error) {
+	if _, err := LookupCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// FromMinor builds Money from trusted storage without validating the
+// currency. Repositories use it when scanning rows.
+func FromMinor(minor int64, currency string) Money {
+	return Money{amount: minor, currency: currency}
+}
+
+func Zero(currency string) Money {
+	return Money{currency: currency}
+}
+
+// Parse reads a decimal string such as "10.50" as an amount in currency.
+// Digits beyond the currency's minor unit are rounded with its rounding mode.
+func Parse(s, currency string) (Money, error) {
+	cur, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" || (hasDot && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	rest := ""
+	if len(fracPart) > cur.Exponent {
+		rest = fracPart[cur.Exponent:]
+		fracPart = fracPart[:cur.Exponent]
+	} else {
+		fracPart += strings.Repeat("0", cur.Exponent-len(fracPart))
+	}
+
+	minor, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	if roundUp(minor, rest, cur.Rounding) {
+		if minor == math.MaxInt64 {
+			return Money{}, ErrOverflow
+		}
+		minor++
+	}
+
+	if neg {
+		minor = -minor
+	}
+
+	return Money{amount: minor, currency: cur.Code}, nil
+}
+
+// Minor returns the amount in minor units.
+func (m Money) Minor() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() string {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+func (m Money) Neg() Money {
+	return Money{amount: -m.amount, currency: m.currency}
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	cur, err := m.sameCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+
+	sum := m.amount + o.amount
+	if (o.amount > 0 && sum < m.amount) || (o.amount < 0 && sum > m.amount) {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: sum, currency: cur}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if o.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Cmp returns -1, 0 or +1 when m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.amount < o.amount:
+		return -1, nil
+	case m.amount > o.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// MulFrac multiplies m by num/den, rounding the result with the currency's
+// rounding mode. It is used for fees, interest and proportional splits.
+func (m Money) MulFrac(num, den int64) (Money, error) {
+	if den == 0 {
+		return Money{}, ErrInvalidAmount
+	}
+
+	cur, err := LookupCurrency(m.currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	n := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(num))
+	d := big.NewInt(den)
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d.Neg(d)
+	}
+
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() != 0 {
+		twice := new(big.Int).Abs(r)
+		twice.Lsh(twice, 1)
+		half := twice.Cmp(d)
+
+		up := false
+		switch cur.Rounding {
+		case RoundHalfUp:
+			up = half >= 0
+		case RoundHalfEven:
+			up = half > 0 || (half == 0 && q.Bit(0) == 1)
+		}
+
+		if up {
+			q.Add(q, big.NewInt(int64(n.Sign())))
+		}
+	}
+
+	if !q.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: q.Int64(), currency: m.currency}, nil
+}
+
+// String formats the amount as a plain decimal, e.g. "10.50".
+func (m Money) String() string {
+	exp := 2
+	if cur, err := LookupCurrency(m.currency); err == nil {
+		exp = cur.Exponent
+	}
+
+	sign := ""
+	abs := uint64(m.amount)
+	if m.amount < 0 {
+		sign = "-"
+		abs = uint64(-(m.amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// Format returns the amount with its currency code, e.g. "10.50 THB".
+func (m Money) Format() string {
+	return m.String() + " " + m.currency
+}
+
+// MarshalJSON encodes the amount as a decimal string so clients never see
+// binary floating point.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// sameCurrency allows the zero Money value to combine with any currency so
+// sums can start from an empty accumulator.
+func (m Money) sameCurrency(o Money) (string, error) {
+	switch {
+	case m.currency == o.currency:
+		return m.currency, nil
+	case m.currency == "" && m.amount == 0:
+		return o.currency, nil
+	case o.currency == "" && o.amount == 0:
+		return m.currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+}
+
+func roundUp(minor int64, rest string, mode RoundingMode) bool {
+	if strings.Trim(rest, "0") == "" {
+		return false
+	}
+
+	switch mode {
+	case RoundHalfUp:
+		return rest[0] >= '5'
+	case RoundHalfEven:
+		if rest[0] != '5' {
+			return rest[0] > '5'
+		}
+		if strings.Trim(rest[1:], "0") != "" {
+			return true
+		}
+		return minor%2 == 1
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
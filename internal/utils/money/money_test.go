@@ -0,0 +1,118 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		currency string
+		expected int64
+		wantErr  error
+	}{
+		{name: "whole units", input: "10", currency: "THB", expected: 1000},
+		{name: "minor units", input: "10.50", currency: "THB", expected: 1050},
+		{name: "short fraction", input: "10.5", currency: "THB", expected: 1050},
+		{name: "negative", input: "-3.25", currency: "THB", expected: -325},
+		{name: "half up", input: "0.125", currency: "THB", expected: 13},
+		{name: "half even down", input: "0.125", currency: "USD", expected: 12},
+		{name: "half even up", input: "0.135", currency: "USD", expected: 14},
+		{name: "half even above half", input: "0.1251", currency: "USD", expected: 13},
+		{name: "no minor unit", input: "1500.4", currency: "JPY", expected: 1500},
+		{name: "three decimals", input: "1.2345", currency: "KWD", expected: 1235},
+		{name: "empty", input: "", currency: "THB", wantErr: ErrInvalidAmount},
+		{name: "trailing dot", input: "10.", currency: "THB", wantErr: ErrInvalidAmount},
+		{name: "exponent", input: "1e3", currency: "THB", wantErr: ErrInvalidAmount},
+		{name: "unknown currency", input: "1", currency: "XXX", wantErr: ErrUnknownCurrency},
+		{name: "overflow", input: "99999999999999999999", currency: "THB", wantErr: ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input, tt.currency)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, m.Minor())
+			assert.Equal(t, tt.currency, m.Currency())
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "10.50", FromMinor(1050, "THB").String())
+	assert.Equal(t, "0.05", FromMinor(5, "THB").String())
+	assert.Equal(t, "-0.05", FromMinor(-5, "THB").String())
+	assert.Equal(t, "1500", FromMinor(1500, "JPY").String())
+	assert.Equal(t, "1.235 KWD", FromMinor(1235, "KWD").Format())
+	assert.Equal(t, "-92233720368547758.08", FromMinor(math.MinInt64, "THB").String())
+
+	b, err := json.Marshal(FromMinor(1050, "THB"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"10.50"`, string(b))
+}
+
+func TestArithmetic(t *testing.T) {
+	a := FromMinor(1050, "THB")
+	b := FromMinor(250, "THB")
+
+	sum, err := a.Add(b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1300), sum.Minor())
+
+	diff, err := b.Sub(a)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-800), diff.Minor())
+
+	cmp, err := a.Cmp(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+
+	_, err = a.Add(FromMinor(1, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = FromMinor(math.MaxInt64, "THB").Add(FromMinor(1, "THB"))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	total, err := Money{}.Add(a)
+	assert.NoError(t, err)
+	assert.Equal(t, a, total)
+}
+
+func TestMulFrac(t *testing.T) {
+	half, err := FromMinor(101, "THB").MulFrac(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(51), half.Minor())
+
+	even, err := FromMinor(101, "USD").MulFrac(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), even.Minor())
+
+	neg, err := FromMinor(-101, "THB").MulFrac(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-51), neg.Minor())
+
+	_, err = FromMinor(1, "THB").MulFrac(1, 0)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestDecimalUnmarshal(t *testing.T) {
+	var req struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+	}
+
+	err := json.Unmarshal([]byte(`{"a": 10.10, "b": "20.20"}`), &req)
+	assert.NoError(t, err)
+	assert.Equal(t, Decimal("10.10"), req.A)
+	assert.Equal(t, Decimal("20.20"), req.B)
+}
@@ -1,8 +1,13 @@
 package response
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/codepnw/simple-bank/internal/utils/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -31,9 +36,56 @@ func ErrInternalServer(ctx *gin.Context, err error) {
 	})
 }
 
+// ErrBadRequest answers a request that cannot be handled as sent. Invalid
+// fields found by validation are listed each with the reason.
 func ErrBadRequest(ctx *gin.Context, err error) {
+	if fields, ok := validation.Fields(err); ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   errs.ErrRequestInvalid.Error(),
+			"fields":  fields,
+		})
+		return
+	}
+
 	ctx.JSON(http.StatusBadRequest, gin.H{
 		"success": false,
 		"error":   err.Error(),
 	})
 }
+
+// Forbidden answers a request of an authenticated user who may not do
+// what was asked. The error is kept on the context for the security log.
+func Forbidden(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+func ErrNotFound(ctx *gin.Context, err error) {
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+func ErrConflict(ctx *gin.Context, err error) {
+	ctx.JSON(http.StatusConflict, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// TooManyRequests answers a request refused by a rate limit and tells the
+// client when to retry. The error is kept on the context for the security
+// log.
+func TooManyRequests(ctx *gin.Context, retryAfter time.Duration, err error) {
+	_ = ctx.Error(err)
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
@@ -1,8 +1,12 @@
 package security
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/codepnw/simple-bank/config"
@@ -13,56 +17,264 @@ type TokenUser struct {
 	ID    int64
 	Email string
 	Role  string
+
+	// Set when an access token is verified; identify that token for
+	// revocation.
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// SessionID is the login an access token belongs to; empty for tokens
+	// of OAuth2 clients.
+	SessionID string
+
+	// Scopes limit what an API key or an OAuth2 client may do; nil for a
+	// user who signed in, who may do all their role allows.
+	Scopes []string
+
+	// Set when the request was made with an API key or with a token of an
+	// OAuth2 client.
+	APIKeyID int64
+	ClientID string
+}
+
+// Scopes besides permissions: read allows safe requests, write all others.
+// The route scopes allow just the routes that move money of the user.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+
+	ScopeWithdraw       = "transactions:withdraw"
+	ScopeTransfer       = "transactions:transfer"
+	ScopeStandingOrders = "standing_orders:manage"
+)
+
+// RouteScopes lists the route scopes.
+var RouteScopes = []string{ScopeWithdraw, ScopeTransfer, ScopeStandingOrders}
+
+// Restricted reports whether u acts through an API key or OAuth2 client.
+func (u *TokenUser) Restricted() bool {
+	return u.Scopes != nil
+}
+
+// HasScope reports whether u may use scope. Users who signed in have
+// every scope.
+func (u *TokenUser) HasScope(scope string) bool {
+	return !u.Restricted() || slices.Contains(u.Scopes, scope)
+}
+
+// RefreshToken is one refresh token of a rotation family. Every refresh
+// replaces it with a new token of the same family.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	User      *TokenUser
+	ExpiresAt time.Time
+}
+
+const (
+	accessTokenExpiry  = time.Hour * 24
+	refreshTokenExpiry = time.Hour * 24 * 7
+
+	// clockLeeway allows for clock drift between the services that issue
+	// and check tokens.
+	clockLeeway = time.Second * 30
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// tokenClaims are the claims of access and refresh tokens. Both are signed
+// with the same keys; typ keeps one from being used as the other.
+type tokenClaims struct {
+	jwt.StandardClaims
+	Type     string `json:"typ"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	FamilyID string `json:"fid,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Session  string `json:"sid,omitempty"`
+
+	now time.Time
+}
+
+// Valid checks the time claims, which jwt-go treats as optional.
+func (c *tokenClaims) Valid() error {
+	if c.ExpiresAt == 0 || c.IssuedAt == 0 || c.Id == "" {
+		return errors.New("token is missing exp, iat or jti")
+	}
+
+	now := c.now.Unix()
+	leeway := int64(clockLeeway / time.Second)
+
+	if now >= c.ExpiresAt+leeway {
+		return errors.New("token is expired")
+	}
+	if c.IssuedAt > now+leeway {
+		return errors.New("token used before issued")
+	}
+	if c.NotBefore != 0 && c.NotBefore > now+leeway {
+		return errors.New("token is not valid yet")
+	}
+
+	return nil
 }
 
 type Token struct {
-	cfg *config.EnvConfig
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
 }
 
-func InitJWT(cfg *config.EnvConfig) *Token {
-	return &Token{cfg: cfg}
+func InitJWT(cfg *config.EnvConfig, keys *KeySet) *Token {
+	return &Token{
+		keys:     keys,
+		issuer:   cfg.JWT.Issuer,
+		audience: cfg.JWT.Audience,
+		now:      time.Now,
+	}
+}
+
+// JWKS returns the public keys tokens are verified with.
+func (t *Token) JWKS() *JWKS {
+	return t.keys.JWKS()
 }
 
 func (t *Token) GenerateAccessToken(user *TokenUser) (string, error) {
 	if t == nil {
 		return "", errors.New("token struct is nil")
 	}
-	exp := time.Hour * 24
-	return t.generateToken(t.cfg.JWT.SecretKey, exp, user)
+
+	return t.accessToken(user, accessTokenExpiry)
 }
 
-func (t *Token) GenerateRefreshToken(user *TokenUser) (string, error) {
+// GenerateClientToken signs an access token of an OAuth2 client, limited
+// to the scopes of user.
+func (t *Token) GenerateClientToken(user *TokenUser, exp time.Duration) (string, error) {
 	if t == nil {
 		return "", errors.New("token struct is nil")
 	}
-	exp := time.Hour * 24 * 7
-	return t.generateToken(t.cfg.JWT.RefreshKey, exp, user)
+	if user.ClientID == "" {
+		return "", errors.New("client token has no client id")
+	}
+
+	return t.accessToken(user, exp)
+}
+
+// GenerateRefreshToken signs a new refresh token in familyID; an empty
+// familyID starts a new family.
+func (t *Token) GenerateRefreshToken(user *TokenUser, familyID string) (string, *RefreshToken, error) {
+	if t == nil {
+		return "", nil, errors.New("token struct is nil")
+	}
+
+	now := t.now()
+	rt := &RefreshToken{
+		ID:        NewTokenID(),
+		FamilyID:  familyID,
+		User:      user,
+		ExpiresAt: now.Add(refreshTokenExpiry),
+	}
+	if rt.FamilyID == "" {
+		rt.FamilyID = NewTokenID()
+	}
+
+	tokenString, err := t.sign(&tokenClaims{
+		StandardClaims: t.standardClaims(user, rt.ID, now, rt.ExpiresAt),
+		Type:           tokenTypeRefresh,
+		Email:          user.Email,
+		Role:           user.Role,
+		FamilyID:       rt.FamilyID,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, rt, nil
 }
 
 func (t *Token) VerifyAccessToken(token string) (*TokenUser, error) {
 	if t == nil {
 		return nil, errors.New("token struct is nil")
 	}
-	return t.verifyToken(token, t.cfg.JWT.SecretKey)
+
+	claims, err := t.parse(token, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenUser(claims)
 }
 
-func (t *Token) VerifyRefreshToken(token string) (*TokenUser, error) {
+func (t *Token) VerifyRefreshToken(token string) (*RefreshToken, error) {
 	if t == nil {
 		return nil, errors.New("token struct is nil")
 	}
-	return t.verifyToken(token, t.cfg.JWT.RefreshKey)
+
+	claims, err := t.parse(token, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	if claims.FamilyID == "" {
+		return nil, errors.New("refresh token has no family")
+	}
+
+	u, err := tokenUser(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshToken{
+		ID:        claims.Id,
+		FamilyID:  claims.FamilyID,
+		User:      u,
+		ExpiresAt: u.ExpiresAt,
+	}, nil
 }
 
-// Generate Token String
-func (t *Token) generateToken(key string, exp time.Duration, user *TokenUser) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(exp).Unix(),
+// NewTokenID returns a random identifier for a token or token family.
+func NewTokenID() string {
+	return rand.Text()
+}
+
+func (t *Token) accessToken(user *TokenUser, exp time.Duration) (string, error) {
+	now := t.now()
+	return t.sign(&tokenClaims{
+		StandardClaims: t.standardClaims(user, NewTokenID(), now, now.Add(exp)),
+		Type:           tokenTypeAccess,
+		Email:          user.Email,
+		Role:           user.Role,
+		Scope:          strings.Join(user.Scopes, " "),
+		ClientID:       user.ClientID,
+		Session:        user.SessionID,
 	})
+}
+
+func (t *Token) standardClaims(user *TokenUser, id string, iat, exp time.Time) jwt.StandardClaims {
+	return jwt.StandardClaims{
+		Id:        id,
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Issuer:    t.issuer,
+		Audience:  t.audience,
+		IssuedAt:  iat.Unix(),
+		ExpiresAt: exp.Unix(),
+	}
+}
+
+// sign signs claims with the current signing key and names the key in the
+// kid header, so the token still verifies after the key is rotated.
+func (t *Token) sign(claims *tokenClaims) (string, error) {
+	key, err := t.keys.Signing()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
 
-	tokenString, err := token.SignedString([]byte(key))
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", fmt.Errorf("sign token failed: %w", err)
 	}
@@ -70,13 +282,20 @@ func (t *Token) generateToken(key string, exp time.Duration, user *TokenUser) (s
 	return tokenString, nil
 }
 
-// Verify Token String
-func (t *Token) verifyToken(tokenStr, key string) (*TokenUser, error) {
-	token, err := jwt.Parse(tokenStr, func(tt *jwt.Token) (any, error) {
-		if _, ok := tt.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unknow signing method: %v", tt.Header)
+func (t *Token) parse(tokenStr, typ string) (*tokenClaims, error) {
+	parser := &jwt.Parser{ValidMethods: []string{AlgorithmRS256, AlgorithmEdDSA}}
+	claims := &tokenClaims{now: t.now()}
+
+	token, err := parser.ParseWithClaims(tokenStr, claims, func(tt *jwt.Token) (any, error) {
+		kid, _ := tt.Header["kid"].(string)
+		key, ok := t.keys.Verification(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if tt.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("signing method %s does not match key %q", tt.Method.Alg(), kid)
 		}
-		return []byte(key), nil
+		return key.Public, nil
 	})
 	if err != nil {
 		return nil, err
@@ -85,16 +304,40 @@ func (t *Token) verifyToken(tokenStr, key string) (*TokenUser, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("verification failed")
+	if claims.Issuer != t.issuer {
+		return nil, errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(t.audience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+	if claims.Type != typ {
+		return nil, fmt.Errorf("expected %s token, got %q", typ, claims.Type)
+	}
+
+	return claims, nil
+}
+
+func tokenUser(claims *tokenClaims) (*TokenUser, error) {
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || id <= 0 || claims.Email == "" || claims.Role == "" {
+		return nil, errors.New("invalid token claims")
 	}
 
-	u := new(TokenUser)
+	u := &TokenUser{
+		ID:        id,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		TokenID:   claims.Id,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		ClientID:  claims.ClientID,
+		SessionID: claims.Session,
+	}
 
-	u.ID = int64(claims["user_id"].(float64))
-	u.Email = claims["email"].(string)
-	u.Role = claims["role"].(string)
+	// A client token without scopes may do nothing rather than everything.
+	if claims.ClientID != "" {
+		u.Scopes = append([]string{}, strings.Fields(claims.Scope)...)
+	}
 
 	return u, nil
 }
@@ -0,0 +1,166 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codepnw/simple-bank/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testJWTConfig(t *testing.T, algorithm string) *config.EnvConfig {
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	cfg, err := config.LoadEnvConfig(file)
+	assert.NoError(t, err)
+
+	cfg.JWT.KeysDir = t.TempDir()
+	cfg.JWT.Algorithm = algorithm
+	return cfg
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	user := &TokenUser{ID: 42, Email: "a@example.com", Role: "USER"}
+
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			cfg := testJWTConfig(t, algorithm)
+			keys, err := NewKeySet(cfg)
+			assert.NoError(t, err)
+			token := InitJWT(cfg, keys)
+
+			access, err := token.GenerateAccessToken(user)
+			assert.NoError(t, err)
+
+			u, err := token.VerifyAccessToken(access)
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID, u.ID)
+			assert.NotEmpty(t, u.TokenID)
+
+			refresh, rt, err := token.GenerateRefreshToken(user, "")
+			assert.NoError(t, err)
+
+			got, err := token.VerifyRefreshToken(refresh)
+			assert.NoError(t, err)
+			assert.Equal(t, rt.FamilyID, got.FamilyID)
+
+			_, err = token.VerifyAccessToken(refresh)
+			assert.Error(t, err, "refresh token accepted as access token")
+			_, err = token.VerifyRefreshToken(access)
+			assert.Error(t, err, "access token accepted as refresh token")
+		})
+	}
+}
+
+func TestVerifyAccessTokenRejects(t *testing.T) {
+	cfg := testJWTConfig(t, AlgorithmEdDSA)
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+	user := &TokenUser{ID: 1, Email: "a@example.com", Role: "USER"}
+
+	other := testJWTConfig(t, AlgorithmEdDSA)
+	otherKeys, err := NewKeySet(other)
+	assert.NoError(t, err)
+
+	wrongAudience := *cfg.JWT
+	wrongAudience.Audience = "other-service"
+	audienceCfg := *cfg
+	audienceCfg.JWT = &wrongAudience
+
+	expired := InitJWT(cfg, keys)
+	expired.now = func() time.Time { return time.Now().Add(-accessTokenExpiry - time.Minute) }
+
+	tests := []struct {
+		name   string
+		issuer *Token
+	}{
+		{name: "unknown key", issuer: InitJWT(other, otherKeys)},
+		{name: "wrong audience", issuer: InitJWT(&audienceCfg, keys)},
+		{name: "expired", issuer: expired},
+	}
+
+	verifier := InitJWT(cfg, keys)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			access, err := tc.issuer.GenerateAccessToken(user)
+			assert.NoError(t, err)
+
+			_, err = verifier.VerifyAccessToken(access)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	cfg := testJWTConfig(t, AlgorithmEdDSA)
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+	token := InitJWT(cfg, keys)
+	user := &TokenUser{ID: 1, Email: "a@example.com", Role: "USER"}
+
+	old, err := keys.Signing()
+	assert.NoError(t, err)
+	access, err := token.GenerateAccessToken(user)
+	assert.NoError(t, err)
+
+	// Rotation is due once the newest key is older than the period.
+	now := time.Now().Add(cfg.JWT.KeyRotation)
+	keys.now = func() time.Time { return now }
+
+	rotated, err := keys.Rotate()
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+	assert.Len(t, keys.JWKS().Keys, 2)
+
+	// The new key is published before it signs.
+	current, err := keys.Signing()
+	assert.NoError(t, err)
+	assert.Equal(t, old.ID, current.ID)
+
+	now = now.Add(keyPublishDelay)
+	current, err = keys.Signing()
+	assert.NoError(t, err)
+	assert.NotEqual(t, old.ID, current.ID)
+
+	// Tokens of the replaced key verify until it is pruned.
+	_, err = token.VerifyAccessToken(access)
+	assert.NoError(t, err)
+
+	now = now.Add(refreshTokenExpiry + keyPublishDelay)
+	rotated, err = keys.Rotate()
+	assert.NoError(t, err)
+	assert.False(t, rotated)
+
+	_, ok := keys.Verification(old.ID)
+	assert.False(t, ok)
+	assert.NoFileExists(t, filepath.Join(cfg.JWT.KeysDir, old.ID+".pem"))
+}
+
+func TestClientTokenScopes(t *testing.T) {
+	cfg := testJWTConfig(t, AlgorithmEdDSA)
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+	token := InitJWT(cfg, keys)
+
+	access, err := token.GenerateClientToken(&TokenUser{ID: 1, Email: "a@example.com", Role: "USER", ClientID: "sbc_1", Scopes: []string{"read"}}, time.Minute)
+	assert.NoError(t, err)
+
+	u, err := token.VerifyAccessToken(access)
+	assert.NoError(t, err)
+	assert.Equal(t, "sbc_1", u.ClientID)
+	assert.True(t, u.Restricted())
+	assert.True(t, u.HasScope(ScopeRead))
+	assert.False(t, u.HasScope(ScopeWrite))
+
+	// A client token without scopes is limited to nothing.
+	access, err = token.GenerateClientToken(&TokenUser{ID: 1, Email: "a@example.com", Role: "USER", ClientID: "sbc_1"}, time.Minute)
+	assert.NoError(t, err)
+
+	u, err = token.VerifyAccessToken(access)
+	assert.NoError(t, err)
+	assert.True(t, u.Restricted())
+	assert.False(t, u.HasScope(ScopeRead))
+}
@@ -0,0 +1,403 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codepnw/simple-bank/config"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits = 3072
+
+	// generatedKeyPrefix marks keys created by rotation; only those are
+	// deleted again. Keys placed by hand stay until removed by hand.
+	generatedKeyPrefix = "gen-"
+
+	// keyPublishDelay is how long a new key is only published in the JWKS
+	// before tokens are signed with it, so services that cache the JWKS
+	// know it by then.
+	keyPublishDelay = time.Minute * 10
+
+	// reloadInterval limits how often a token with an unknown kid makes
+	// the key directory be read again.
+	reloadInterval = time.Second * 30
+)
+
+// SigningKey is one key of the key set. Keys without a private part, e.g.
+// those of retired or external signers, only verify.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeySet holds the keys that sign and verify tokens, read from the PEM
+// files of a directory. The newest private key signs; every key verifies.
+type KeySet struct {
+	dir       string
+	algorithm string
+	rotation  time.Duration
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]*SigningKey
+	loadedAt time.Time
+}
+
+// NewKeySet loads the key directory and creates a first key when it has
+// no private key yet.
+func NewKeySet(cfg *config.EnvConfig) (*KeySet, error) {
+	if cfg.JWT.Algorithm != AlgorithmEdDSA && cfg.JWT.Algorithm != AlgorithmRS256 {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.JWT.Algorithm)
+	}
+
+	ks := &KeySet{
+		dir:       cfg.JWT.KeysDir,
+		algorithm: cfg.JWT.Algorithm,
+		rotation:  cfg.JWT.KeyRotation,
+		now:       time.Now,
+	}
+
+	if err := ks.Load(); err != nil {
+		return nil, err
+	}
+
+	if ks.newestPrivate() == nil {
+		if err := ks.generate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+// Load reads every <kid>.pem file of the key directory.
+func (ks *KeySet) Load() error {
+	if err := os.MkdirAll(ks.dir, 0o700); err != nil {
+		return fmt.Errorf("create key dir failed: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey, len(files))
+	for _, file := range files {
+		key, err := readKey(file)
+		if err != nil {
+			return fmt.Errorf("load key %s failed: %w", filepath.Base(file), err)
+		}
+		keys[key.ID] = key
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.loadedAt = ks.now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// Rotate picks up keys added by other instances, creates a new key once
+// the newest one is older than the rotation period and deletes generated
+// keys that no longer verify any unexpired token. It reports whether a key
+// was created.
+func (ks *KeySet) Rotate() (bool, error) {
+	if err := ks.Load(); err != nil {
+		return false, err
+	}
+
+	rotated := false
+	if newest := ks.newestPrivate(); newest == nil || ks.now().Sub(newest.CreatedAt) >= ks.rotation {
+		if err := ks.generate(); err != nil {
+			return false, err
+		}
+		rotated = true
+	}
+
+	return rotated, ks.prune()
+}
+
+// Signing returns the key new tokens are signed with: the newest private
+// key that has been published for keyPublishDelay, or the newest one when
+// none has.
+func (ks *KeySet) Signing() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	private := ks.privateKeys()
+	if len(private) == 0 {
+		return nil, errors.New("no signing key")
+	}
+
+	published := ks.now().Add(-keyPublishDelay)
+	for i := len(private) - 1; i >= 0; i-- {
+		if !private[i].CreatedAt.After(published) {
+			return private[i], nil
+		}
+	}
+
+	return private[len(private)-1], nil
+}
+
+// Verification returns the key with id kid. An unknown kid may belong to a
+// key another instance just created, so the directory is read again.
+func (ks *KeySet) Verification(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	stale := ks.now().Sub(ks.loadedAt) > reloadInterval
+	ks.mu.RUnlock()
+
+	if ok || !stale || kid == "" {
+		return key, ok
+	}
+
+	if err := ks.Load(); err != nil {
+		return nil, false
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok = ks.keys[kid]
+	return key, ok
+}
+
+// JWK is the public part of a key as in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services verify our tokens with.
+func (ks *KeySet) JWKS() *JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range ks.sorted() {
+		jwk := JWK{Use: "sig", Alg: key.Algorithm, Kid: key.ID}
+
+		switch pub := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func (ks *KeySet) generate() error {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch ks.algorithm {
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	if err != nil {
+		return fmt.Errorf("generate key failed: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return err
+	}
+
+	now := ks.now().UTC()
+	kid := generatedKeyPrefix + now.Format("20060102T150405Z") + "-" + rand.Text()[:6]
+	file := filepath.Join(ks.dir, kid+".pem")
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(file, data, 0o600); err != nil {
+		return fmt.Errorf("write key failed: %w", err)
+	}
+	if err = os.Chtimes(file, now, now); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys[kid] = &SigningKey{
+		ID:        kid,
+		Algorithm: ks.algorithm,
+		Private:   signer,
+		Public:    signer.Public(),
+		CreatedAt: now,
+	}
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// prune deletes generated keys replaced longer ago than the lifetime of
+// the longest-lived token they may have signed.
+func (ks *KeySet) prune() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	private := ks.privateKeys()
+	cutoff := ks.now().Add(-refreshTokenExpiry - keyPublishDelay)
+
+	for i := 0; i < len(private)-1; i++ {
+		key, replacedAt := private[i], private[i+1].CreatedAt
+		if !strings.HasPrefix(key.ID, generatedKeyPrefix) || replacedAt.After(cutoff) {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(ks.dir, key.ID+".pem"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("delete key %s failed: %w", key.ID, err)
+		}
+		delete(ks.keys, key.ID)
+	}
+
+	return nil
+}
+
+func (ks *KeySet) newestPrivate() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	private := ks.privateKeys()
+	if len(private) == 0 {
+		return nil
+	}
+	return private[len(private)-1]
+}
+
+// privateKeys returns the keys that can sign, oldest first. The caller
+// holds mu.
+func (ks *KeySet) privateKeys() []*SigningKey {
+	var private []*SigningKey
+	for _, key := range ks.sorted() {
+		if key.Private != nil {
+			private = append(private, key)
+		}
+	}
+	return private
+}
+
+func (ks *KeySet) sorted() []*SigningKey {
+	keys := make([]*SigningKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys
+}
+
+// readKey parses a PEM file holding an RSA or Ed25519 private key, or only
+// the public key. Its modification time is taken as creation time.
+func readKey(file string) (*SigningKey, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	key := &SigningKey{
+		ID:        strings.TrimSuffix(filepath.Base(file), ".pem"),
+		CreatedAt: info.ModTime().UTC(),
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private, key.Public = AlgorithmEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Algorithm, key.Public = AlgorithmEdDSA, k
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Private, key.Public = AlgorithmRS256, k, k.Public()
+	case *rsa.PublicKey:
+		key.Algorithm, key.Public = AlgorithmRS256, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if pub, ok := key.Public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, errors.New("rsa key must have at least 2048 bits")
+	}
+
+	return key, nil
+}
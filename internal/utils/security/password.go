@@ -1,24 +1,161 @@
 package security
 
 import (
-	"errors"
+	"bufio"
+	"crypto/rand"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/codepnw/simple-bank/config"
+	"github.com/codepnw/simple-bank/internal/utils/errs"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// maxPasswordBytes is the most bcrypt uses of a password.
+const maxPasswordBytes = 72
+
+// PasswordPolicy decides which passwords are allowed and hashes them.
+type PasswordPolicy struct {
+	minLength  int
+	minClasses int
+	cost       int
+	common     map[string]struct{}
+
+	// dummy is checked against when there is no hash, so that takes as
+	// long as a real check.
+	dummy func() string
+}
+
+func NewPasswordPolicy(cfg *config.EnvConfig) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		minLength:  cfg.Password.MinLength,
+		minClasses: min(cfg.Password.MinClasses, 4),
+		cost:       cfg.Password.BcryptCost,
+	}
+
+	if p.cost < bcrypt.MinCost || p.cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	if cfg.Password.CommonListFile != "" {
+		common, err := readPasswordList(cfg.Password.CommonListFile)
+		if err != nil {
+			return nil, err
+		}
+		p.common = common
+	}
+
+	p.dummy = sync.OnceValue(func() string {
+		hash, _ := p.Hash(rand.Text())
+		return hash
+	})
+
+	return p, nil
+}
+
+// Check returns an error matching ErrPasswordWeak that says what is wrong
+// when the password is not allowed.
+func (p *PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.minLength {
+		return fmt.Errorf("%w: use at least %d characters", errs.ErrPasswordWeak, p.minLength)
+	}
+
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: use at most %d bytes", errs.ErrPasswordWeak, maxPasswordBytes)
+	}
+
+	if passwordClasses(password) < p.minClasses {
+		return fmt.Errorf("%w: mix at least %d of lowercase letters, uppercase letters, digits and symbols", errs.ErrPasswordWeak, p.minClasses)
+	}
+
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: it is too common", errs.ErrPasswordWeak)
+	}
+
+	return nil
+}
+
+func (p *PasswordPolicy) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
 	if err != nil {
 		return "", fmt.Errorf("hash password failed: %w", err)
 	}
 	return string(hash), nil
 }
 
+// NeedsRehash reports whether a hash is weaker than the policy asks for.
+func (p *PasswordPolicy) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < p.cost
+}
+
+// CompareDummy takes as long as checking a password against a real hash
+// and always fails, for when there is no user to check against.
+func (p *PasswordPolicy) CompareDummy(password string) error {
+	_ = ComparePassword(p.dummy(), password)
+	return errs.ErrInvalidCredentials
+}
+
 func ComparePassword(hashPassword, password string) error {
 	err := bcrypt.CompareHashAndPassword([]byte(hashPassword), []byte(password))
 	if err != nil {
-		return errors.New("invalid email or password")
+		return errs.ErrInvalidCredentials
 	}
 	return nil
 }
+
+// passwordClasses counts which of lowercase letters, uppercase letters,
+// digits and other characters a password has.
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	n := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
+
+// readPasswordList reads one password per line, skipping blank lines and
+// lines starting with #.
+func readPasswordList(file string) (map[string]struct{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open common password list failed: %w", err)
+	}
+	defer f.Close()
+
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read common password list failed: %w", err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,68 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codepnw/simple-bank/internal/utils/errs"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testPasswordPolicy(t *testing.T, cost int) *PasswordPolicy {
+	cfg := testJWTConfig(t, AlgorithmEdDSA)
+
+	list := filepath.Join(t.TempDir(), "common.txt")
+	assert.NoError(t, os.WriteFile(list, []byte("# common\nPassword123!\n\n"), 0o600))
+	cfg.Password.CommonListFile = list
+	cfg.Password.BcryptCost = cost
+
+	p, err := NewPasswordPolicy(cfg)
+	assert.NoError(t, err)
+	return p
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := testPasswordPolicy(t, bcrypt.MinCost)
+
+	tests := []struct {
+		name     string
+		password string
+		ok       bool
+	}{
+		{name: "strong", password: "correct-Horse-battery", ok: true},
+		{name: "unicode", password: "ñandú-Äpfel-straße", ok: true},
+		{name: "empty", password: ""},
+		{name: "too short", password: "Ab1!ab1!"},
+		{name: "too long", password: "Ab1!" + string(make([]byte, maxPasswordBytes))},
+		{name: "one class", password: "correcthorsebattery"},
+		{name: "two classes", password: "correcthorsebattery1"},
+		{name: "common in other case", password: "PASSWORD123!"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.Check(tc.password)
+
+			if tc.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errs.ErrPasswordWeak)
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	weak := testPasswordPolicy(t, bcrypt.MinCost)
+	strong := testPasswordPolicy(t, bcrypt.MinCost+1)
+
+	hash, err := weak.Hash("correct-Horse-battery")
+	assert.NoError(t, err)
+
+	assert.False(t, weak.NeedsRehash(hash))
+	assert.True(t, strong.NeedsRehash(hash))
+	assert.True(t, strong.NeedsRehash("not a hash"))
+	assert.ErrorIs(t, strong.CompareDummy("correct-Horse-battery"), errs.ErrInvalidCredentials)
+}
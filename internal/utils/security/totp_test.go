@@ -0,0 +1,51 @@
+package security
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors of RFC 6238, appendix B, for SHA1 truncated to 6 digits.
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tc := range tests {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, code)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := NewTOTPSecret()
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+
+	previous, _ := TOTPCode(secret, step-1)
+	expired, _ := TOTPCode(secret, step-2)
+
+	matched, ok := VerifyTOTP(secret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, matched)
+
+	_, ok = VerifyTOTP(secret, expired, now)
+	assert.False(t, ok)
+
+	_, ok = VerifyTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
@@ -6,7 +6,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const ContextKeyUser = "user"
+const (
+	ContextKeyUser  = "user"
+	ContextKeyToken = "token"
+
+	// ContextKeyPermissions holds the permissions of the current user.
+	ContextKeyPermissions = "permissions"
+
+	// ContextKeyChannel holds the deposit channel of a machine request.
+	ContextKeyChannel = "channel"
+
+	// ContextKeyStepUp is set when the request carried a verified one-time
+	// code.
+	ContextKeyStepUp = "step_up"
+)
 
 func GetParamID(ctx *gin.Context, key string) (int64, error) {
 	id := ctx.Param(key)
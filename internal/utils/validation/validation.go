@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/codepnw/simple-bank/internal/utils/money"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// phonePattern is an E.164 number: a plus and up to 15 digits.
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+	// accountNamePattern allows letters, digits, spaces and ' & . - inside
+	// a name that starts with a letter or digit. Marks are allowed for
+	// scripts such as Thai that combine them with letters.
+	accountNamePattern = regexp.MustCompile(`^[\p{L}\p{N}](?:[\p{L}\p{M}\p{N} '&.\-]*[\p{L}\p{M}\p{N}.])?$`)
+)
+
+// FieldError is one invalid field of a request and why it is invalid.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// New returns a validator that knows the custom tags of this service:
+// phone, currency and account_name. An empty phone passes, as it removes
+// the phone number of a user. Fields are named as in the JSON body or
+// query string.
+func New() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return ""
+	})
+
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		phone := fl.Field().String()
+		return phone == "" || phonePattern.MatchString(phone)
+	})
+	_ = v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return money.IsCurrency(fl.Field().String())
+	})
+	_ = v.RegisterValidation("account_name", func(fl validator.FieldLevel) bool {
+		return accountNamePattern.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+// Fields lists the invalid fields of a failed validation or of a JSON body
+// with a value of the wrong type. It returns false for any other error.
+func Fields(err error) ([]FieldError, bool) {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make([]FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, FieldError{Field: fe.Field(), Reason: reason(fe)})
+		}
+		return fields, true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:  typeErr.Field,
+			Reason: fmt.Sprintf("must be %s", jsonKind(typeErr.Type)),
+		}}, true
+	}
+
+	return nil, false
+}
+
+func reason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "phone":
+		return "must be a phone number in international format, like +66812345678"
+	case "currency":
+		return "must be a supported currency code"
+	case "account_name":
+		return "may only contain letters, digits, spaces and ' & . -"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "datetime":
+		return "must be a date like " + fe.Param()
+	case "nefield":
+		return "must differ from " + fe.Param()
+	case "min", "max", "len":
+		return lengthReason(fe)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// lengthReason explains min, max and len, which count characters of
+// strings, items of lists and the value of numbers.
+func lengthReason(fe validator.FieldError) string {
+	bound := map[string]string{"min": "at least", "max": "at most", "len": "exactly"}[fe.Tag()]
+
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must have %s %s items", bound, fe.Param())
+	default:
+		return fmt.Sprintf("must be %s %s", bound, fe.Param())
+	}
+}
+
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
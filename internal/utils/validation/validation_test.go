@@ -0,0 +1,90 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	Name     string   `json:"name" validate:"required,max=30,account_name"`
+	Currency string   `json:"currency" validate:"omitempty,currency"`
+	Phone    *string  `json:"phone" validate:"omitempty,phone"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Query    string   `form:"q" validate:"omitempty,oneof=a b"`
+}
+
+func TestCustomValidators(t *testing.T) {
+	phone := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		req    testRequest
+		fields []FieldError
+	}{
+		{name: "valid", req: testRequest{Name: "Rainy Day Fund", Currency: "USD", Phone: phone("+66812345678")}},
+		{name: "unicode name", req: testRequest{Name: "เงินออม 2025"}},
+		{name: "empty phone", req: testRequest{Name: "Savings", Phone: phone("")}},
+		{
+			name:   "missing name",
+			req:    testRequest{},
+			fields: []FieldError{{Field: "name", Reason: "is required"}},
+		},
+		{
+			name:   "name too long",
+			req:    testRequest{Name: "An account name that is far too long"},
+			fields: []FieldError{{Field: "name", Reason: "must be at most 30 characters long"}},
+		},
+		{
+			name:   "name with markup",
+			req:    testRequest{Name: "<b>Savings</b>"},
+			fields: []FieldError{{Field: "name", Reason: "may only contain letters, digits, spaces and ' & . -"}},
+		},
+		{
+			name: "unknown currency and local phone",
+			req:  testRequest{Name: "Savings", Currency: "XYZ", Phone: phone("0812345678")},
+			fields: []FieldError{
+				{Field: "currency", Reason: "must be a supported currency code"},
+				{Field: "phone", Reason: "must be a phone number in international format, like +66812345678"},
+			},
+		},
+		{
+			name: "list and query",
+			req:  testRequest{Name: "Savings", Tags: []string{"a", "b", "c"}, Query: "c"},
+			fields: []FieldError{
+				{Field: "tags", Reason: "must have at most 2 items"},
+				{Field: "q", Reason: "must be one of a, b"},
+			},
+		},
+	}
+
+	v := New()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Struct(&tc.req)
+
+			if tc.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			fields, ok := Fields(err)
+			assert.True(t, ok)
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestFieldsOfJSONTypeError(t *testing.T) {
+	var req testRequest
+	err := json.Unmarshal([]byte(`{"name": 42}`), &req)
+
+	fields, ok := Fields(err)
+	assert.True(t, ok)
+	assert.Equal(t, []FieldError{{Field: "name", Reason: "must be a string"}}, fields)
+
+	_, ok = Fields(errors.New("unexpected EOF"))
+	assert.False(t, ok)
+}